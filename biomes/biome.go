@@ -144,3 +144,8 @@ func JavaNameToID(name string) (int32, bool) {
 	id, ok := biomeToID[name]
 	return id, ok
 }
+
+// Count returns the number of biomes known. Biome IDs range from zero up to, but not including, the count.
+func Count() int32 {
+	return int32(len(idToBiome))
+}
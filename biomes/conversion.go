@@ -8,23 +8,23 @@ import (
 var (
 	//go:embed biomes.json
 	blockMappingData []byte
-	// javaToBedrockBiome is a map between a Java biome name and a Bedrock biome ID.
-	javaToBedrockBiome = make(map[string]uint32)
 )
 
 func init() {
 	parsedData := gjson.ParseBytes(blockMappingData)
 	parsedData.ForEach(func(key, value gjson.Result) bool {
-		javaToBedrockBiome[key.String()] = uint32(value.Get("bedrock_id").Uint())
+		defaultMapper.Map(key.String(), uint32(value.Get("bedrock_id").Uint()))
 		return true
 	})
 }
 
-// ConvertToBedrock converts a Java biome name to a Bedrock biome ID.
+// ConvertToBedrock converts a Java biome name to a Bedrock biome ID using the default mapper.
 func ConvertToBedrock(name string) (uint32, bool) {
-	if name == "minecraft:the_void" {
-		name = "minecraft:ocean" // The void biome doesn't exist in Bedrock, default to ocean.
-	}
-	converted, ok := javaToBedrockBiome[name]
-	return converted, ok
+	return defaultMapper.ConvertToBedrock(name)
+}
+
+// Nearest returns the Java biome that can be converted to Bedrock by the default mapper whose name is closest
+// to the name passed. See Mapper.Nearest.
+func Nearest(name string) (string, bool) {
+	return defaultMapper.Nearest(name)
 }
@@ -0,0 +1,96 @@
+package biomes
+
+import (
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Mapper maps Java biome names to Bedrock biome IDs. Mappers may be layered over a parent mapper, in which case
+// biomes that the mapper does not map itself are looked up in the parent.
+type Mapper struct {
+	// parent is the mapper used for biomes not mapped by this mapper. It is nil for the default mapper.
+	parent *Mapper
+	// javaToBedrockBiome is a map between a Java biome name and a Bedrock biome ID.
+	javaToBedrockBiome map[string]uint32
+}
+
+// defaultMapper is the mapper holding the embedded biome mappings.
+var defaultMapper = NewMapper(nil)
+
+// DefaultMapper returns the mapper holding the default, embedded biome mappings.
+func DefaultMapper() *Mapper {
+	return defaultMapper
+}
+
+// NewMapper creates a new, empty mapper layered over the parent passed. If the parent is nil, the mapper will
+// only map the biomes that are explicitly added to it.
+func NewMapper(parent *Mapper) *Mapper {
+	return &Mapper{parent: parent, javaToBedrockBiome: make(map[string]uint32)}
+}
+
+// Map maps the Java biome passed to the Bedrock biome ID passed, overriding any mapping of the parent.
+func (m *Mapper) Map(java string, bedrock uint32) {
+	m.javaToBedrockBiome[java] = bedrock
+}
+
+// LoadJSON loads mappings from JSON in the same format as the embedded mappings: an object with Java biome names
+// as keys, and objects holding a "bedrock_id" as values.
+func (m *Mapper) LoadJSON(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !gjson.ValidBytes(data) {
+		return fmt.Errorf("invalid biome mapping json")
+	}
+	gjson.ParseBytes(data).ForEach(func(key, value gjson.Result) bool {
+		id := value.Get("bedrock_id")
+		if id.Type != gjson.Number {
+			err = fmt.Errorf("invalid mapping for %v: missing bedrock_id", key.String())
+			return false
+		}
+		m.Map(key.String(), uint32(id.Uint()))
+		return true
+	})
+	return err
+}
+
+// ConvertToBedrock converts a Java biome name to a Bedrock biome ID.
+func (m *Mapper) ConvertToBedrock(name string) (uint32, bool) {
+	if name == "minecraft:the_void" {
+		name = "minecraft:ocean" // The void biome doesn't exist in Bedrock, default to ocean.
+	}
+	for mapper := m; mapper != nil; mapper = mapper.parent {
+		if converted, ok := mapper.javaToBedrockBiome[name]; ok {
+			return converted, true
+		}
+	}
+	return 0, false
+}
+
+// Nearest returns the Java biome that can be converted to Bedrock whose name is closest to the name passed. The
+// namespace is ignored first, after which the vanilla biome with the longest name contained in the name passed
+// is returned. If no such biome exists, false is returned.
+func (m *Mapper) Nearest(name string) (string, bool) {
+	path := name[strings.Index(name, ":")+1:]
+	if _, ok := m.ConvertToBedrock("minecraft:" + path); ok {
+		return "minecraft:" + path, true
+	}
+
+	var nearest string
+	for mapper := m; mapper != nil; mapper = mapper.parent {
+		for candidate := range mapper.javaToBedrockBiome {
+			candidatePath := strings.TrimPrefix(candidate, "minecraft:")
+			if !strings.Contains(path, candidatePath) {
+				continue
+			}
+			if len(candidate) > len(nearest) || (len(candidate) == len(nearest) && candidate < nearest) {
+				nearest = candidate
+			}
+		}
+	}
+	return nearest, nearest != ""
+}
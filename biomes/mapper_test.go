@@ -0,0 +1,23 @@
+package biomes
+
+import "testing"
+
+func TestMapperNearest(t *testing.T) {
+	m := NewMapper(DefaultMapper())
+	m.Map("minecraft:crystal_caves", 1)
+	for name, expected := range map[string]string{
+		"minecraft:plains":         "minecraft:plains",
+		"mymod:desert":             "minecraft:desert",
+		"mymod:dark_forest_glade":  "minecraft:dark_forest",
+		"mymod:pale_crystal_caves": "minecraft:crystal_caves",
+		"mymod:glade":              "",
+	} {
+		nearest, ok := m.Nearest(name)
+		if nearest != expected || ok != (expected != "") {
+			t.Errorf("%v: expected %q, got %q (%v)", name, expected, nearest, ok)
+		}
+	}
+	if _, ok := DefaultMapper().Nearest("mymod:pale_crystal_caves"); ok {
+		t.Error("expected biomes added to a layer not to be found by its parent")
+	}
+}
@@ -0,0 +1,51 @@
+package biomes
+
+import (
+	"bytes"
+	_ "embed"
+	"github.com/justtaldevelops/mcanvil/internal/versions"
+)
+
+const (
+	// JavaDataVersion is the Java data version of the biome mappings, which is that of Minecraft 1.19. Chunks of
+	// older Java versions are upgraded to the current biome names by the upgrade package when they are read, so
+	// the same mappings are used to convert them. Mappings are not registered per Java version.
+	JavaDataVersion = 3105
+	// BedrockProtocol is the Bedrock protocol version targeted by the default biome mappings, which is that of
+	// Minecraft 1.19.0.
+	BedrockProtocol = 527
+)
+
+var (
+	//go:embed versions/bedrock_475.json
+	bedrock475MappingData []byte
+	// mappers holds all mappers registered using RegisterMapper.
+	mappers versions.Registry[*Mapper]
+)
+
+func init() {
+	RegisterMapper(BedrockProtocol, defaultMapper)
+
+	// Bedrock 1.18.0 does not yet have the deep dark and mangrove swamps, the only biomes added in 1.19, so they
+	// are mapped to the closest biomes it has.
+	bedrock475 := NewMapper(defaultMapper)
+	if err := bedrock475.LoadJSON(bytes.NewReader(bedrock475MappingData)); err != nil {
+		panic(err)
+	}
+	RegisterMapper(475, bedrock475)
+}
+
+// RegisterMapper registers a mapper for converting Java biomes to the Bedrock protocol version passed. A mapper
+// registered earlier for the same version is replaced.
+func RegisterMapper(protocol int32, m *Mapper) {
+	mappers.Register(protocol, m)
+}
+
+// MapperFor returns the registered mapper best suited for converting Java biomes to the Bedrock protocol version
+// passed. Mappers are selected in the same way as block state mappers are.
+func MapperFor(protocol int32) *Mapper {
+	if m, ok := mappers.Lookup(protocol); ok {
+		return m
+	}
+	return defaultMapper
+}
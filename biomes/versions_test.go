@@ -0,0 +1,34 @@
+package biomes
+
+import "testing"
+
+func TestMapperFor(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		protocol int32
+		// expected holds the Bedrock biome IDs expected for Java biomes.
+		expected map[string]uint32
+	}{
+		{name: "1.19.0", protocol: BedrockProtocol, expected: map[string]uint32{
+			"minecraft:deep_dark": 190, "minecraft:mangrove_swamp": 191, "minecraft:plains": 1, "minecraft:meadow": 186,
+		}},
+		{name: "1.18.0", protocol: 475, expected: map[string]uint32{
+			"minecraft:deep_dark": 188, "minecraft:mangrove_swamp": 6, "minecraft:plains": 1, "minecraft:meadow": 186,
+		}},
+		{name: "older protocol", protocol: 440, expected: map[string]uint32{
+			"minecraft:deep_dark": 188, "minecraft:mangrove_swamp": 6,
+		}},
+		{name: "newer protocol", protocol: 560, expected: map[string]uint32{
+			"minecraft:deep_dark": 190, "minecraft:mangrove_swamp": 191,
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := MapperFor(test.protocol)
+			for name, id := range test.expected {
+				if got, ok := m.ConvertToBedrock(name); !ok || got != id {
+					t.Errorf("%v: expected %v, got %v (ok: %v)", name, id, got, ok)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package blockentity
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"github.com/justtaldevelops/mcanvil/items"
+	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/text"
+	"strings"
+)
+
+func init() {
+	Register(banner{}, "minecraft:banner")
+	RegisterJava(banner{}, "Banner")
+	for id, code := range bannerPatterns {
+		bannerPatternIDs[code] = id
+	}
+}
+
+// ominousBanner is the translation key of the name of ominous banners, which Bedrock marks with a banner type.
+const ominousBanner = "block.minecraft.ominous_banner"
+
+// bannerPatterns maps the IDs of Java banner patterns, used since 1.20.5, to the pattern codes used by Bedrock
+// and older Java versions.
+var bannerPatterns = map[string]string{
+	"minecraft:base":                   "b",
+	"minecraft:square_bottom_left":     "bl",
+	"minecraft:square_bottom_right":    "br",
+	"minecraft:square_top_left":        "tl",
+	"minecraft:square_top_right":       "tr",
+	"minecraft:stripe_bottom":          "bs",
+	"minecraft:stripe_top":             "ts",
+	"minecraft:stripe_left":            "ls",
+	"minecraft:stripe_right":           "rs",
+	"minecraft:stripe_center":          "cs",
+	"minecraft:stripe_middle":          "ms",
+	"minecraft:stripe_downright":       "drs",
+	"minecraft:stripe_downleft":        "dls",
+	"minecraft:small_stripes":          "ss",
+	"minecraft:cross":                  "cr",
+	"minecraft:straight_cross":         "sc",
+	"minecraft:triangle_bottom":        "bt",
+	"minecraft:triangle_top":           "tt",
+	"minecraft:triangles_bottom":       "bts",
+	"minecraft:triangles_top":          "tts",
+	"minecraft:diagonal_left":          "ld",
+	"minecraft:diagonal_up_right":      "rd",
+	"minecraft:diagonal_up_left":       "lud",
+	"minecraft:diagonal_right":         "rud",
+	"minecraft:circle":                 "mc",
+	"minecraft:rhombus":                "mr",
+	"minecraft:half_vertical":          "vh",
+	"minecraft:half_horizontal":        "hh",
+	"minecraft:half_vertical_right":    "vhr",
+	"minecraft:half_horizontal_bottom": "hhb",
+	"minecraft:border":                 "bo",
+	"minecraft:curly_border":           "cbo",
+	"minecraft:gradient":               "gra",
+	"minecraft:gradient_up":            "gru",
+	"minecraft:bricks":                 "bri",
+	"minecraft:globe":                  "glb",
+	"minecraft:creeper":                "cre",
+	"minecraft:skull":                  "sku",
+	"minecraft:flower":                 "flo",
+	"minecraft:mojang":                 "moj",
+	"minecraft:piglin":                 "pig",
+	"minecraft:flow":                   "flw",
+	"minecraft:guster":                 "gus",
+}
+
+// bannerPatternIDs maps Bedrock banner pattern codes to the IDs of Java banner patterns.
+var bannerPatternIDs = make(map[string]string)
+
+// bannerPattern is a single pattern layer of a banner.
+type bannerPattern struct {
+	// code is the pattern code, such as "bs".
+	code string
+	// colour is the Java dye ID of the colour of the pattern.
+	colour int
+}
+
+// banner converts standing and wall banners. Java stores the base colour of banners in the name of the block,
+// while Bedrock stores it in the block entity. Bedrock numbers the colours of banners in reverse order.
+type banner struct{}
+
+// ToBedrock converts a Java banner with patterns saved either before or after 1.20.5.
+func (banner) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	name := strings.Replace(ctx.Block.Name, "_wall_banner", "_banner", 1)
+	base, ok := blockColour(name, "_banner")
+	if !ok {
+		return nil, fmt.Errorf("unknown banner %v", ctx.Block.Name)
+	}
+	patterns := make([]any, 0)
+	for _, p := range readBannerPatterns(data) {
+		patterns = append(patterns, map[string]any{"Pattern": p.code, "Color": int32(15 - p.colour)})
+	}
+	var bannerType int32
+	if customName, ok := data["CustomName"].(string); ok {
+		if c, err := text.Parse(customName); err == nil && c.Translate == ominousBanner {
+			bannerType = 1
+		}
+	}
+	return map[string]any{"id": "Banner", "Base": int32(15 - base), "Patterns": patterns, "Type": bannerType}, nil
+}
+
+// ToJava converts a Bedrock banner, returning the Java banner block of its base colour.
+func (banner) ToJava(data map[string]any, ctx Context) (map[string]any, states.Block, error) {
+	base, _ := nbtutil.Int(data["Base"])
+	if base < 0 || base > 15 {
+		return nil, ctx.Block, fmt.Errorf("unknown banner colour %v", base)
+	}
+	suffix := "_banner"
+	if strings.HasSuffix(ctx.Block.Name, "_wall_banner") {
+		suffix = "_wall_banner"
+	}
+	block := states.Block{Name: "minecraft:" + dyeColours[15-base] + suffix, Properties: ctx.Block.Properties}
+
+	m := map[string]any{"id": "minecraft:banner"}
+	list, _ := data["Patterns"].([]any)
+	patterns := make([]any, 0, len(list))
+	for _, v := range list {
+		p, _ := v.(map[string]any)
+		code, _ := p["Pattern"].(string)
+		colour, _ := nbtutil.Int(p["Color"])
+		if colour < 0 || colour > 15 {
+			continue
+		}
+		if ctx.DataVersion < items.ComponentsDataVersion {
+			patterns = append(patterns, map[string]any{"Pattern": code, "Color": int32(15 - colour)})
+			continue
+		}
+		if id, ok := bannerPatternIDs[code]; ok {
+			patterns = append(patterns, map[string]any{"pattern": id, "color": dyeColours[15-colour]})
+		}
+	}
+	if ctx.DataVersion < items.ComponentsDataVersion {
+		m["Patterns"] = patterns
+	} else {
+		m["patterns"] = patterns
+	}
+	if bannerType, _ := nbtutil.Int(data["Type"]); bannerType == 1 {
+		m["CustomName"] = `{"color":"gold","translate":"` + ominousBanner + `"}`
+	}
+	return m, block, nil
+}
+
+// readBannerPatterns reads the patterns of a Java banner, saved either before or after 1.20.5. Patterns that
+// Bedrock does not have are left out.
+func readBannerPatterns(data map[string]any) []bannerPattern {
+	var patterns []bannerPattern
+	if list, ok := data["patterns"].([]any); ok {
+		for _, v := range list {
+			p, _ := v.(map[string]any)
+			id, _ := p["pattern"].(string)
+			colourName, _ := p["color"].(string)
+			code, ok := bannerPatterns[id]
+			colour, colourOk := dyeColour(colourName)
+			if ok && colourOk {
+				patterns = append(patterns, bannerPattern{code: code, colour: colour})
+			}
+		}
+		return patterns
+	}
+	list, _ := data["Patterns"].([]any)
+	for _, v := range list {
+		p, _ := v.(map[string]any)
+		code, _ := p["Pattern"].(string)
+		colour, _ := nbtutil.Int(p["Color"])
+		if code != "" && colour >= 0 && colour <= 15 {
+			patterns = append(patterns, bannerPattern{code: code, colour: int(colour)})
+		}
+	}
+	return patterns
+}
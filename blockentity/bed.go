@@ -0,0 +1,35 @@
+package blockentity
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"github.com/justtaldevelops/mcanvil/states"
+)
+
+func init() {
+	Register(bed{}, "minecraft:bed")
+	RegisterJava(bed{}, "Bed")
+}
+
+// bed converts beds. Java stores the colour of beds in the name of the block, while Bedrock stores it in the
+// block entity.
+type bed struct{}
+
+// ToBedrock converts a Java bed, taking the colour from its block.
+func (bed) ToBedrock(_ map[string]any, ctx Context) (map[string]any, error) {
+	colour, ok := blockColour(ctx.Block.Name, "_bed")
+	if !ok {
+		return nil, fmt.Errorf("unknown bed %v", ctx.Block.Name)
+	}
+	return map[string]any{"id": "Bed", "color": uint8(colour)}, nil
+}
+
+// ToJava converts a Bedrock bed, returning the Java bed block of its colour.
+func (bed) ToJava(data map[string]any, ctx Context) (map[string]any, states.Block, error) {
+	colour, _ := nbtutil.Int(data["color"])
+	if colour < 0 || int(colour) >= len(dyeColours) {
+		return nil, ctx.Block, fmt.Errorf("unknown bed colour %v", colour)
+	}
+	block := states.Block{Name: "minecraft:" + dyeColours[colour] + "_bed", Properties: ctx.Block.Properties}
+	return map[string]any{"id": "minecraft:bed"}, block, nil
+}
@@ -0,0 +1,166 @@
+package blockentity
+
+import (
+	"fmt"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/justtaldevelops/mcanvil/commands"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"github.com/justtaldevelops/mcanvil/items"
+	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/text"
+	"strings"
+	"sync"
+)
+
+// textNBTDataVersion is the Java data version since which text components in block entities are stored as NBT
+// rather than as JSON, like those of items.
+const textNBTDataVersion = items.TextNBTDataVersion
+
+// Context holds the information about a block entity that is needed to convert it, besides its NBT. It is used
+// for conversion in both directions.
+type Context struct {
+	// Block is the Java block state of the block holding the block entity.
+	Block states.Block
+	// Pos is the position of the block entity in the world converted to, with any vertical shift applied.
+	Pos cube.Pos
+	// DataVersion is the Java data version of the chunk holding the block entity, or the Java data version
+	// converted to.
+	DataVersion int32
+	// Protocol is the Bedrock protocol version converted to or from.
+	Protocol int32
+	// Text holds the options used to convert text components to Bedrock formatted text.
+	Text text.Options
+	// Commands is the translator used for the commands of command blocks. If nil, commands are kept as they are.
+	Commands *commands.Translator
+	// Untranslatable is called with every command that could not be translated, which is kept as it is. It may
+	// be nil.
+	Untranslatable func(command string, err error)
+}
+
+// Converter converts the NBT of a type of Java block entity to Bedrock.
+type Converter interface {
+	// ToBedrock converts the NBT of a Java block entity to the NBT of a Bedrock block entity. The id and position
+	// of the Bedrock block entity are set by the converter.
+	ToBedrock(data map[string]any, ctx Context) (map[string]any, error)
+}
+
+// JavaConverter converts the NBT of a type of Bedrock block entity to Java.
+type JavaConverter interface {
+	// ToJava converts the NBT of a Bedrock block entity to the NBT of a Java block entity. The Block of the
+	// Context is the Java block state converted from the Bedrock block, and the Java block state to place is
+	// returned, as Java keeps some data that Bedrock stores in block entities in the block, such as the colour of
+	// beds. The id and position of the Java block entity are set by the converter.
+	ToJava(data map[string]any, ctx Context) (map[string]any, states.Block, error)
+}
+
+var (
+	convertersMu sync.RWMutex
+	// converters maps Java block entity IDs to the converter registered for them.
+	converters = make(map[string]Converter)
+	// javaConverters maps Bedrock block entity IDs to the Java converter registered for them.
+	javaConverters = make(map[string]JavaConverter)
+)
+
+// Register registers a Converter for the Java block entity IDs passed, such as "minecraft:sign". Converters
+// registered earlier for the same IDs are replaced.
+func Register(c Converter, ids ...string) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	for _, id := range ids {
+		converters[id] = c
+	}
+}
+
+// RegisterJava registers a JavaConverter for the Bedrock block entity IDs passed, such as "Bed". Converters
+// registered earlier for the same IDs are replaced.
+func RegisterJava(c JavaConverter, ids ...string) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	for _, id := range ids {
+		javaConverters[id] = c
+	}
+}
+
+// ToBedrock converts the NBT of a Java block entity to Bedrock using the Converter registered for its ID. False
+// is returned if no Converter is registered for the ID.
+func ToBedrock(data map[string]any, ctx Context) (map[string]any, bool, error) {
+	id := ID(data)
+	convertersMu.RLock()
+	c, ok := converters[id]
+	convertersMu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	m, err := c.ToBedrock(data, ctx)
+	if err != nil {
+		return nil, true, fmt.Errorf("block entity %v at %v: %w", id, ctx.Pos, err)
+	}
+	m["x"], m["y"], m["z"] = int32(ctx.Pos.X()), int32(ctx.Pos.Y()), int32(ctx.Pos.Z())
+	if _, ok := m["isMovable"]; !ok {
+		m["isMovable"] = uint8(1)
+	}
+	return m, true, nil
+}
+
+// ToJava converts the NBT of a Bedrock block entity to Java using the JavaConverter registered for its ID. The Pos
+// and DataVersion of the Context are those of the Java world. The NBT is returned along with the Java block state
+// to place, and false is returned if no JavaConverter is registered for the ID.
+func ToJava(data map[string]any, ctx Context) (map[string]any, states.Block, bool, error) {
+	id, _ := data["id"].(string)
+	convertersMu.RLock()
+	c, ok := javaConverters[id]
+	convertersMu.RUnlock()
+	if !ok {
+		return nil, ctx.Block, false, nil
+	}
+	m, block, err := c.ToJava(data, ctx)
+	if err != nil {
+		return nil, ctx.Block, true, fmt.Errorf("block entity %v at %v: %w", id, ctx.Pos, err)
+	}
+	m["x"], m["y"], m["z"] = int32(ctx.Pos.X()), int32(ctx.Pos.Y()), int32(ctx.Pos.Z())
+	m["keepPacked"] = uint8(0)
+	return m, block, true, nil
+}
+
+// ID returns the namespaced Java ID of the block entity passed.
+func ID(data map[string]any) string {
+	id, _ := data["id"].(string)
+	if id == "" {
+		return ""
+	}
+	return namespaced(id)
+}
+
+// Position returns the position stored in the NBT of a Java block entity.
+func Position(data map[string]any) (cube.Pos, bool) {
+	x, okX := nbtutil.Int(data["x"])
+	y, okY := nbtutil.Int(data["y"])
+	z, okZ := nbtutil.Int(data["z"])
+	return cube.Pos{int(x), int(y), int(z)}, okX && okY && okZ
+}
+
+// formattedText converts a Java text component stored in a block entity to Bedrock formatted text.
+func formattedText(v any, ctx Context) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		if ctx.DataVersion < textNBTDataVersion {
+			return text.ToBedrock(v, ctx.Text), nil
+		}
+	}
+	c, err := text.FromNBT(v)
+	if err != nil {
+		return "", err
+	}
+	return c.Bedrock(ctx.Text), nil
+}
+
+// namespaced returns the namespaced form of a Java ID. IDs saved before 1.11, such as "Chest", have no namespace
+// and become "minecraft:chest".
+func namespaced(id string) string {
+	if strings.Contains(id, ":") {
+		return id
+	}
+	return "minecraft:" + strings.ToLower(id)
+}
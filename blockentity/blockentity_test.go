@@ -0,0 +1,273 @@
+package blockentity
+
+import (
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/justtaldevelops/mcanvil/states"
+	"reflect"
+	"testing"
+)
+
+// testPos is the position of the block entities converted in tests.
+var testPos = cube.Pos{5, 64, 7}
+
+func TestToBedrock(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name     string
+		data     map[string]any
+		ctx      Context
+		expected map[string]any
+	}{
+		{
+			name: "sign before 1.20",
+			data: map[string]any{
+				"id": "minecraft:sign", "Color": "red", "GlowingText": uint8(1),
+				"Text1": `{"text":"Hello"}`, "Text2": `{"text":"World","color":"blue"}`, "Text3": `{"text":""}`, "Text4": `{"text":""}`,
+			},
+			ctx: Context{DataVersion: 3105, Protocol: 560},
+			expected: map[string]any{
+				"id":        "Sign",
+				"FrontText": signSide("Hello\n§9World", -0x4fd1da, true),
+				"BackText":  signSide("", -0x1000000, false),
+				"IsWaxed":   uint8(0),
+				// Bedrock versions before 1.20 read the front text from the root compound.
+				"Text": "Hello\n§9World", "TextOwner": "", "SignTextColor": int32(-0x4fd1da), "IgnoreLighting": uint8(1),
+				"HideGlowOutline": uint8(0), "PersistFormatting": uint8(1),
+			},
+		},
+		{
+			name: "sign with two sides",
+			data: map[string]any{
+				"id": "minecraft:sign", "is_waxed": uint8(1),
+				"front_text": map[string]any{"color": "blue", "has_glowing_text": uint8(0), "messages": []any{`"Front"`, `""`, `""`, `""`}},
+				"back_text":  map[string]any{"color": "white", "has_glowing_text": uint8(1), "messages": []any{`""`, `"Back"`, `""`, `""`}},
+			},
+			ctx: Context{DataVersion: 3465, Protocol: SignSidesProtocol},
+			expected: map[string]any{
+				"id":        "Sign",
+				"FrontText": signSide("Front", -0xc3bb56, false),
+				"BackText":  signSide("\nBack", -0xf0f10, true),
+				"IsWaxed":   uint8(1),
+			},
+		},
+		{
+			name: "hanging sign with NBT text",
+			data: map[string]any{
+				"id":         "minecraft:hanging_sign",
+				"front_text": map[string]any{"messages": []any{"Plain", map[string]any{"text": "Red", "color": "red"}, "", ""}},
+				"back_text":  map[string]any{"messages": []any{"", "", "", ""}},
+			},
+			ctx: Context{DataVersion: textNBTDataVersion, Protocol: SignSidesProtocol},
+			expected: map[string]any{
+				"id":        "HangingSign",
+				"FrontText": signSide("Plain\n§cRed", -0x1000000, false),
+				"BackText":  signSide("", -0x1000000, false),
+				"IsWaxed":   uint8(0),
+			},
+		},
+		{
+			name: "left half of a double chest",
+			data: map[string]any{"id": "minecraft:chest", "Items": []any{}},
+			ctx:  Context{Block: states.Block{Name: "minecraft:chest", Properties: map[string]any{"facing": "north", "type": "left"}}},
+			expected: map[string]any{
+				"id": "Chest", "Items": []any{}, "pairx": int32(6), "pairz": int32(7),
+			},
+		},
+		{
+			name: "right half of a double chest",
+			data: map[string]any{"id": "minecraft:trapped_chest", "Items": []any{}},
+			ctx:  Context{Block: states.Block{Name: "minecraft:trapped_chest", Properties: map[string]any{"facing": "east", "type": "right"}}},
+			expected: map[string]any{
+				"id": "Chest", "Items": []any{}, "pairx": int32(5), "pairz": int32(6), "pairlead": uint8(1),
+			},
+		},
+		{
+			name: "single chest with a loot table",
+			data: map[string]any{"id": "Chest", "LootTable": "minecraft:chests/simple_dungeon", "LootTableSeed": int64(12)},
+			ctx:  Context{Block: states.Block{Name: "minecraft:chest", Properties: map[string]any{"facing": "south", "type": "single"}}},
+			expected: map[string]any{
+				"id": "Chest", "Items": []any{}, "LootTable": "loot_tables/chests/simple_dungeon.json", "LootTableSeed": int32(12),
+			},
+		},
+		{
+			name: "furnace before 1.21.4",
+			data: map[string]any{"id": "minecraft:furnace", "BurnTime": int16(100), "CookTime": int16(50), "CookTimeTotal": int16(200)},
+			expected: map[string]any{
+				"id": "Furnace", "Items": []any{}, "BurnTime": int16(100), "BurnDuration": int16(100), "CookTime": int16(50),
+				"StoredXPInt": int32(0),
+			},
+		},
+		{
+			name: "blast furnace",
+			data: map[string]any{"id": "minecraft:blast_furnace", "lit_time_remaining": int16(80), "lit_total_time": int16(200), "cooking_time_spent": int16(30)},
+			expected: map[string]any{
+				"id": "BlastFurnace", "Items": []any{}, "BurnTime": int16(80), "BurnDuration": int16(200), "CookTime": int16(30),
+				"StoredXPInt": int32(0),
+			},
+		},
+		{
+			name: "banner with pattern IDs",
+			data: map[string]any{"id": "minecraft:banner", "patterns": []any{
+				map[string]any{"pattern": "minecraft:stripe_bottom", "color": "blue"},
+				map[string]any{"pattern": "minecraft:unknown", "color": "red"},
+			}},
+			ctx: Context{Block: states.Block{Name: "minecraft:red_banner", Properties: map[string]any{"rotation": "0"}}},
+			expected: map[string]any{
+				"id": "Banner", "Base": int32(1), "Type": int32(0),
+				"Patterns": []any{map[string]any{"Pattern": "bs", "Color": int32(4)}},
+			},
+		},
+		{
+			name: "ominous wall banner with pattern codes",
+			data: map[string]any{
+				"id": "minecraft:banner", "CustomName": `{"color":"gold","translate":"block.minecraft.ominous_banner"}`,
+				"Patterns": []any{map[string]any{"Pattern": "cre", "Color": int32(15)}},
+			},
+			ctx: Context{Block: states.Block{Name: "minecraft:white_wall_banner", Properties: map[string]any{"facing": "north"}}},
+			expected: map[string]any{
+				"id": "Banner", "Base": int32(15), "Type": int32(1),
+				"Patterns": []any{map[string]any{"Pattern": "cre", "Color": int32(0)}},
+			},
+		},
+		{
+			name:     "bed",
+			data:     map[string]any{"id": "minecraft:bed"},
+			ctx:      Context{Block: states.Block{Name: "minecraft:red_bed", Properties: map[string]any{"part": "head"}}},
+			expected: map[string]any{"id": "Bed", "color": uint8(14)},
+		},
+		{
+			name: "beehive",
+			data: map[string]any{"id": "minecraft:beehive", "bees": []any{
+				map[string]any{"entity_data": map[string]any{"id": "minecraft:bee"}, "ticks_in_hive": int32(100), "min_ticks_in_hive": int32(600)},
+			}},
+			expected: map[string]any{"id": "Beehive", "ShouldSpawnBees": uint8(0), "Occupants": []any{map[string]any{
+				"ActorIdentifier": "minecraft:bee<>", "SaveData": map[string]any{"identifier": "minecraft:bee"}, "TicksLeftToStay": int32(500),
+			}}},
+		},
+		{
+			name: "bee nest before 1.20.5",
+			data: map[string]any{"id": "minecraft:beehive", "Bees": []any{
+				map[string]any{"EntityData": map[string]any{"id": "minecraft:bee"}, "TicksInHive": int32(700), "MinOccupationTicks": int32(600)},
+			}},
+			expected: map[string]any{"id": "Beehive", "ShouldSpawnBees": uint8(0), "Occupants": []any{map[string]any{
+				"ActorIdentifier": "minecraft:bee<>", "SaveData": map[string]any{"identifier": "minecraft:bee"}, "TicksLeftToStay": int32(0),
+			}}},
+		},
+		{
+			name:     "beacon",
+			data:     map[string]any{"id": "minecraft:beacon", "primary_effect": "minecraft:haste", "secondary_effect": "minecraft:regeneration"},
+			expected: map[string]any{"id": "Beacon", "primary": int32(3), "secondary": int32(10)},
+		},
+		{
+			name:     "beacon before 1.20.2",
+			data:     map[string]any{"id": "minecraft:beacon", "Primary": int32(1), "Secondary": int32(-1), "Levels": int32(4)},
+			expected: map[string]any{"id": "Beacon", "primary": int32(1), "secondary": int32(0)},
+		},
+		{
+			name:     "conduit",
+			data:     map[string]any{"id": "minecraft:conduit", "Target": []int32{1, 2, 3, 4}},
+			expected: map[string]any{"id": "Conduit", "Active": uint8(0), "Target": int64(-1)},
+		},
+	} {
+		test.ctx.Pos = testPos
+		m, ok, err := ToBedrock(test.data, test.ctx)
+		if err != nil || !ok {
+			t.Errorf("%v: could not convert: %v (registered: %v)", test.name, err, ok)
+			continue
+		}
+		test.expected["x"], test.expected["y"], test.expected["z"] = int32(5), int32(64), int32(7)
+		test.expected["isMovable"] = uint8(1)
+		if !reflect.DeepEqual(m, test.expected) {
+			t.Errorf("%v:\nexpected %#v\ngot      %#v", test.name, test.expected, m)
+		}
+	}
+}
+
+func TestToBedrockErrors(t *testing.T) {
+	t.Parallel()
+	if _, ok, err := ToBedrock(map[string]any{"id": "minecraft:unknown"}, Context{}); ok || err != nil {
+		t.Errorf("expected an unregistered block entity to be skipped, got %v (registered: %v)", err, ok)
+	}
+	for name, test := range map[string]struct {
+		data map[string]any
+		ctx  Context
+	}{
+		"bed of an unknown colour": {map[string]any{"id": "minecraft:bed"}, Context{Block: states.Block{Name: "minecraft:bed"}}},
+		"unknown beacon effect":    {map[string]any{"id": "minecraft:beacon", "primary_effect": "minecraft:glowing"}, Context{}},
+		"invalid sign text":        {map[string]any{"id": "minecraft:sign", "Text1": []any{}}, Context{DataVersion: textNBTDataVersion}},
+	} {
+		if _, ok, err := ToBedrock(test.data, test.ctx); !ok || err == nil {
+			t.Errorf("%v: expected an error, got %v (registered: %v)", name, err, ok)
+		}
+	}
+}
+
+func TestToJava(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		data          map[string]any
+		ctx           Context
+		expected      map[string]any
+		expectedBlock states.Block
+	}{
+		{
+			name: "banner",
+			data: map[string]any{"id": "Banner", "Base": int32(1), "Type": int32(1), "Patterns": []any{
+				map[string]any{"Pattern": "bs", "Color": int32(4)},
+				map[string]any{"Pattern": "unknown", "Color": int32(4)},
+			}},
+			ctx: Context{DataVersion: 3955, Block: states.Block{Name: "minecraft:white_banner", Properties: map[string]any{"rotation": "4"}}},
+			expected: map[string]any{
+				"id":         "minecraft:banner",
+				"patterns":   []any{map[string]any{"pattern": "minecraft:stripe_bottom", "color": "blue"}},
+				"CustomName": `{"color":"gold","translate":"block.minecraft.ominous_banner"}`,
+			},
+			expectedBlock: states.Block{Name: "minecraft:red_banner", Properties: map[string]any{"rotation": "4"}},
+		},
+		{
+			name: "wall banner before 1.20.5",
+			data: map[string]any{"id": "Banner", "Base": int32(15), "Patterns": []any{map[string]any{"Pattern": "cre", "Color": int32(0)}}},
+			ctx:  Context{DataVersion: 3105, Block: states.Block{Name: "minecraft:white_wall_banner", Properties: map[string]any{"facing": "east"}}},
+			expected: map[string]any{
+				"id":       "minecraft:banner",
+				"Patterns": []any{map[string]any{"Pattern": "cre", "Color": int32(15)}},
+			},
+			expectedBlock: states.Block{Name: "minecraft:white_wall_banner", Properties: map[string]any{"facing": "east"}},
+		},
+		{
+			name:          "bed",
+			data:          map[string]any{"id": "Bed", "color": uint8(11)},
+			ctx:           Context{Block: states.Block{Name: "minecraft:white_bed", Properties: map[string]any{"part": "foot"}}},
+			expected:      map[string]any{"id": "minecraft:bed"},
+			expectedBlock: states.Block{Name: "minecraft:blue_bed", Properties: map[string]any{"part": "foot"}},
+		},
+	} {
+		test.ctx.Pos = testPos
+		m, block, ok, err := ToJava(test.data, test.ctx)
+		if err != nil || !ok {
+			t.Errorf("%v: could not convert: %v (registered: %v)", test.name, err, ok)
+			continue
+		}
+		test.expected["x"], test.expected["y"], test.expected["z"] = int32(5), int32(64), int32(7)
+		test.expected["keepPacked"] = uint8(0)
+		if !reflect.DeepEqual(m, test.expected) {
+			t.Errorf("%v:\nexpected %#v\ngot      %#v", test.name, test.expected, m)
+		}
+		if !reflect.DeepEqual(block, test.expectedBlock) {
+			t.Errorf("%v: expected block %v, got %v", test.name, test.expectedBlock, block)
+		}
+	}
+
+	if _, _, ok, err := ToJava(map[string]any{"id": "Bed", "color": uint8(16)}, Context{}); !ok || err == nil {
+		t.Errorf("expected a bed of an unknown colour to fail, got %v (registered: %v)", err, ok)
+	}
+}
+
+// signSide returns the Bedrock compound holding the text on one side of a sign.
+func signSide(text string, colour int32, glowing bool) map[string]any {
+	return map[string]any{
+		"Text": text, "TextOwner": "", "SignTextColor": colour, "IgnoreLighting": byteBool(glowing),
+		"HideGlowOutline": uint8(0), "PersistFormatting": uint8(1),
+	}
+}
@@ -0,0 +1,29 @@
+package blockentity
+
+import "strings"
+
+// dyeColours holds the names of all dye colours, ordered by their Java dye ID.
+var dyeColours = []string{
+	"white", "orange", "magenta", "light_blue", "yellow", "lime", "pink", "gray",
+	"light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black",
+}
+
+// dyeColour returns the Java dye ID of the dye colour with the name passed.
+func dyeColour(name string) (int, bool) {
+	for i, colour := range dyeColours {
+		if colour == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// blockColour returns the Java dye ID of the colour in the name of a coloured block, such as "minecraft:red_bed"
+// with the suffix "_bed".
+func blockColour(name, suffix string) (int, bool) {
+	name = strings.TrimPrefix(name, "minecraft:")
+	if !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	return dyeColour(strings.TrimSuffix(name, suffix))
+}
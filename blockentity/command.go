@@ -0,0 +1,121 @@
+package blockentity
+
+import (
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"strings"
+)
+
+func init() {
+	Register(commandBlock{}, "minecraft:command_block")
+	Register(structureBlock{}, "minecraft:structure_block")
+}
+
+// commandBlock converts impulse, repeating and chain command blocks.
+type commandBlock struct{}
+
+// commandModes maps the names of Java command blocks to the Bedrock command block mode.
+var commandModes = map[string]int32{
+	"minecraft:command_block":           0,
+	"minecraft:repeating_command_block": 1,
+	"minecraft:chain_command_block":     2,
+}
+
+// ToBedrock converts the command, output and redstone settings of a Java command block. The command is
+// translated if the Context has a command translator, and kept as it is if it cannot be translated.
+func (commandBlock) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	command, _ := data["Command"].(string)
+	if ctx.Commands != nil && command != "" {
+		translated, err := ctx.Commands.Translate(command)
+		if err != nil {
+			if ctx.Untranslatable != nil {
+				ctx.Untranslatable(command, err)
+			}
+		} else {
+			command = translated
+		}
+	}
+	lastOutput, _ := formattedText(data["LastOutput"], ctx)
+	customName, _ := formattedText(data["CustomName"], ctx)
+	successCount, _ := nbtutil.Int(data["SuccessCount"])
+	lastExecution, _ := nbtutil.Int(data["LastExecution"])
+
+	auto := boolean(data["auto"])
+	conditional := ctx.Block.Properties["conditional"] == "true"
+	return map[string]any{
+		"id":               "CommandBlock",
+		"Command":          command,
+		"CustomName":       customName,
+		"LastOutput":       lastOutput,
+		"LastOutputParams": []any{},
+		"TrackOutput":      byteBool(data["TrackOutput"] == nil || boolean(data["TrackOutput"])),
+		"SuccessCount":     int32(successCount),
+		"LastExecution":    lastExecution,
+		"auto":             byteBool(auto),
+		"powered":          byteBool(boolean(data["powered"])),
+		"conditionMet":     byteBool(boolean(data["conditionMet"])),
+		"conditionalMode":  byteBool(conditional),
+		"LPCommandMode":    commandModes[ctx.Block.Name],
+		// Bedrock spells this field without the t.
+		"LPCondionalMode":    byteBool(conditional),
+		"LPRedstoneMode":     byteBool(!auto),
+		"TickDelay":          int32(0),
+		"ExecuteOnFirstTick": uint8(1),
+	}, nil
+}
+
+// structureBlock converts structure blocks.
+type structureBlock struct{}
+
+var (
+	// structureModes maps the Java structure block modes to the Bedrock structure block modes.
+	structureModes = map[string]int32{"DATA": 0, "SAVE": 1, "LOAD": 2, "CORNER": 3}
+	// structureRotations maps the Java structure rotations to the Bedrock rotations.
+	structureRotations = map[string]uint8{"NONE": 0, "CLOCKWISE_90": 1, "CLOCKWISE_180": 2, "COUNTERCLOCKWISE_90": 3}
+	// structureMirrors maps the Java structure mirrors to the Bedrock mirrors. Java LEFT_RIGHT flips the Z axis and
+	// FRONT_BACK the X axis.
+	structureMirrors = map[string]uint8{"NONE": 0, "FRONT_BACK": 1, "LEFT_RIGHT": 2}
+)
+
+// ToBedrock converts the name, bounds, mode and placement settings of a Java structure block. Structures in the
+// minecraft namespace are placed in the mystructure namespace that Bedrock uses for structures saved in worlds.
+func (structureBlock) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	name, _ := data["name"].(string)
+	if strings.HasPrefix(name, "minecraft:") {
+		name = "mystructure:" + strings.TrimPrefix(name, "minecraft:")
+	}
+	metadata, _ := data["metadata"].(string)
+	mode, _ := data["mode"].(string)
+	rotation, _ := data["rotation"].(string)
+	mirror, _ := data["mirror"].(string)
+	integrity := float32(1)
+	if v, ok := data["integrity"].(float32); ok {
+		integrity = v
+	}
+	seed, _ := nbtutil.Int(data["seed"])
+
+	m := map[string]any{
+		"id":               "StructureBlock",
+		"structureName":    name,
+		"dataField":        metadata,
+		"data":             structureModes[mode],
+		"rotation":         structureRotations[rotation],
+		"mirror":           structureMirrors[mirror],
+		"ignoreEntities":   byteBool(boolean(data["ignoreEntities"])),
+		"includePlayers":   uint8(0),
+		"showBoundingBox":  byteBool(boolean(data["showboundingbox"])),
+		"isPowered":        byteBool(boolean(data["powered"])),
+		"removeBlocks":     uint8(0),
+		"integrity":        integrity * 100,
+		"seed":             seed,
+		"redstoneSaveMode": int32(0),
+		"animationMode":    uint8(0),
+		"animationSeconds": float32(0),
+	}
+	for _, axis := range []string{"X", "Y", "Z"} {
+		offset, _ := nbtutil.Int(data["pos"+axis])
+		size, _ := nbtutil.Int(data["size"+axis])
+		lower := strings.ToLower(axis)
+		m[lower+"StructureOffset"], m[lower+"StructureSize"] = int32(offset), int32(size)
+	}
+	return m, nil
+}
@@ -0,0 +1,183 @@
+package blockentity
+
+import (
+	"fmt"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"github.com/justtaldevelops/mcanvil/items"
+	"strings"
+)
+
+func init() {
+	Register(container{id: "Chest"}, "minecraft:chest", "minecraft:trapped_chest")
+	Register(container{id: "EnderChest"}, "minecraft:ender_chest")
+	Register(container{id: "Barrel"}, "minecraft:barrel")
+	Register(container{id: "ShulkerBox"}, "minecraft:shulker_box")
+	Register(container{id: "Hopper"}, "minecraft:hopper")
+	Register(container{id: "Dispenser"}, "minecraft:dispenser")
+	Register(container{id: "Dropper"}, "minecraft:dropper")
+	Register(furnace{id: "Furnace"}, "minecraft:furnace")
+	Register(furnace{id: "BlastFurnace"}, "minecraft:blast_furnace")
+	Register(furnace{id: "Smoker"}, "minecraft:smoker")
+	Register(brewingStand{}, "minecraft:brewing_stand")
+}
+
+// container converts block entities that hold items in the same slots in both editions, such as chests.
+type container struct {
+	// id is the Bedrock block entity ID of the container.
+	id string
+}
+
+// ToBedrock converts the items, custom name and loot table of a Java container. Double chests are paired, and
+// shulker boxes get the facing of their block.
+func (c container) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	m, err := containerData(c.id, data, ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch c.id {
+	case "Chest":
+		chestPair(m, ctx)
+	case "ShulkerBox":
+		if face, ok := faceByName(ctx.Block.Properties["facing"]); ok {
+			m["facing"] = uint8(face)
+		}
+	case "Hopper":
+		cooldown, _ := nbtutil.Int(data["TransferCooldown"])
+		m["TransferCooldown"] = int32(cooldown)
+	}
+	return m, nil
+}
+
+// furnace converts furnaces, blast furnaces and smokers.
+type furnace struct {
+	// id is the Bedrock block entity ID of the furnace.
+	id string
+}
+
+// ToBedrock converts the items and cook timers of a Java furnace. Furnaces saved before 1.21.4 do not store the
+// burn duration of their fuel, so the remaining burn time is used in its place.
+func (f furnace) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	m, err := containerData(f.id, data, ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	burnTime, ok := nbtutil.Int(data["lit_time_remaining"])
+	if !ok {
+		burnTime, _ = nbtutil.Int(data["BurnTime"])
+	}
+	burnDuration, ok := nbtutil.Int(data["lit_total_time"])
+	if !ok {
+		burnDuration = burnTime
+	}
+	cookTime, ok := nbtutil.Int(data["cooking_time_spent"])
+	if !ok {
+		cookTime, _ = nbtutil.Int(data["CookTime"])
+	}
+	m["BurnTime"], m["BurnDuration"], m["CookTime"] = int16(burnTime), int16(burnDuration), int16(cookTime)
+	m["StoredXPInt"] = int32(0)
+	return m, nil
+}
+
+// brewingStandSlots maps the slots of Java brewing stands to those of Bedrock brewing stands. Java puts the
+// bottles first and the ingredient after, while Bedrock puts the ingredient first.
+var brewingStandSlots = map[uint8]uint8{0: 1, 1: 2, 2: 3, 3: 0, 4: 4}
+
+// brewingStand converts brewing stands.
+type brewingStand struct{}
+
+// ToBedrock converts the items, brew time and fuel of a Java brewing stand.
+func (brewingStand) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	m, err := containerData("BrewingStand", data, ctx, brewingStandSlots)
+	if err != nil {
+		return nil, err
+	}
+	cookTime, _ := nbtutil.Int(data["BrewTime"])
+	fuel, _ := nbtutil.Int(data["Fuel"])
+	m["CookTime"], m["FuelAmount"], m["FuelTotal"] = int16(cookTime), int16(fuel), int16(20)
+	return m, nil
+}
+
+// containerData converts the parts shared by all Java containers: the items, custom name and loot table. Slots
+// are mapped using the slots passed, or kept as they are if it is nil.
+func containerData(id string, data map[string]any, ctx Context, slots map[uint8]uint8) (map[string]any, error) {
+	m := map[string]any{"id": id}
+	list, _ := data["Items"].([]any)
+	bedrockItems := make([]any, 0, len(list))
+	for _, v := range list {
+		item, _ := v.(map[string]any)
+		converted, err := items.ToBedrock(item, ctx.DataVersion, ctx.Text)
+		if err != nil {
+			return nil, fmt.Errorf("item: %w", err)
+		}
+		if slot, ok := converted["Slot"].(uint8); ok && slots != nil {
+			bedrockSlot, ok := slots[slot]
+			if !ok {
+				continue
+			}
+			converted["Slot"] = bedrockSlot
+		}
+		bedrockItems = append(bedrockItems, converted)
+	}
+	if id != "EnderChest" {
+		// The items of ender chests belong to the players using them.
+		m["Items"] = bedrockItems
+	}
+
+	if name, ok := data["CustomName"]; ok {
+		customName, err := formattedText(name, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("custom name: %w", err)
+		}
+		m["CustomName"] = customName
+	}
+	if lootTable, ok := data["LootTable"].(string); ok {
+		m["LootTable"] = bedrockLootTable(lootTable)
+		seed, _ := nbtutil.Int(data["LootTableSeed"])
+		m["LootTableSeed"] = int32(seed)
+	}
+	return m, nil
+}
+
+// bedrockLootTable converts a Java loot table, such as "minecraft:chests/simple_dungeon", to the path of the
+// Bedrock loot table, such as "loot_tables/chests/simple_dungeon.json".
+func bedrockLootTable(lootTable string) string {
+	lootTable = strings.TrimPrefix(lootTable, "minecraft:")
+	if i := strings.Index(lootTable, ":"); i != -1 {
+		// Loot tables of other namespaces are looked up in a folder named after the namespace.
+		lootTable = lootTable[:i] + "/" + lootTable[i+1:]
+	}
+	return "loot_tables/" + lootTable + ".json"
+}
+
+// chestPair sets the pairing of a Bedrock chest from the type and facing of the Java chest block. Bedrock stores
+// the position of the other half in pairx and pairz, and marks the half whose items are shown first as the lead.
+func chestPair(m map[string]any, ctx Context) {
+	facing, ok := faceByName(ctx.Block.Properties["facing"])
+	if !ok {
+		return
+	}
+	var side cube.Face
+	switch ctx.Block.Properties["type"] {
+	case "left":
+		side = facing.RotateRight()
+	case "right":
+		side = facing.RotateLeft()
+		// Java shows the items of the right half first.
+		m["pairlead"] = uint8(1)
+	default:
+		return
+	}
+	pair := ctx.Pos.Side(side)
+	m["pairx"], m["pairz"] = int32(pair.X()), int32(pair.Z())
+}
+
+// faceByName returns the face with the name passed, such as "north".
+func faceByName(name any) (cube.Face, bool) {
+	for _, f := range cube.Faces() {
+		if f.String() == name {
+			return f, true
+		}
+	}
+	return 0, false
+}
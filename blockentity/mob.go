@@ -0,0 +1,179 @@
+package blockentity
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/entities"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+)
+
+func init() {
+	Register(spawner{}, "minecraft:mob_spawner")
+	Register(beehive{}, "minecraft:beehive")
+	Register(beacon{}, "minecraft:beacon")
+	Register(conduit{}, "minecraft:conduit")
+}
+
+// spawner converts mob spawners.
+type spawner struct{}
+
+// ToBedrock converts the spawned entity, spawn potentials, delays and ranges of a Java spawner. Entities that
+// Bedrock does not have leave the spawner empty.
+func (spawner) ToBedrock(data map[string]any, _ Context) (map[string]any, error) {
+	m := map[string]any{
+		"id":                  "MobSpawner",
+		"EntityIdentifier":    "",
+		"DisplayEntityWidth":  float32(0.8),
+		"DisplayEntityHeight": float32(1.8),
+		"DisplayEntityScale":  float32(1),
+	}
+	for _, k := range []string{"Delay", "MinSpawnDelay", "MaxSpawnDelay", "SpawnCount", "MaxNearbyEntities", "RequiredPlayerRange", "SpawnRange"} {
+		if v, ok := nbtutil.Int(data[k]); ok {
+			m[k] = int16(v)
+		}
+	}
+	spawnData, _ := data["SpawnData"].(map[string]any)
+	if id, ok := spawnEntity(spawnData); ok {
+		m["EntityIdentifier"] = id
+		m["SpawnData"] = map[string]any{"TypeId": id, "Properties": map[string]any{}}
+	}
+
+	list, _ := data["SpawnPotentials"].([]any)
+	potentials := make([]any, 0, len(list))
+	for _, v := range list {
+		potential, _ := v.(map[string]any)
+		// Spawn potentials hold their entity in "data" and "weight" since 1.18, and in "Entity" and "Weight"
+		// before.
+		entity, ok := potential["data"].(map[string]any)
+		if !ok {
+			entity = map[string]any{"entity": potential["Entity"]}
+		}
+		weight, ok := nbtutil.Int(potential["weight"])
+		if !ok {
+			weight, _ = nbtutil.Int(potential["Weight"])
+		}
+		if id, ok := spawnEntity(entity); ok {
+			potentials = append(potentials, map[string]any{"TypeId": id, "Weight": int32(weight), "Properties": map[string]any{}})
+		}
+	}
+	m["SpawnPotentials"] = potentials
+	return m, nil
+}
+
+// spawnEntity returns the Bedrock entity ID of the entity spawned by the spawn data passed. The entity is held
+// in "entity" since 1.18, while older spawn data is the entity itself.
+func spawnEntity(spawnData map[string]any) (string, bool) {
+	entity, ok := spawnData["entity"].(map[string]any)
+	if !ok {
+		entity = spawnData
+	}
+	id, _ := entity["id"].(string)
+	if id == "" {
+		return "", false
+	}
+	return entities.ConvertToBedrock(namespaced(id))
+}
+
+// beehive converts beehives and bee nests.
+type beehive struct{}
+
+// ToBedrock converts the bees in a Java beehive. Only the type of the bees is kept, so bees leave the hive with
+// the default state of a new bee.
+func (beehive) ToBedrock(data map[string]any, _ Context) (map[string]any, error) {
+	list, ok := data["bees"].([]any)
+	if !ok {
+		list, _ = data["Bees"].([]any)
+	}
+	occupants := make([]any, 0, len(list))
+	for _, v := range list {
+		bee, _ := v.(map[string]any)
+		// Bees use snake case keys since 1.20.5.
+		entity, ok := bee["entity_data"].(map[string]any)
+		if !ok {
+			entity, _ = bee["EntityData"].(map[string]any)
+		}
+		ticksInHive, ok := nbtutil.Int(bee["ticks_in_hive"])
+		if !ok {
+			ticksInHive, _ = nbtutil.Int(bee["TicksInHive"])
+		}
+		minTicks, ok := nbtutil.Int(bee["min_ticks_in_hive"])
+		if !ok {
+			minTicks, _ = nbtutil.Int(bee["MinOccupationTicks"])
+		}
+		id, _ := entity["id"].(string)
+		if id == "" {
+			id = "minecraft:bee"
+		}
+		bedrockID, ok := entities.ConvertToBedrock(namespaced(id))
+		if !ok {
+			continue
+		}
+		occupants = append(occupants, map[string]any{
+			"ActorIdentifier": bedrockID + "<>",
+			"SaveData":        map[string]any{"identifier": bedrockID},
+			"TicksLeftToStay": int32(maxInt64(minTicks-ticksInHive, 0)),
+		})
+	}
+	return map[string]any{"id": "Beehive", "Occupants": occupants, "ShouldSpawnBees": uint8(0)}, nil
+}
+
+// beaconEffects maps the names of the effects a beacon can give to their IDs, which are the same in Bedrock and
+// in Java versions that saved effect IDs.
+var beaconEffects = map[string]int32{
+	"minecraft:speed":        1,
+	"minecraft:haste":        3,
+	"minecraft:strength":     5,
+	"minecraft:jump_boost":   8,
+	"minecraft:regeneration": 10,
+	"minecraft:resistance":   11,
+}
+
+// beacon converts beacons.
+type beacon struct{}
+
+// ToBedrock converts the effects of a Java beacon. The level of the beacon is not stored by Bedrock, which
+// computes it from the pyramid below the beacon.
+func (beacon) ToBedrock(data map[string]any, _ Context) (map[string]any, error) {
+	primary, err := beaconEffect(data, "primary_effect", "Primary")
+	if err != nil {
+		return nil, err
+	}
+	secondary, err := beaconEffect(data, "secondary_effect", "Secondary")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"id": "Beacon", "primary": primary, "secondary": secondary}, nil
+}
+
+// beaconEffect returns the Bedrock effect ID of a beacon effect, which is stored by name since 1.20.2 and by ID
+// before. Zero is returned if the beacon has no such effect.
+func beaconEffect(data map[string]any, nameKey, idKey string) (int32, error) {
+	if name, ok := data[nameKey].(string); ok {
+		id, ok := beaconEffects[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown beacon effect %v", name)
+		}
+		return id, nil
+	}
+	id, _ := nbtutil.Int(data[idKey])
+	if id < 0 {
+		return 0, nil
+	}
+	return int32(id), nil
+}
+
+// conduit converts conduits.
+type conduit struct{}
+
+// ToBedrock converts a Java conduit. Bedrock finds the state and target of conduits again when they are loaded,
+// so the conduit starts out inactive without a target.
+func (conduit) ToBedrock(map[string]any, Context) (map[string]any, error) {
+	return map[string]any{"id": "Conduit", "Active": uint8(0), "Target": int64(-1)}, nil
+}
+
+// maxInt64 returns the larger of two integers.
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
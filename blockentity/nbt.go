@@ -0,0 +1,17 @@
+package blockentity
+
+import "github.com/justtaldevelops/mcanvil/internal/nbtutil"
+
+// boolean returns true if the tag passed is a non-zero integer tag.
+func boolean(v any) bool {
+	n, _ := nbtutil.Int(v)
+	return n != 0
+}
+
+// byteBool converts a bool to a byte tag.
+func byteBool(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package blockentity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SignSidesProtocol is the Bedrock protocol version of Minecraft 1.20, which added text on the back of signs and
+// moved the text of signs into the FrontText and BackText compounds.
+const SignSidesProtocol = 589
+
+func init() {
+	Register(sign{id: "Sign"}, "minecraft:sign")
+	Register(sign{id: "HangingSign"}, "minecraft:hanging_sign")
+}
+
+// sign converts standing, wall and hanging signs of any wood type.
+type sign struct {
+	// id is the Bedrock block entity ID of the sign.
+	id string
+}
+
+// signText is the text on one side of a sign.
+type signText struct {
+	// messages holds the four lines of the side as Java text components.
+	messages []any
+	color    string
+	glowing  bool
+}
+
+// ToBedrock converts a Java sign, saved either before or after 1.20, to a Bedrock sign.
+func (s sign) ToBedrock(data map[string]any, ctx Context) (map[string]any, error) {
+	var front, back signText
+	if frontText, ok := data["front_text"].(map[string]any); ok {
+		front = readSignText(frontText)
+		backText, _ := data["back_text"].(map[string]any)
+		back = readSignText(backText)
+	} else {
+		// Signs saved before 1.20 only have text on the front.
+		front.messages = []any{data["Text1"], data["Text2"], data["Text3"], data["Text4"]}
+		front.color, _ = data["Color"].(string)
+		front.glowing = boolean(data["GlowingText"])
+	}
+
+	frontData, err := s.bedrockText(front, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("front text: %w", err)
+	}
+	backData, err := s.bedrockText(back, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("back text: %w", err)
+	}
+	m := map[string]any{
+		"id":        s.id,
+		"FrontText": frontData,
+		"BackText":  backData,
+		"IsWaxed":   byteBool(boolean(data["is_waxed"])),
+	}
+	if ctx.Protocol < SignSidesProtocol {
+		// Older Bedrock versions only have text on the front, stored in the root compound. Newer versions ignore
+		// these fields if FrontText is present.
+		for k, v := range frontData {
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+// bedrockText converts the text on one side of a sign to the Bedrock compound holding it.
+func (sign) bedrockText(t signText, ctx Context) (map[string]any, error) {
+	lines := make([]string, 0, len(t.messages))
+	for _, message := range t.messages {
+		line, err := formattedText(message, ctx)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return map[string]any{
+		"Text":              strings.TrimRight(strings.Join(lines, "\n"), "\n"),
+		"TextOwner":         "",
+		"SignTextColor":     signColour(t.color),
+		"IgnoreLighting":    byteBool(t.glowing),
+		"HideGlowOutline":   uint8(0),
+		"PersistFormatting": uint8(1),
+	}, nil
+}
+
+// readSignText reads a front_text or back_text compound of a Java sign.
+func readSignText(m map[string]any) signText {
+	t := signText{glowing: boolean(m["has_glowing_text"])}
+	t.color, _ = m["color"].(string)
+	t.messages, _ = m["messages"].([]any)
+	return t
+}
+
+// signColours holds the ARGB text colours used by Bedrock signs for every dye colour. Black is the default colour
+// of sign text.
+var signColours = map[string]uint32{
+	"white":      0xfff0f0f0,
+	"orange":     0xfff9801d,
+	"magenta":    0xffc74ebd,
+	"light_blue": 0xff3ab3da,
+	"yellow":     0xfffed83d,
+	"lime":       0xff80c71f,
+	"pink":       0xfff38baa,
+	"gray":       0xff474f52,
+	"light_gray": 0xff9d9d97,
+	"cyan":       0xff169c9c,
+	"purple":     0xff8932b8,
+	"blue":       0xff3c44aa,
+	"brown":      0xff835432,
+	"green":      0xff5e7c16,
+	"red":        0xffb02e26,
+	"black":      0xff000000,
+}
+
+// signColour converts the name of the dye colour of Java sign text to the ARGB colour used by Bedrock.
+func signColour(colour string) int32 {
+	c, ok := signColours[colour]
+	if !ok {
+		c = signColours["black"]
+	}
+	return int32(c)
+}
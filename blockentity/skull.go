@@ -0,0 +1,73 @@
+package blockentity
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"github.com/justtaldevelops/mcanvil/states"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(skull{}, "minecraft:skull")
+	RegisterJava(skull{}, "Skull")
+}
+
+// skullTypes holds the names of the Java skull blocks, ordered by their Bedrock skull type.
+var skullTypes = []string{
+	"skeleton_skull", "wither_skeleton_skull", "zombie_head", "player_head", "creeper_head", "dragon_head", "piglin_head",
+}
+
+// skull converts mob heads and skulls. Java stores the type and rotation of skulls in the block, while Bedrock
+// stores them in the block entity. Bedrock cannot show player skins on heads, so the owner of player heads is
+// not kept.
+type skull struct{}
+
+// ToBedrock converts a Java skull, taking its type and rotation from its block.
+func (skull) ToBedrock(_ map[string]any, ctx Context) (map[string]any, error) {
+	name := strings.TrimPrefix(ctx.Block.Name, "minecraft:")
+	name = strings.Replace(strings.Replace(name, "_wall_skull", "_skull", 1), "_wall_head", "_head", 1)
+	skullType := -1
+	for i, t := range skullTypes {
+		if t == name {
+			skullType = i
+		}
+	}
+	if skullType == -1 {
+		return nil, fmt.Errorf("unknown skull %v", ctx.Block.Name)
+	}
+	var rotation float32
+	if v, ok := ctx.Block.Properties["rotation"].(string); ok {
+		r, _ := strconv.Atoi(v)
+		rotation = float32(r) * 22.5
+	}
+	return map[string]any{
+		"id":             "Skull",
+		"SkullType":      uint8(skullType),
+		"Rotation":       rotation,
+		"MouthMoving":    uint8(0),
+		"MouthTickCount": int32(0),
+	}, nil
+}
+
+// ToJava converts a Bedrock skull, returning the Java skull block of its type and rotation. Wall skulls keep the
+// facing of the block passed.
+func (skull) ToJava(data map[string]any, ctx Context) (map[string]any, states.Block, error) {
+	skullType, _ := nbtutil.Int(data["SkullType"])
+	if skullType < 0 || int(skullType) >= len(skullTypes) {
+		return nil, ctx.Block, fmt.Errorf("unknown skull type %v", skullType)
+	}
+	name := skullTypes[skullType]
+	properties := make(map[string]any, len(ctx.Block.Properties))
+	for k, v := range ctx.Block.Properties {
+		properties[k] = v
+	}
+	if _, wall := properties["facing"]; wall {
+		name = strings.Replace(strings.Replace(name, "_skull", "_wall_skull", 1), "_head", "_wall_head", 1)
+	} else {
+		rotation, _ := data["Rotation"].(float32)
+		properties["rotation"] = strconv.Itoa(int(math.Round(float64(rotation)/22.5)) & 15)
+	}
+	return map[string]any{"id": "minecraft:skull"}, states.Block{Name: "minecraft:" + name, Properties: properties}, nil
+}
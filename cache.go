@@ -0,0 +1,48 @@
+package mcanvil
+
+import (
+	"github.com/justtaldevelops/mcanvil/states"
+	"sync"
+)
+
+// blockCache caches the Bedrock blocks that Java state IDs convert to. A single cache is shared by all regions
+// converted by a converter, so that every state is converted only once per block state mapper, rather than once
+// per section. A blockCache is safe for concurrent use. A nil cache caches nothing.
+type blockCache struct {
+	mu      sync.RWMutex
+	entries map[blockCacheKey]bedrockBlock
+}
+
+// blockCacheKey is the key of a block in a blockCache. Chunks of different data versions may use different
+// mappers, so the mapper is part of the key.
+type blockCacheKey struct {
+	mapper *states.Mapper
+	id     int32
+}
+
+// newBlockCache creates a new, empty blockCache.
+func newBlockCache() *blockCache {
+	return &blockCache{entries: make(map[blockCacheKey]bedrockBlock)}
+}
+
+// load returns the cached Bedrock block that the Java state ID converts to using the mapper passed. False is
+// returned if the state was not yet converted with the mapper.
+func (c *blockCache) load(mapper *states.Mapper, id int32) (bedrockBlock, bool) {
+	if c == nil {
+		return bedrockBlock{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	b, ok := c.entries[blockCacheKey{mapper: mapper, id: id}]
+	return b, ok
+}
+
+// store caches the Bedrock block that the Java state ID converts to using the mapper passed.
+func (c *blockCache) store(mapper *states.Mapper, id int32, b bedrockBlock) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[blockCacheKey{mapper: mapper, id: id}] = b
+}
@@ -0,0 +1,538 @@
+package mcanvil
+
+import (
+	"fmt"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/justtaldevelops/mcanvil/biomes"
+	"github.com/justtaldevelops/mcanvil/column"
+	"github.com/justtaldevelops/mcanvil/states"
+	"math"
+	"math/bits"
+	"reflect"
+)
+
+// Chunk represents a 16x16x16 chunk of blocks. In Java, these are known as columns. Chunks decode their sections
+// lazily, even when only read from, so a Chunk and its sub-chunks are not safe for concurrent use.
+type Chunk struct {
+	DataVersion   int32
+	XPos          int32            `nbt:"xPos"`
+	YPos          int32            `nbt:"yPos"`
+	ZPos          int32            `nbt:"zPos"`
+	BlockEntities []map[string]any `nbt:"block_entities"`
+	Structures    map[string]any   `nbt:"structures"`
+	Heightmaps    struct {
+		MotionBlocking         any `nbt:"MOTION_BLOCKING"`
+		MotionBlockingNoLeaves any `nbt:"MOTION_BLOCKING_NO_LEAVES"`
+		OceanFloor             any `nbt:"OCEAN_FLOOR"`
+		OceanFloorWg           any `nbt:"OCEAN_FLOOR_WG"`
+		WorldSurface           any `nbt:"WORLD_SURFACE"`
+		WorldSurfaceWg         any `nbt:"WORLD_SURFACE_WG"`
+	}
+	Sections       []SubChunk `nbt:"sections"`
+	Lights         any        `nbt:"Lights"`
+	Entities       any        `nbt:"entities"`
+	BlockTicks     any        `nbt:"block_ticks"`
+	FluidTicks     any        `nbt:"fluid_ticks"`
+	PostProcessing any
+	CarvingMasks   any
+	InhabitedTime  int64
+	IsLightOn      byte `nbt:"isLightOn"`
+	LastUpdate     int64
+	Status         string
+
+	// dirty is true if the chunk was modified since it was last saved.
+	dirty bool
+}
+
+// SubChunk represents a 16x16 sub-chunk of a chunk. In Java, these are known as chunks or sections. Like Chunk,
+// it is not safe for concurrent use.
+type SubChunk struct {
+	Y           byte
+	BlockStates struct {
+		Palette []states.Block `nbt:"palette"`
+		Data    []int64        `nbt:"data"`
+	} `nbt:"block_states"`
+	Biomes struct {
+		Palette []string `nbt:"palette"`
+		Data    []int64  `nbt:"data"`
+	} `nbt:"biomes"`
+	SkyLight   any `nbt:"SkyLight,omitempty"`
+	BlockLight any `nbt:"BlockLight,omitempty"`
+
+	// blocks and biomes hold the decoded palettes of the sub-chunk. They are nil until first used.
+	blocks, biomes *column.DataPalette
+	// dataVersion is the data version of the chunk holding the sub-chunk, which decides how block states are
+	// packed. It is set by Chunk.Sub.
+	dataVersion int32
+}
+
+// airState is the Java state returned for positions that do not hold any block data.
+var airState = states.Block{Name: "minecraft:air"}
+
+// plainsBiome is the Java biome returned for positions that do not hold any biome data. Vanilla fills the biomes
+// of sections missing from a chunk with plains.
+const plainsBiome = "minecraft:plains"
+
+// Sub returns the sub-chunk at the section Y passed. If the chunk does not have a section at that Y, false is
+// returned.
+func (c *Chunk) Sub(y int8) (*SubChunk, bool) {
+	for i := range c.Sections {
+		if c.Sections[i].SectionY() == y {
+			c.Sections[i].dataVersion = c.DataVersion
+			return &c.Sections[i], true
+		}
+	}
+	return nil, false
+}
+
+// Range returns the vertical range of the world the chunk is in, derived from its yPos: chunks starting below Y 0
+// span 384 blocks, others 256.
+func (c *Chunk) Range() cube.Range {
+	minY := int(c.YPos) << 4
+	if minY < 0 {
+		return cube.Range{minY, minY + 383}
+	}
+	return cube.Range{minY, minY + 255}
+}
+
+// Block returns the Java block state at the position passed. The x and z coordinates are relative to the chunk,
+// while the y coordinate is absolute. Positions in sections that are not present in the chunk hold air. A
+// *PositionError is returned if the y coordinate lies outside of the Range of the chunk.
+func (c *Chunk) Block(x uint8, y int16, z uint8) (states.Block, error) {
+	if err := c.checkY(x, int(y), z, c.Range()); err != nil {
+		return states.Block{}, err
+	}
+	return c.block(x, y, z)
+}
+
+// block returns the Java block state at the position passed, without checking the y coordinate against the range
+// of the chunk.
+func (c *Chunk) block(x uint8, y int16, z uint8) (states.Block, error) {
+	sub, ok := c.Sub(int8(y >> 4))
+	if !ok {
+		return airState, nil
+	}
+	return sub.Block(x&15, uint8(y&15), z&15)
+}
+
+// Biome returns the Java biome name at the position passed. The x and z coordinates are relative to the chunk,
+// while the y coordinate is absolute. Positions in sections that are not present in the chunk are in plains. A
+// *PositionError is returned if the y coordinate lies outside of the Range of the chunk.
+func (c *Chunk) Biome(x uint8, y int16, z uint8) (string, error) {
+	if err := c.checkY(x, int(y), z, c.Range()); err != nil {
+		return "", err
+	}
+	return c.biome(x, y, z)
+}
+
+// biome returns the Java biome name at the position passed, without checking the y coordinate against the range
+// of the chunk.
+func (c *Chunk) biome(x uint8, y int16, z uint8) (string, error) {
+	sub, ok := c.Sub(int8(y >> 4))
+	if !ok {
+		return plainsBiome, nil
+	}
+	return sub.Biome(x&15, uint8(y&15), z&15)
+}
+
+// SetBlock sets the Java block state at the position passed. The x and z coordinates are relative to the chunk,
+// while the y coordinate is absolute. A *PositionError is returned if the y coordinate lies outside of the Range
+// of the chunk.
+func (c *Chunk) SetBlock(x uint8, y int16, z uint8, state states.Block) error {
+	if err := c.checkY(x, int(y), z, c.Range()); err != nil {
+		return err
+	}
+	return c.setBlock(x, y, z, state)
+}
+
+// setBlock sets the Java block state at the position passed, without checking the y coordinate against the range
+// of the chunk.
+func (c *Chunk) setBlock(x uint8, y int16, z uint8, state states.Block) error {
+	sub, ok := c.Sub(int8(y >> 4))
+	if !ok {
+		return fmt.Errorf("no section at y %v in chunk %v, %v", y, c.XPos, c.ZPos)
+	}
+	if err := sub.SetBlock(x&15, uint8(y&15), z&15, state); err != nil {
+		return err
+	}
+	c.dirty = true
+	return nil
+}
+
+// SetBiome sets the Java biome at the position passed. The x and z coordinates are relative to the chunk, while
+// the y coordinate is absolute. A *PositionError is returned if the y coordinate lies outside of the Range of the
+// chunk.
+func (c *Chunk) SetBiome(x uint8, y int16, z uint8, name string) error {
+	if err := c.checkY(x, int(y), z, c.Range()); err != nil {
+		return err
+	}
+	return c.setBiome(x, y, z, name)
+}
+
+// setBiome sets the Java biome at the position passed, without checking the y coordinate against the range of the
+// chunk.
+func (c *Chunk) setBiome(x uint8, y int16, z uint8, name string) error {
+	sub, ok := c.Sub(int8(y >> 4))
+	if !ok {
+		return fmt.Errorf("no section at y %v in chunk %v, %v", y, c.XPos, c.ZPos)
+	}
+	if err := sub.SetBiome(x&15, uint8(y&15), z&15, name); err != nil {
+		return err
+	}
+	c.dirty = true
+	return nil
+}
+
+// checkY returns a *PositionError if the y coordinate passed lies outside of the range passed, or outside of the
+// sections a chunk can hold. The x and z coordinates are relative to the chunk.
+func (c *Chunk) checkY(x uint8, y int, z uint8, r cube.Range) error {
+	// Sections are indexed by a signed byte, so positions beyond them would wrap around onto other sections.
+	if y < r.Min() || y > r.Max() || y < math.MinInt8<<4 || y > math.MaxInt8<<4|15 {
+		return &PositionError{Pos: cube.Pos{int(c.XPos)<<4 | int(x&15), y, int(c.ZPos)<<4 | int(z&15)}, Range: r}
+	}
+	return nil
+}
+
+// SectionY returns the signed Y of the sub-chunk. The Y is stored as a byte, so sections below Y 0 would otherwise
+// have large positive indices.
+func (s *SubChunk) SectionY() int8 {
+	return int8(s.Y)
+}
+
+// Block returns the Java block state at the position passed, relative to the sub-chunk.
+func (s *SubChunk) Block(x, y, z uint8) (states.Block, error) {
+	p, err := s.BlockPalette()
+	if err != nil {
+		return states.Block{}, err
+	}
+	id, err := p.Get(column.BlockPos{int32(x), int32(y), int32(z)})
+	if err != nil {
+		return states.Block{}, err
+	}
+	state, ok := states.IDToJavaState(id)
+	if !ok {
+		return states.Block{}, fmt.Errorf("could not find state for id: %d", id)
+	}
+	return state, nil
+}
+
+// Biome returns the Java biome name at the position passed, relative to the sub-chunk. Biomes are stored in
+// cells of 4x4x4 blocks, so all positions within the same cell share a biome.
+func (s *SubChunk) Biome(x, y, z uint8) (string, error) {
+	p, err := s.BiomePalette()
+	if err != nil {
+		return "", err
+	}
+	id, err := p.Get(column.BlockPos{int32(x >> 2), int32(y >> 2), int32(z >> 2)})
+	if err != nil {
+		return "", err
+	}
+	name, ok := biomes.IDToJavaName(id)
+	if !ok {
+		return "", fmt.Errorf("could not find biome name for id: %d", id)
+	}
+	return name, nil
+}
+
+// BiomeCells returns the Java biome names of the 4x4x4 cells of the sub-chunk, indexed by y<<4|z<<2|x, where x, y
+// and z are the coordinates of the cell.
+func (s *SubChunk) BiomeCells() ([]string, error) {
+	p, err := s.BiomePalette()
+	if err != nil {
+		return nil, err
+	}
+	names, indices, err := biomeIndices(p)
+	if err != nil {
+		return nil, &SectionError{Y: s.SectionY(), Err: err}
+	}
+	cells := make([]string, len(indices))
+	for i, index := range indices {
+		cells[i] = names[index]
+	}
+	return cells, nil
+}
+
+// SetBlock sets the Java block state at the position passed, relative to the sub-chunk.
+func (s *SubChunk) SetBlock(x, y, z uint8, state states.Block) error {
+	id, ok := states.JavaStateToID(state)
+	if !ok {
+		return fmt.Errorf("could not find block id for state %v", state)
+	}
+	p, err := s.BlockPalette()
+	if err != nil {
+		return err
+	}
+	_, err = p.Set(column.BlockPos{int32(x), int32(y), int32(z)}, id)
+	return err
+}
+
+// SetBiome sets the Java biome of the 4x4x4 cell holding the position passed, relative to the sub-chunk.
+func (s *SubChunk) SetBiome(x, y, z uint8, name string) error {
+	id, ok := biomes.JavaNameToID(name)
+	if !ok {
+		return fmt.Errorf("could not find biome id for name: %v", name)
+	}
+	p, err := s.BiomePalette()
+	if err != nil {
+		return err
+	}
+	_, err = p.Set(column.BlockPos{int32(x >> 2), int32(y >> 2), int32(z >> 2)}, id)
+	return err
+}
+
+// BlockPalette returns the block states of the sub-chunk as a data palette of Java state IDs. The palette is
+// decoded once and reused for subsequent calls. Sub-chunks obtained through Chunk.Sub decode block states packed
+// in the format used by the data version of their chunk.
+func (s *SubChunk) BlockPalette() (*column.DataPalette, error) {
+	if s.blocks != nil {
+		return s.blocks, nil
+	}
+	rawBlockPalette := make([]int32, 0, len(s.BlockStates.Palette))
+	for _, state := range s.BlockStates.Palette {
+		id, ok := states.JavaStateToID(state)
+		if !ok {
+			return nil, fmt.Errorf("could not find block id for state %v", state)
+		}
+		rawBlockPalette = append(rawBlockPalette, id)
+	}
+	p, err := decodeBlockPalette(rawBlockPalette, s.BlockStates.Data, s.dataVersion)
+	if err != nil {
+		return nil, &SectionError{Y: s.SectionY(), Err: err}
+	}
+	s.blocks = p
+	return p, nil
+}
+
+// BiomePalette returns the biomes of the sub-chunk as a data palette of Java biome IDs. The palette is decoded
+// once and reused for subsequent calls.
+func (s *SubChunk) BiomePalette() (*column.DataPalette, error) {
+	if s.biomes != nil {
+		return s.biomes, nil
+	}
+	rawBiomePalette := make([]int32, 0, len(s.Biomes.Palette))
+	for _, name := range s.Biomes.Palette {
+		id, ok := biomes.JavaNameToID(name)
+		if !ok {
+			return nil, fmt.Errorf("could not find biome id for name: %v", name)
+		}
+		rawBiomePalette = append(rawBiomePalette, id)
+	}
+	p, err := decodeBiomePalette(rawBiomePalette, s.Biomes.Data)
+	if err != nil {
+		return nil, &SectionError{Y: s.SectionY(), Err: err}
+	}
+	s.biomes = p
+	return p, nil
+}
+
+// blockPaletteType returns the palette type of block states, with its global palette sized for the Java states
+// currently registered.
+func blockPaletteType() column.PaletteType {
+	return column.ChunkPaletteType().WithRegistrySize(states.Count())
+}
+
+// biomePaletteType returns the palette type of biomes, with its global palette sized for the biomes known.
+func biomePaletteType() column.PaletteType {
+	return column.BiomePaletteType().WithRegistrySize(biomes.Count())
+}
+
+// decodeBlockPalette decodes the packed block states of a section into a data palette, using the Java state
+// IDs passed as its palette. The data is unpacked using the format of the Java data version passed.
+func decodeBlockPalette(rawBlockPalette []int32, data []int64, dataVersion int32) (*column.DataPalette, error) {
+	if len(rawBlockPalette) == 0 {
+		return nil, fmt.Errorf("empty block palette")
+	}
+	n := int32(bits.Len(uint(len(rawBlockPalette) - 1)))
+	p, t := column.Palette(column.NewGlobalPalette()), blockPaletteType()
+	if n == 0 {
+		// Like vanilla, data stored for a single state is ignored. Chunks upgraded from before 1.18 by older
+		// versions of the upgrade package may still hold it.
+		p, data = column.NewSingletonPalette(rawBlockPalette[0]), nil
+	} else if n <= t.MinimumBitsPerEntry {
+		p, n = column.NewFilledListPalette(4, rawBlockPalette), 4
+	} else {
+		// Java sections always index into their own palette, even when it needs more bits than the maximum
+		// of the palette type.
+		p = column.NewFilledMapPalette(n, rawBlockPalette)
+	}
+
+	storage, err := column.NewBitStorageFor(dataVersion, n, t.StorageSize, data)
+	if err != nil {
+		return nil, err
+	}
+	return column.NewFilledDataPalette(t, t.GlobalBitsPerEntry, p, storage), nil
+}
+
+// decodeBiomePalette decodes the packed biomes of a section into a data palette, using the Java biome IDs
+// passed as its palette.
+func decodeBiomePalette(rawBiomePalette []int32, data []int64) (*column.DataPalette, error) {
+	if len(rawBiomePalette) == 0 {
+		return nil, fmt.Errorf("empty biome palette")
+	}
+	n := int32(bits.Len(uint(len(rawBiomePalette) - 1)))
+	p, t := column.Palette(column.NewSingletonPalette(rawBiomePalette[0])), biomePaletteType()
+	if n > 0 {
+		// Like block states, Java biomes always index into the palette of the section, even when it needs more
+		// bits than the maximum of the palette type, such as in sections crossing many biome borders.
+		p = column.NewFilledListPalette(n, rawBiomePalette)
+	}
+
+	storage := column.NewEmptyBitStorage(n, t.StorageSize)
+	if len(data) > 0 {
+		var err error
+		storage, err = column.NewFilledBitStorage(n, storage.Capacity(), data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return column.NewFilledDataPalette(t, t.GlobalBitsPerEntry, p, storage), nil
+}
+
+// encode encodes the block states and biomes of the sub-chunk into the Java section format, writing them to
+// the section map passed. Sections that were never decoded are left untouched, while decoded palettes are
+// compacted first, so that states that are no longer used are not written.
+func (s *SubChunk) encode(section map[string]any) error {
+	section["Y"] = s.Y
+	if s.blocks != nil {
+		if err := s.blocks.Compact(); err != nil {
+			return err
+		}
+		palette, data, err := encodePalette(s.blocks, column.ChunkPaletteType().MinimumBitsPerEntry)
+		if err != nil {
+			return err
+		}
+		entries := make([]any, 0, len(palette))
+		for _, id := range palette {
+			state, ok := states.IDToJavaState(id)
+			if !ok {
+				return fmt.Errorf("could not find state for id: %d", id)
+			}
+			entry := map[string]any{"Name": state.Name}
+			if len(state.Properties) > 0 {
+				entry["Properties"] = state.Properties
+			}
+			entries = append(entries, entry)
+		}
+		blockStates := map[string]any{"palette": entries}
+		if len(data) > 0 {
+			blockStates["data"] = longArray(data)
+		}
+		section["block_states"] = blockStates
+	}
+	if s.biomes != nil {
+		if err := s.biomes.Compact(); err != nil {
+			return err
+		}
+		palette, data, err := encodePalette(s.biomes, 0)
+		if err != nil {
+			return err
+		}
+		entries := make([]any, 0, len(palette))
+		for _, id := range palette {
+			name, ok := biomes.IDToJavaName(id)
+			if !ok {
+				return fmt.Errorf("could not find biome name for id: %d", id)
+			}
+			entries = append(entries, name)
+		}
+		biomeStates := map[string]any{"palette": entries}
+		if len(data) > 0 {
+			biomeStates["data"] = longArray(data)
+		}
+		section["biomes"] = biomeStates
+	}
+	return nil
+}
+
+// encodePalette returns the palette entries and packed data of a data palette, as stored in Java sections.
+// Java never stores global palettes in sections, so those are repacked into a list of the states present,
+// using at least minBits bits per entry.
+func encodePalette(p *column.DataPalette, minBits int32) ([]int32, []int64, error) {
+	if _, ok := p.Palette().(*column.GlobalPalette); !ok {
+		palette := make([]int32, 0, p.Palette().Size())
+		for i := int32(0); i < p.Palette().Size(); i++ {
+			palette = append(palette, p.Palette().IDToState(i))
+		}
+		if len(palette) == 1 {
+			return palette, nil, nil
+		}
+		return palette, p.Storage().Data(), nil
+	}
+
+	palette, values, err := paletteIndices(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := int32(bits.Len(uint(len(palette) - 1)))
+	if n < minBits {
+		n = minBits
+	}
+	repacked := column.NewEmptyBitStorage(n, p.Storage().Capacity())
+	if p.Storage().Spanning() {
+		repacked = column.NewEmptySpanningBitStorage(n, p.Storage().Capacity())
+	}
+	if err := repacked.EncodeAll(values); err != nil {
+		return nil, nil, err
+	}
+	return palette, repacked.Data(), nil
+}
+
+// biomeIndices unpacks a data palette of Java biome IDs, returning the Java biome names of its palette and the
+// palette index of every 4x4x4 cell, indexed by y<<4|z<<2|x.
+func biomeIndices(p *column.DataPalette) ([]string, []int32, error) {
+	palette, indices, err := paletteIndices(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, len(palette))
+	for i, id := range palette {
+		name, ok := biomes.IDToJavaName(id)
+		if !ok {
+			return nil, nil, fmt.Errorf("could not find biome name for id: %d", id)
+		}
+		names[i] = name
+	}
+	return names, indices, nil
+}
+
+// paletteIndices unpacks the storage of a data palette, returning the states of its palette and the palette
+// index of every entry. Global palettes are reduced to the states present, so that callers can handle every
+// distinct state once rather than once per entry.
+func paletteIndices(p *column.DataPalette) ([]int32, []int32, error) {
+	indices := make([]int32, p.Storage().Capacity())
+	if _, ok := p.Palette().(*column.GlobalPalette); !ok {
+		if err := p.Storage().DecodeAll(indices); err != nil {
+			return nil, nil, err
+		}
+		palette := make([]int32, p.Palette().Size())
+		for i := range palette {
+			palette[i] = p.Palette().IDToState(int32(i))
+		}
+		for i, id := range indices {
+			if int(id) >= len(palette) {
+				return nil, nil, fmt.Errorf("palette index at %v: %w", i, &column.IndexError{Index: id, Size: int32(len(palette))})
+			}
+		}
+		return palette, indices, nil
+	}
+	if err := p.DecodeAll(indices); err != nil {
+		return nil, nil, err
+	}
+	ids, palette := make(map[int32]int32), make([]int32, 0, 16)
+	for i, state := range indices {
+		id, ok := ids[state]
+		if !ok {
+			id = int32(len(palette))
+			ids[state], palette = id, append(palette, state)
+		}
+		indices[i] = id
+	}
+	return palette, indices, nil
+}
+
+// longArray converts a slice of longs to a fixed size array, which the NBT encoder writes as a long array tag.
+func longArray(data []int64) any {
+	arr := reflect.New(reflect.ArrayOf(len(data), reflect.TypeOf(int64(0)))).Elem()
+	reflect.Copy(arr, reflect.ValueOf(data))
+	return arr.Interface()
+}
@@ -3,12 +3,12 @@ package column
 // This has effectively been copied from go-mc. Many thanks for their work.
 // https://github.com/Tnze/go-mc
 
-import (
-	"fmt"
-	"math"
-)
+// PaddedDataVersion is the Java data version of Minecraft 1.16 (20w17a), since which entries in the storage never
+// span two longs. Older versions pack entries tightly, so that an entry may start in one long and end in the next.
+const PaddedDataVersion = 2527
 
-// BitStorage implements the compacted data storage format used in chunks since Minecraft v1.16.
+// BitStorage implements the compacted data storage format used in chunks since Minecraft v1.16, as well as the
+// spanning format used before.
 // https://wiki.vg/Chunk_Format
 type BitStorage struct {
 	data []int64
@@ -17,6 +17,8 @@ type BitStorage struct {
 	bitsPerEntry   int32
 	valuesPerEntry int32
 	size           int32
+	// spanning is true if entries are packed tightly, spanning two longs where needed.
+	spanning bool
 }
 
 // NewEmptyBitStorage creates a new empty BitStorage.
@@ -38,49 +40,200 @@ func NewEmptyBitStorage(bitsPerEntry int32, size int32) *BitStorage {
 func NewFilledBitStorage(bitsPerEntry int32, size int32, data []int64) (*BitStorage, error) {
 	storage := NewEmptyBitStorage(bitsPerEntry, size)
 	if len(data) != len(storage.data) {
-		return nil, fmt.Errorf("data length %d does not match storage length %d", len(data), len(storage.data))
+		return nil, &DataLengthError{Length: len(data), Expected: len(storage.data)}
 	}
 	storage.data = data
 	return storage, nil
 }
 
+// NewEmptySpanningBitStorage creates a new empty BitStorage in the spanning format used before Minecraft 1.16.
+func NewEmptySpanningBitStorage(bitsPerEntry int32, size int32) *BitStorage {
+	storage := NewEmptyBitStorage(bitsPerEntry, size)
+	if bitsPerEntry == 0 {
+		return storage
+	}
+	storage.spanning = true
+	storage.data = make([]int64, (int64(size)*int64(bitsPerEntry)+63)/64)
+	return storage
+}
+
+// NewFilledSpanningBitStorage creates a new BitStorage in the spanning format used before Minecraft 1.16 with the
+// provided data.
+func NewFilledSpanningBitStorage(bitsPerEntry int32, size int32, data []int64) (*BitStorage, error) {
+	storage := NewEmptySpanningBitStorage(bitsPerEntry, size)
+	if len(data) != len(storage.data) {
+		return nil, &DataLengthError{Length: len(data), Expected: len(storage.data)}
+	}
+	storage.data = data
+	return storage, nil
+}
+
+// NewBitStorageFor creates a new BitStorage with the provided data, using the format of the Java data version
+// passed. If the data is empty, an empty storage is returned.
+func NewBitStorageFor(dataVersion int32, bitsPerEntry int32, size int32, data []int64) (*BitStorage, error) {
+	if dataVersion < PaddedDataVersion {
+		if len(data) == 0 {
+			return NewEmptySpanningBitStorage(bitsPerEntry, size), nil
+		}
+		return NewFilledSpanningBitStorage(bitsPerEntry, size, data)
+	}
+	if len(data) == 0 {
+		return NewEmptyBitStorage(bitsPerEntry, size), nil
+	}
+	return NewFilledBitStorage(bitsPerEntry, size, data)
+}
+
 // Capacity returns the capacity of the storage.
 func (b *BitStorage) Capacity() int32 {
 	return b.size
 }
 
+// BitsPerEntry returns the number of bits used to store each entry.
+func (b *BitStorage) BitsPerEntry() int32 {
+	return b.bitsPerEntry
+}
+
+// Spanning returns true if the storage uses the spanning format from before Minecraft 1.16.
+func (b *BitStorage) Spanning() bool {
+	return b.spanning
+}
+
+// Data returns the packed longs backing the storage.
+func (b *BitStorage) Data() []int64 {
+	return b.data
+}
+
 // Set sets the value at the given index.
 func (b *BitStorage) Set(index, value int32) error {
+	if index < 0 || index > b.size-1 {
+		return &IndexError{Index: index, Size: b.size}
+	}
 	if b.valuesPerEntry == 0 {
 		return nil
 	}
-	if index < 0 || index > b.size-1 || value < 0 || int64(value) > b.mask {
-		return fmt.Errorf("index out of data bounds (%v)", index)
+	if value < 0 || int64(value) > b.mask {
+		return &ValueError{Index: index, Value: value, BitsPerEntry: b.bitsPerEntry}
+	}
+
+	if b.spanning {
+		b.setSpanning(index, value)
+		return nil
 	}
 
 	c, offset := b.calculateIndex(index)
 	l := b.data[c]
 
-	b.data[c] = l&(b.mask<<offset^math.MaxInt64) | (int64(value)&b.mask)<<offset
+	b.data[c] = l&^(b.mask<<offset) | (int64(value)&b.mask)<<offset
 	return nil
 }
 
 // Get returns the value at the given index.
 func (b *BitStorage) Get(index int32) (int32, error) {
+	if index < 0 || index > b.size-1 {
+		return 0, &IndexError{Index: index, Size: b.size}
+	}
 	if b.valuesPerEntry == 0 {
 		return 0, nil
 	}
-	if index < 0 || index > b.size-1 {
-		return 0, fmt.Errorf("index out of data bounds (%v)", index)
+	if b.spanning {
+		return b.getSpanning(index), nil
 	}
 	c, offset := b.calculateIndex(index)
 	l := b.data[c]
 	return int32(l >> offset & b.mask), nil
 }
 
+// DecodeAll unpacks every value in the storage into out, which must have a length equal to the capacity of the
+// storage. It is considerably faster than calling Get for every index.
+func (b *BitStorage) DecodeAll(out []int32) error {
+	if int32(len(out)) != b.size {
+		return &DataLengthError{Length: len(out), Expected: int(b.size)}
+	}
+	if b.valuesPerEntry == 0 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil
+	}
+	if b.spanning {
+		for i := range out {
+			out[i] = b.getSpanning(int32(i))
+		}
+		return nil
+	}
+	mask := uint64(b.mask)
+	i := 0
+	for _, l := range b.data {
+		v := uint64(l)
+		for j := int32(0); j < b.valuesPerEntry && i < len(out); j++ {
+			out[i] = int32(v & mask)
+			v >>= b.bitsPerEntry
+			i++
+		}
+	}
+	return nil
+}
+
+// EncodeAll packs the values passed into the storage, replacing all of its values. The number of values must
+// be equal to the capacity of the storage. The storage is left unchanged if any of the values is too large.
+func (b *BitStorage) EncodeAll(values []int32) error {
+	if int32(len(values)) != b.size {
+		return &DataLengthError{Length: len(values), Expected: int(b.size)}
+	}
+	if b.valuesPerEntry == 0 {
+		return nil
+	}
+	for i, v := range values {
+		if v < 0 || int64(v) > b.mask {
+			return &ValueError{Index: int32(i), Value: v, BitsPerEntry: b.bitsPerEntry}
+		}
+	}
+	if b.spanning {
+		for i := range b.data {
+			b.data[i] = 0
+		}
+		for i, v := range values {
+			b.setSpanning(int32(i), v)
+		}
+		return nil
+	}
+	for c := range b.data {
+		var l uint64
+		start := int32(c) * b.valuesPerEntry
+		for j := int32(0); j < b.valuesPerEntry && start+j < b.size; j++ {
+			l |= uint64(values[start+j]) << (j * b.bitsPerEntry)
+		}
+		b.data[c] = int64(l)
+	}
+	return nil
+}
+
 // calculateIndex calculates the new index and offset of the given index.
 func (b *BitStorage) calculateIndex(index int32) (int32, int32) {
 	ind := index / b.valuesPerEntry
 	offset := (index - ind*b.valuesPerEntry) * b.bitsPerEntry
 	return ind, offset
 }
+
+// getSpanning returns the value at the given index of a spanning storage.
+func (b *BitStorage) getSpanning(index int32) int32 {
+	bit := int64(index) * int64(b.bitsPerEntry)
+	c, offset := bit>>6, uint(bit&63)
+	v := uint64(b.data[c]) >> offset
+	if offset+uint(b.bitsPerEntry) > 64 {
+		v |= uint64(b.data[c+1]) << (64 - offset)
+	}
+	return int32(v & uint64(b.mask))
+}
+
+// setSpanning sets the value at the given index of a spanning storage.
+func (b *BitStorage) setSpanning(index, value int32) {
+	bit := int64(index) * int64(b.bitsPerEntry)
+	c, offset := bit>>6, uint(bit&63)
+	mask, v := uint64(b.mask), uint64(value)&uint64(b.mask)
+	b.data[c] = int64(uint64(b.data[c])&^(mask<<offset) | v<<offset)
+	if offset+uint(b.bitsPerEntry) > 64 {
+		shift := 64 - offset
+		b.data[c+1] = int64(uint64(b.data[c+1])&^(mask>>shift) | v>>shift)
+	}
+}
@@ -0,0 +1,193 @@
+package column
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// wikiEntries are the entries of the example section on the chunk format page of wiki.vg, which was captured from
+// a vanilla chunk. The entries are packed using 5 bits per entry.
+var wikiEntries = []int32{1, 2, 2, 3, 4, 4, 5, 6, 6, 4, 8, 0, 7, 4, 3, 13, 15, 16, 9, 14, 10, 12, 0, 2}
+
+func TestBitStorageVanilla(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		spanning bool
+		longs    []int64
+	}{
+		// Since 1.16, the 13th entry starts a new long.
+		{name: "padded", longs: []int64{0x0020863148418841, 0x01018A7260F68C87}},
+		// Before 1.16, the low four bits of the 13th entry fill up the first long.
+		{name: "spanning", spanning: true, longs: []int64{0x7020863148418841, 0x001018A7260F68C8}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dataVersion := int32(PaddedDataVersion)
+			if test.spanning {
+				dataVersion--
+			}
+			storage, err := NewBitStorageFor(dataVersion, 5, int32(len(wikiEntries)), append([]int64(nil), test.longs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if storage.Spanning() != test.spanning {
+				t.Fatalf("expected spanning %v, got %v", test.spanning, storage.Spanning())
+			}
+			out := make([]int32, len(wikiEntries))
+			if err := storage.DecodeAll(out); err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range wikiEntries {
+				if got, _ := storage.Get(int32(i)); got != v || out[i] != v {
+					t.Fatalf("entry %v: expected %v, got %v (Get) and %v (DecodeAll)", i, v, got, out[i])
+				}
+			}
+
+			encoded := NewEmptyBitStorage(5, int32(len(wikiEntries)))
+			if test.spanning {
+				encoded = NewEmptySpanningBitStorage(5, int32(len(wikiEntries)))
+			}
+			if err := encoded.EncodeAll(wikiEntries); err != nil {
+				t.Fatal(err)
+			}
+			for i, l := range test.longs {
+				if encoded.Data()[i] != l {
+					t.Fatalf("long %v: expected %#016x, got %#016x", i, l, encoded.Data()[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBitStorageRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for bitsPerEntry := int32(0); bitsPerEntry <= 32; bitsPerEntry++ {
+		for _, size := range []int32{64, 4096} {
+			for _, spanning := range []bool{false, true} {
+				values := make([]int32, size)
+				for i := range values {
+					values[i] = randomValue(r, bitsPerEntry)
+				}
+				testBitStorageRoundTrip(t, bitsPerEntry, spanning, values)
+			}
+		}
+	}
+}
+
+func TestBitStorageErrors(t *testing.T) {
+	storage := NewEmptyBitStorage(4, 4096)
+	if err := storage.Set(4096, 0); !errors.Is(err, ErrIndexOutOfBounds) {
+		t.Fatalf("expected ErrIndexOutOfBounds, got %v", err)
+	}
+	if _, err := storage.Get(-1); !errors.Is(err, ErrIndexOutOfBounds) {
+		t.Fatalf("expected ErrIndexOutOfBounds, got %v", err)
+	}
+	var valueErr *ValueError
+	if err := storage.Set(5, 16); !errors.As(err, &valueErr) || valueErr.Index != 5 || valueErr.Value != 16 {
+		t.Fatalf("expected value error at index 5, got %v", err)
+	}
+	if _, err := NewFilledBitStorage(4, 4096, make([]int64, 255)); !errors.Is(err, ErrDataLengthMismatch) {
+		t.Fatalf("expected ErrDataLengthMismatch, got %v", err)
+	}
+	if _, err := NewFilledSpanningBitStorage(5, 4096, make([]int64, 321)); !errors.Is(err, ErrDataLengthMismatch) {
+		t.Fatalf("expected ErrDataLengthMismatch, got %v", err)
+	}
+}
+
+func FuzzBitStorage(f *testing.F) {
+	f.Add(uint8(4), false, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	f.Add(uint8(5), true, []byte{0xff, 0x00, 0xff, 0x00})
+	f.Add(uint8(15), false, []byte{0x12, 0x34, 0x56, 0x78, 0x9a})
+	f.Add(uint8(32), true, []byte{0x7f, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, bitsPerEntry uint8, spanning bool, data []byte) {
+		n := int32(bitsPerEntry % 33)
+		values := make([]int32, 64)
+		for i := range values {
+			var b [4]byte
+			copy(b[:], data[minInt(i*4, len(data)):])
+			values[i] = int32(binary.LittleEndian.Uint32(b[:])) & maxValue(n)
+		}
+		testBitStorageRoundTrip(t, n, spanning, values)
+	})
+}
+
+// testBitStorageRoundTrip checks that the values passed are returned unchanged by Get and DecodeAll, both when
+// set one by one and when packed through EncodeAll, and that both result in the same longs.
+func testBitStorageRoundTrip(t *testing.T, bitsPerEntry int32, spanning bool, values []int32) {
+	t.Helper()
+	size := int32(len(values))
+	newStorage := func() *BitStorage {
+		if spanning {
+			return NewEmptySpanningBitStorage(bitsPerEntry, size)
+		}
+		return NewEmptyBitStorage(bitsPerEntry, size)
+	}
+	set, encoded := newStorage(), newStorage()
+	for i, v := range values {
+		if err := set.Set(int32(i), v); err != nil {
+			t.Fatalf("%v bits: set %v at %v: %v", bitsPerEntry, v, i, err)
+		}
+	}
+	if err := encoded.EncodeAll(values); err != nil {
+		t.Fatalf("%v bits: %v", bitsPerEntry, err)
+	}
+	if len(set.Data()) != len(encoded.Data()) {
+		t.Fatalf("%v bits: data lengths %v and %v differ", bitsPerEntry, len(set.Data()), len(encoded.Data()))
+	}
+	for i := range set.Data() {
+		if set.Data()[i] != encoded.Data()[i] {
+			t.Fatalf("%v bits: long %v differs: %#016x != %#016x", bitsPerEntry, i, set.Data()[i], encoded.Data()[i])
+		}
+	}
+
+	filled, err := NewBitStorageFor(dataVersionFor(spanning), bitsPerEntry, size, encoded.Data())
+	if err != nil {
+		t.Fatalf("%v bits: %v", bitsPerEntry, err)
+	}
+	out := make([]int32, size)
+	if err := filled.DecodeAll(out); err != nil {
+		t.Fatalf("%v bits: %v", bitsPerEntry, err)
+	}
+	for i, v := range values {
+		if bitsPerEntry == 0 {
+			v = 0
+		}
+		got, err := filled.Get(int32(i))
+		if err != nil {
+			t.Fatalf("%v bits: get %v: %v", bitsPerEntry, i, err)
+		}
+		if got != v || out[i] != v {
+			t.Fatalf("%v bits: entry %v: expected %v, got %v (Get) and %v (DecodeAll)", bitsPerEntry, i, v, got, out[i])
+		}
+	}
+}
+
+// dataVersionFor returns a data version that uses the spanning or padded storage format.
+func dataVersionFor(spanning bool) int32 {
+	if spanning {
+		return PaddedDataVersion - 1
+	}
+	return PaddedDataVersion
+}
+
+// randomValue returns a random value that fits in the number of bits passed.
+func randomValue(r *rand.Rand, bitsPerEntry int32) int32 {
+	return int32(r.Uint32()) & maxValue(bitsPerEntry)
+}
+
+// maxValue returns the largest value that fits in the number of bits passed, limited to the largest int32.
+func maxValue(bitsPerEntry int32) int32 {
+	if bitsPerEntry >= 31 {
+		return 1<<31 - 1
+	}
+	return 1<<bitsPerEntry - 1
+}
+
+// minInt returns the smallest of two ints.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
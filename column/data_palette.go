@@ -3,8 +3,7 @@ package column
 // This has effectively been ported from Geyser's MCProtocolLib. Thanks a ton!
 // https://github.com/GeyserMC/MCProtocolLib
 
-// globalPaletteBitsPerEntry is the number of bitsPerEntry per entry in the global palette.
-const globalPaletteBitsPerEntry = 14
+import "math/bits"
 
 // DataPalette is an implementation of the modern Minecraft data palette.
 type DataPalette struct {
@@ -14,13 +13,20 @@ type DataPalette struct {
 	storage *BitStorage
 	// paletteType contains the type of the palette.
 	paletteType PaletteType
-	// globalPaletteBits contains the size of the storage.
+	// globalPaletteBits contains the number of bits per entry used by the storage of the global palette.
 	globalPaletteBits int32
 }
 
-// NewEmptyChunkDataPalette creates a new empty chunk data palette.
+// NewEmptyChunkDataPalette creates a new empty chunk data palette, using the global palette size of the chunk
+// palette type.
 func NewEmptyChunkDataPalette() *DataPalette {
-	return NewChunkDataPalette(globalPaletteBitsPerEntry)
+	return NewChunkDataPalette(ChunkPaletteType().GlobalBitsPerEntry)
+}
+
+// NewEmptyBiomeDataPalette creates a new empty biome data palette, using the global palette size of the biome
+// palette type.
+func NewEmptyBiomeDataPalette() *DataPalette {
+	return NewBiomeDataPalette(BiomePaletteType().GlobalBitsPerEntry)
 }
 
 // NewChunkDataPalette creates a new chunk data palette with the globalPaletteBits given.
@@ -53,12 +59,16 @@ func NewFilledDataPalette(paletteType PaletteType, globalPaletteBits int32, pale
 	}
 }
 
-// Get returns the value at the given position.
+// Get returns the value at the given position. Errors returned are of the type *PositionError.
 func (d *DataPalette) Get(pos BlockPos) (int32, error) {
+	ind, err := d.index(pos)
+	if err != nil {
+		return 0, err
+	}
 	if d.storage != nil {
-		id, err := d.storage.Get(index(pos))
+		id, err := d.storage.Get(ind)
 		if err != nil {
-			return 0, err
+			return 0, &PositionError{Pos: pos, Err: err}
 		}
 		return d.palette.IDToState(id), nil
 	} else {
@@ -66,34 +76,127 @@ func (d *DataPalette) Get(pos BlockPos) (int32, error) {
 	}
 }
 
-// Set sets the value at the given position.
+// Palette returns the palette used to map storage IDs to states.
+func (d *DataPalette) Palette() Palette {
+	return d.palette
+}
+
+// Storage returns the bit storage holding the storage IDs of the data palette.
+func (d *DataPalette) Storage() *BitStorage {
+	return d.storage
+}
+
+// Set sets the value at the given position. The value previously at the position is returned. Errors returned
+// are of the type *PositionError.
 func (d *DataPalette) Set(pos BlockPos, state int32) (int32, error) {
-	id := d.palette.StateToID(state)
-	if id == -1 {
-		d.resize()
-		id = d.palette.StateToID(state)
+	ind, err := d.index(pos)
+	if err != nil {
+		return 0, err
+	}
+	id, ok := d.palette.StateToID(state)
+	if !ok {
+		if err := d.resize(); err != nil {
+			return 0, &PositionError{Pos: pos, Err: err}
+		}
+		if id, ok = d.palette.StateToID(state); !ok {
+			return 0, &PositionError{Pos: pos, Err: &PaletteFullError{State: state, Size: d.palette.Size()}}
+		}
 	}
 
 	if d.storage != nil {
-		ind := index(pos)
 		curr, err := d.storage.Get(ind)
 		if err != nil {
-			return 0, err
+			return 0, &PositionError{Pos: pos, Err: err}
 		}
 
 		err = d.storage.Set(ind, id)
 		if err != nil {
-			return 0, err
+			return 0, &PositionError{Pos: pos, Err: err}
 		}
-		return curr, nil
+		return d.palette.IDToState(curr), nil
 	}
 
 	// Singleton palette and the block has not changed because the palette hasn't resized
 	return state, nil
 }
 
-// resize performs a resize on the palette of the chunk.
-func (d *DataPalette) resize() {
+// DecodeAll writes the state of every entry of the data palette to out, in storage order. The length of out
+// must be equal to the storage size of the palette type.
+func (d *DataPalette) DecodeAll(out []int32) error {
+	if d.storage == nil {
+		if int32(len(out)) != d.paletteType.StorageSize {
+			return &DataLengthError{Length: len(out), Expected: int(d.paletteType.StorageSize)}
+		}
+		for i := range out {
+			out[i] = d.palette.IDToState(0)
+		}
+		return nil
+	}
+	if err := d.storage.DecodeAll(out); err != nil {
+		return err
+	}
+	if _, ok := d.palette.(*GlobalPalette); ok {
+		return nil
+	}
+	for i, id := range out {
+		out[i] = d.palette.IDToState(id)
+	}
+	return nil
+}
+
+// EncodeAll replaces every entry of the data palette with the states passed, in storage order. A new palette
+// is built from the distinct states, using the smallest palette kind and storage that can hold them.
+func (d *DataPalette) EncodeAll(states []int32) error {
+	if int32(len(states)) != d.paletteType.StorageSize {
+		return &DataLengthError{Length: len(states), Expected: int(d.paletteType.StorageSize)}
+	}
+	ids, entries := make(map[int32]int32), make([]int32, 0, 16)
+	values := make([]int32, len(states))
+	for i, state := range states {
+		id, ok := ids[state]
+		if !ok {
+			id = int32(len(entries))
+			ids[state], entries = id, append(entries, state)
+		}
+		values[i] = id
+	}
+
+	bitsPerEntry := int32(bits.Len(uint(len(entries) - 1)))
+	if bitsPerEntry == 0 {
+		d.palette, d.storage = NewSingletonPalette(entries[0]), NewEmptyBitStorage(0, d.paletteType.StorageSize)
+		return nil
+	}
+	bitsPerEntry = d.sanitizeBitsPerEntry(bitsPerEntry)
+
+	var palette Palette
+	if bitsPerEntry <= d.paletteType.MinimumBitsPerEntry {
+		palette = NewFilledListPalette(bitsPerEntry, entries)
+	} else if bitsPerEntry <= d.paletteType.MaximumBitsPerEntry {
+		palette = NewFilledMapPalette(bitsPerEntry, entries)
+	} else {
+		palette, values = NewGlobalPalette(), states
+	}
+	storage := d.newStorage(bitsPerEntry)
+	if err := storage.EncodeAll(values); err != nil {
+		return err
+	}
+	d.palette, d.storage = palette, storage
+	return nil
+}
+
+// Compact removes the states that are no longer used from the palette, such as states replaced through Set, and
+// repacks the storage using the smallest palette kind and number of bits per entry that can hold the states left.
+func (d *DataPalette) Compact() error {
+	states := make([]int32, d.paletteType.StorageSize)
+	if err := d.DecodeAll(states); err != nil {
+		return err
+	}
+	return d.EncodeAll(states)
+}
+
+// resize performs a resize on the palette of the chunk. An error is returned if the states of the palette could
+// not be moved to the resized palette, in which case the palette is left unchanged.
+func (d *DataPalette) resize() error {
 	bitsPerEntry := int32(1)
 	if _, ok := d.palette.(*SingletonPalette); !ok {
 		bitsPerEntry = d.storage.bitsPerEntry + 1
@@ -101,31 +204,49 @@ func (d *DataPalette) resize() {
 
 	bitsPerEntry = d.sanitizeBitsPerEntry(bitsPerEntry)
 	newPalette := createPalette(bitsPerEntry, d.paletteType)
-	newStorage := NewEmptyBitStorage(bitsPerEntry, d.paletteType.StorageSize)
+	newStorage := d.newStorage(bitsPerEntry)
 
-	if _, ok := d.palette.(*SingletonPalette); ok {
-		for i := int32(0); i < d.paletteType.StorageSize; i++ {
-			_ = newStorage.Set(i, 0)
+	_, singleton := d.palette.(*SingletonPalette)
+	for i := int32(0); i < d.paletteType.StorageSize; i++ {
+		var id int32
+		if !singleton {
+			id, _ = d.storage.Get(i)
 		}
-	} else {
-		for i := int32(0); i < d.paletteType.StorageSize; i++ {
-			id, _ := d.storage.Get(i)
-			_ = newStorage.Set(i, newPalette.StateToID(d.palette.IDToState(id)))
+		state := d.palette.IDToState(id)
+		newID, ok := newPalette.StateToID(state)
+		if !ok {
+			return &PaletteFullError{State: state, Size: newPalette.Size()}
+		}
+		if err := newStorage.Set(i, newID); err != nil {
+			return err
 		}
 	}
 
 	d.palette, d.storage = newPalette, newStorage
+	return nil
 }
 
-// sanitizeBitsPerEntry sanitizes the bitsPerEntry per entry of the palette.
+// newStorage creates a new empty storage with the given number of bits per entry, in the same format as the
+// current storage of the palette.
+func (d *DataPalette) newStorage(bitsPerEntry int32) *BitStorage {
+	if d.storage != nil && d.storage.spanning {
+		return NewEmptySpanningBitStorage(bitsPerEntry, d.paletteType.StorageSize)
+	}
+	return NewEmptyBitStorage(bitsPerEntry, d.paletteType.StorageSize)
+}
+
+// sanitizeBitsPerEntry sanitizes the bitsPerEntry per entry of the palette. Bits beyond the maximum of the palette
+// type are replaced with the bits of the global palette, which default to those of the palette type.
 func (d *DataPalette) sanitizeBitsPerEntry(bitsPerEntry int32) int32 {
 	if bitsPerEntry <= d.paletteType.MaximumBitsPerEntry {
 		if bitsPerEntry < d.paletteType.MinimumBitsPerEntry {
 			return d.paletteType.MinimumBitsPerEntry
 		}
 		return bitsPerEntry
+	} else if d.globalPaletteBits > d.paletteType.MaximumBitsPerEntry {
+		return d.globalPaletteBits
 	} else {
-		return globalPaletteBitsPerEntry
+		return d.paletteType.GlobalBitsPerEntry
 	}
 }
 
@@ -140,7 +261,15 @@ func createPalette(bitsPerEntry int32, paletteType PaletteType) Palette {
 	}
 }
 
-// index converts a position to an integer based index.
-func index(pos BlockPos) int32 {
-	return pos.Y()<<8 | pos.Z()<<4 | pos.X()
+// index converts a position to an integer based index. The number of bits used per axis is derived from the
+// storage size of the palette type, so that both 16x16x16 block and 4x4x4 biome palettes are indexed correctly.
+// A *PositionError is returned if the position lies outside of the palette.
+func (d *DataPalette) index(pos BlockPos) (int32, error) {
+	shift := int32(bits.Len32(uint32(d.paletteType.StorageSize-1)) / 3)
+	for _, v := range pos {
+		if v < 0 || v >= 1<<shift {
+			return 0, &PositionError{Pos: pos, Err: ErrIndexOutOfBounds}
+		}
+	}
+	return pos.Y()<<(shift*2) | pos.Z()<<shift | pos.X(), nil
 }
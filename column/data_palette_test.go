@@ -0,0 +1,181 @@
+package column
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestDataPaletteResize(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		paletteType PaletteType
+		// steps holds the number of distinct states set and the palette kind expected after setting them.
+		steps []paletteStep
+	}{
+		{name: "chunk", paletteType: ChunkPaletteType(), steps: []paletteStep{
+			{1, &SingletonPalette{}},
+			{2, &ListPalette{}},
+			{16, &ListPalette{}},
+			{17, &MapPalette{}},
+			{256, &MapPalette{}},
+			{257, &GlobalPalette{}},
+			{4096, &GlobalPalette{}},
+		}},
+		{name: "biome", paletteType: BiomePaletteType(), steps: []paletteStep{
+			{1, &SingletonPalette{}},
+			{2, &ListPalette{}},
+			{3, &MapPalette{}},
+			{8, &MapPalette{}},
+			{9, &GlobalPalette{}},
+			{64, &GlobalPalette{}},
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewFilledDataPalette(test.paletteType, test.paletteType.GlobalBitsPerEntry, NewSingletonPalette(0), NewEmptyBitStorage(0, test.paletteType.StorageSize))
+			expected := make([]int32, test.paletteType.StorageSize)
+			var set int32
+			for _, step := range test.steps {
+				// Every entry is set to a new state, starting with the first. State 0 is already in the palette.
+				for ; set < step.states; set++ {
+					pos := positionOf(set, test.paletteType)
+					if _, err := d.Set(pos, set); err != nil {
+						t.Fatalf("set %v at %v: %v", set, pos, err)
+					}
+					expected[set] = set
+				}
+				if got, want := fmt.Sprintf("%T", d.Palette()), fmt.Sprintf("%T", step.palette); got != want {
+					t.Fatalf("%v states: expected %v, got %v", step.states, want, got)
+				}
+				checkDataPalette(t, d, expected)
+			}
+			if bits := d.Storage().BitsPerEntry(); bits != test.paletteType.GlobalBitsPerEntry {
+				t.Fatalf("expected global storage of %v bits, got %v", test.paletteType.GlobalBitsPerEntry, bits)
+			}
+		})
+	}
+}
+
+// paletteStep is a step of TestDataPaletteResize.
+type paletteStep struct {
+	states  int32
+	palette Palette
+}
+
+func TestDataPaletteRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, paletteType := range []PaletteType{ChunkPaletteType(), BiomePaletteType()} {
+		for _, distinct := range []int32{1, 2, 16, 17, 256, 257, 1000} {
+			d := NewEmptyDataPalette(paletteType, paletteType.GlobalBitsPerEntry)
+			expected := make([]int32, paletteType.StorageSize)
+			for i := 0; i < int(paletteType.StorageSize)*2; i++ {
+				index := r.Int31n(paletteType.StorageSize)
+				state := r.Int31n(distinct) * 7 % (1 << paletteType.GlobalBitsPerEntry)
+				previous, err := d.Set(positionOf(index, paletteType), state)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if previous != expected[index] {
+					t.Fatalf("index %v: expected previous state %v, got %v", index, expected[index], previous)
+				}
+				expected[index] = state
+			}
+			checkDataPalette(t, d, expected)
+
+			if err := d.Compact(); err != nil {
+				t.Fatal(err)
+			}
+			checkDataPalette(t, d, expected)
+
+			encoded := NewEmptyDataPalette(paletteType, paletteType.GlobalBitsPerEntry)
+			if err := encoded.EncodeAll(expected); err != nil {
+				t.Fatal(err)
+			}
+			checkDataPalette(t, encoded, expected)
+		}
+	}
+}
+
+func TestDataPaletteErrors(t *testing.T) {
+	d := NewEmptyChunkDataPalette()
+	var posErr *PositionError
+	if _, err := d.Set(BlockPos{16, 0, 0}, 1); !errors.Is(err, ErrIndexOutOfBounds) || !errors.As(err, &posErr) || posErr.Pos != (BlockPos{16, 0, 0}) {
+		t.Fatalf("expected out of bounds error at [16 0 0], got %v", err)
+	}
+	if _, err := d.Get(BlockPos{0, -1, 0}); !errors.Is(err, ErrIndexOutOfBounds) {
+		t.Fatalf("expected ErrIndexOutOfBounds, got %v", err)
+	}
+
+	d = NewChunkDataPalette(9)
+	for i := int32(0); i < 257; i++ {
+		if _, err := d.Set(positionOf(i, ChunkPaletteType()), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Set(BlockPos{}, 1<<9); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected ErrValueTooLarge, got %v", err)
+	}
+	if _, err := d.Set(BlockPos{}, -1); !errors.Is(err, ErrPaletteFull) {
+		t.Fatalf("expected ErrPaletteFull, got %v", err)
+	}
+}
+
+func FuzzDataPalette(f *testing.F) {
+	f.Add(false, []byte{0, 0, 1, 0, 0, 2, 2, 0, 3})
+	f.Add(true, []byte{1, 0, 5, 2, 0, 6, 3, 0, 7, 4, 0, 8})
+	f.Fuzz(func(t *testing.T, biome bool, data []byte) {
+		paletteType := ChunkPaletteType()
+		if biome {
+			paletteType = BiomePaletteType()
+		}
+		d := NewEmptyDataPalette(paletteType, paletteType.GlobalBitsPerEntry)
+		expected := make([]int32, paletteType.StorageSize)
+		// Every operation is two bytes of the index to set, followed by a byte that is mixed into the state set.
+		for i := 0; i+3 <= len(data); i += 3 {
+			index := int32(binary.LittleEndian.Uint16(data[i:])) % paletteType.StorageSize
+			state := (int32(data[i+2]) * 131) % (1 << paletteType.GlobalBitsPerEntry)
+			previous, err := d.Set(positionOf(index, paletteType), state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if previous != expected[index] {
+				t.Fatalf("index %v: expected previous state %v, got %v", index, expected[index], previous)
+			}
+			expected[index] = state
+		}
+		checkDataPalette(t, d, expected)
+		if err := d.Compact(); err != nil {
+			t.Fatal(err)
+		}
+		checkDataPalette(t, d, expected)
+	})
+}
+
+// checkDataPalette checks that the states returned by Get and DecodeAll for the data palette passed match the
+// states expected, in storage order.
+func checkDataPalette(t *testing.T, d *DataPalette, expected []int32) {
+	t.Helper()
+	out := make([]int32, len(expected))
+	if err := d.DecodeAll(out); err != nil {
+		t.Fatal(err)
+	}
+	for i, state := range expected {
+		got, err := d.Get(positionOf(int32(i), d.paletteType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != state || out[i] != state {
+			t.Fatalf("index %v (%T): expected %v, got %v (Get) and %v (DecodeAll)", i, d.Palette(), state, got, out[i])
+		}
+	}
+}
+
+// positionOf returns the position of the storage index passed in a palette of the type passed.
+func positionOf(index int32, paletteType PaletteType) BlockPos {
+	if paletteType.StorageSize == BiomePaletteType().StorageSize {
+		return BlockPos{index & 3, index >> 4, (index >> 2) & 3}
+	}
+	return BlockPos{index & 15, index >> 8, (index >> 4) & 15}
+}
@@ -0,0 +1,113 @@
+package column
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrIndexOutOfBounds is matched by errors for indices or positions that lie outside of a storage or palette.
+	ErrIndexOutOfBounds = errors.New("index out of bounds")
+	// ErrValueTooLarge is matched by errors for values that do not fit in the bits per entry of a storage.
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrPaletteFull is matched by errors for states that a palette is unable to map.
+	ErrPaletteFull = errors.New("palette full")
+	// ErrDataLengthMismatch is matched by errors for data that does not have the length a storage expects.
+	ErrDataLengthMismatch = errors.New("data length mismatch")
+)
+
+// IndexError is returned when an index lies outside of a storage or palette. It matches ErrIndexOutOfBounds.
+type IndexError struct {
+	// Index is the index that was out of bounds.
+	Index int32
+	// Size is the number of entries of the storage or palette indexed.
+	Size int32
+}
+
+// Error returns the message of the error.
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("index %v out of bounds for %v entries", e.Index, e.Size)
+}
+
+// Is returns true if the target is ErrIndexOutOfBounds.
+func (e *IndexError) Is(target error) bool {
+	return target == ErrIndexOutOfBounds
+}
+
+// ValueError is returned when a value does not fit in the bits per entry of a storage. It matches
+// ErrValueTooLarge.
+type ValueError struct {
+	// Index is the index the value was set at.
+	Index int32
+	// Value is the value that did not fit.
+	Value int32
+	// BitsPerEntry is the number of bits per entry of the storage.
+	BitsPerEntry int32
+}
+
+// Error returns the message of the error.
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("value %v at index %v does not fit in %v bits", e.Value, e.Index, e.BitsPerEntry)
+}
+
+// Is returns true if the target is ErrValueTooLarge.
+func (e *ValueError) Is(target error) bool {
+	return target == ErrValueTooLarge
+}
+
+// PaletteFullError is returned when a palette is unable to map a state, even after resizing. It matches
+// ErrPaletteFull.
+type PaletteFullError struct {
+	// State is the state that could not be mapped.
+	State int32
+	// Size is the number of states in the palette.
+	Size int32
+}
+
+// Error returns the message of the error.
+func (e *PaletteFullError) Error() string {
+	return fmt.Sprintf("state %v cannot be mapped by palette of %v states", e.State, e.Size)
+}
+
+// Is returns true if the target is ErrPaletteFull.
+func (e *PaletteFullError) Is(target error) bool {
+	return target == ErrPaletteFull
+}
+
+// DataLengthError is returned when data does not have the length a storage expects. It matches
+// ErrDataLengthMismatch.
+type DataLengthError struct {
+	// Length is the length of the data.
+	Length int
+	// Expected is the length the storage expected.
+	Expected int
+}
+
+// Error returns the message of the error.
+func (e *DataLengthError) Error() string {
+	return fmt.Sprintf("data length %d does not match storage length %d", e.Length, e.Expected)
+}
+
+// Is returns true if the target is ErrDataLengthMismatch.
+func (e *DataLengthError) Is(target error) bool {
+	return target == ErrDataLengthMismatch
+}
+
+// PositionError is returned by data palettes for errors at a position in the palette. It wraps the error that
+// occurred, so that it matches the same errors.
+type PositionError struct {
+	// Pos is the position the error occurred at.
+	Pos BlockPos
+	// Err is the error that occurred.
+	Err error
+}
+
+// Error returns the message of the error.
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("position %v: %v", e.Pos, e.Err)
+}
+
+// Unwrap returns the error that occurred at the position.
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,333 @@
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteTo writes the data palette to the writer passed in the format of the Java network protocol: a byte holding
+// the bits per entry, followed by the palette as VarInts and the packed storage as a VarInt prefixed long array.
+// Spanning storages are repacked, as the network format has always been padded, and local palettes using more bits
+// than the maximum of the palette type are written as global palettes.
+func (d *DataPalette) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if _, ok := d.palette.(*SingletonPalette); ok || d.storage == nil || d.storage.bitsPerEntry == 0 {
+		buf.WriteByte(0)
+		writeVarInt(&buf, d.palette.IDToState(0))
+		writeVarInt(&buf, 0)
+		n, err := w.Write(buf.Bytes())
+		return int64(n), err
+	}
+
+	storage := d.storage
+	_, global := d.palette.(*GlobalPalette)
+	if !global && storage.bitsPerEntry > d.paletteType.MaximumBitsPerEntry {
+		// Clients read palettes with more bits than the maximum of the palette type as global palettes, so the
+		// states themselves are written instead. Sections decoded from Java worlds may have such palettes.
+		states := make([]int32, storage.size)
+		if err := d.DecodeAll(states); err != nil {
+			return 0, err
+		}
+		storage, global = NewEmptyBitStorage(d.sanitizeBitsPerEntry(storage.bitsPerEntry), storage.size), true
+		if err := storage.EncodeAll(states); err != nil {
+			return 0, err
+		}
+	} else if storage.spanning {
+		values := make([]int32, storage.size)
+		if err := storage.DecodeAll(values); err != nil {
+			return 0, err
+		}
+		storage = NewEmptyBitStorage(storage.bitsPerEntry, storage.size)
+		if err := storage.EncodeAll(values); err != nil {
+			return 0, err
+		}
+	}
+	buf.WriteByte(byte(storage.bitsPerEntry))
+	if !global {
+		writeVarInt(&buf, d.palette.Size())
+		for i := int32(0); i < d.palette.Size(); i++ {
+			writeVarInt(&buf, d.palette.IDToState(i))
+		}
+	}
+	writeVarInt(&buf, int32(len(storage.data)))
+	for _, l := range storage.data {
+		_ = binary.Write(&buf, binary.BigEndian, l)
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// ReadFrom reads a data palette in the format of the Java network protocol from the reader passed, replacing the
+// palette and storage of the data palette. The palette type of the data palette decides which palette kind is
+// used for the bits per entry read, in the same way as the Java client does.
+func (d *DataPalette) ReadFrom(r io.Reader) (int64, error) {
+	nr := &networkReader{r: r}
+	bitsPerEntry, err := nr.readByte()
+	if err != nil {
+		return nr.n, err
+	}
+	if bitsPerEntry == 0 {
+		state, err := nr.readVarInt()
+		if err != nil {
+			return nr.n, err
+		}
+		// The data array of single valued palettes is always empty, but is still prefixed with its length.
+		if _, err := nr.readLongs(0); err != nil {
+			return nr.n, err
+		}
+		d.palette, d.storage = NewSingletonPalette(state), NewEmptyBitStorage(0, d.paletteType.StorageSize)
+		return nr.n, nil
+	}
+
+	n := int32(bitsPerEntry)
+	if n > 32 {
+		return nr.n, fmt.Errorf("invalid bits per entry %v", n)
+	}
+	var palette Palette
+	if n <= d.paletteType.MaximumBitsPerEntry {
+		n = d.sanitizeBitsPerEntry(n)
+		size, err := nr.readVarInt()
+		if err != nil {
+			return nr.n, err
+		}
+		if size < 0 || size > 1<<n {
+			return nr.n, fmt.Errorf("palette size %v does not fit in %v bits: %w", size, n, ErrPaletteFull)
+		}
+		entries := make([]int32, size)
+		for i := range entries {
+			if entries[i], err = nr.readVarInt(); err != nil {
+				return nr.n, err
+			}
+		}
+		if n <= d.paletteType.MinimumBitsPerEntry {
+			palette = NewFilledListPalette(n, entries)
+		} else {
+			palette = NewFilledMapPalette(n, entries)
+		}
+	} else {
+		palette = NewGlobalPalette()
+	}
+
+	storage := NewEmptyBitStorage(n, d.paletteType.StorageSize)
+	data, err := nr.readLongs(len(storage.data))
+	if err != nil {
+		return nr.n, err
+	}
+	copy(storage.data, data)
+	d.palette, d.storage = palette, storage
+	return nr.n, nil
+}
+
+// Section is a 16x16x16 section of a chunk column as sent in the Java chunk data packet: the number of non-air
+// blocks, followed by the block states and biomes of the section.
+type Section struct {
+	// BlockCount is the number of blocks in the section that are not air.
+	BlockCount int16
+	// Blocks holds the block states of the section.
+	Blocks *DataPalette
+	// Biomes holds the biomes of the section.
+	Biomes *DataPalette
+}
+
+// NewEmptySection returns a new section filled with the zero block state and biome.
+func NewEmptySection() *Section {
+	return &Section{
+		Blocks: NewEmptyChunkDataPalette(),
+		Biomes: NewEmptyBiomeDataPalette(),
+	}
+}
+
+// WriteTo writes the section to the writer passed in the format of the Java network protocol.
+func (s *Section) WriteTo(w io.Writer) (int64, error) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(s.BlockCount))
+	n, err := w.Write(buf[:])
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, p := range []*DataPalette{s.Blocks, s.Biomes} {
+		n, err := p.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom reads a section in the format of the Java network protocol from the reader passed. Block states and
+// biomes that are nil are created before reading.
+func (s *Section) ReadFrom(r io.Reader) (int64, error) {
+	var buf [2]byte
+	n, err := io.ReadFull(r, buf[:])
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+	s.BlockCount = int16(binary.BigEndian.Uint16(buf[:]))
+	if s.Blocks == nil {
+		s.Blocks = NewEmptyChunkDataPalette()
+	}
+	if s.Biomes == nil {
+		s.Biomes = NewEmptyBiomeDataPalette()
+	}
+	for _, p := range []*DataPalette{s.Blocks, s.Biomes} {
+		n, err := p.ReadFrom(r)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// LightSectionSize is the size in bytes of the sky or block light of a section: half a byte for each block.
+const LightSectionSize = 2048
+
+// Light holds the light of a chunk column as sent in the Java chunk data and light update packets. Bit i of each
+// mask refers to the light section i, where light section 0 is the section below the lowest section of the
+// world. The trust edges boolean that versions before Minecraft 1.20 send first is not part of it.
+type Light struct {
+	// SkyLightMask and BlockLightMask mark the light sections that sky and block light are sent for.
+	SkyLightMask, BlockLightMask []int64
+	// EmptySkyLightMask and EmptyBlockLightMask mark the light sections that are entirely unlit.
+	EmptySkyLightMask, EmptyBlockLightMask []int64
+	// SkyLight and BlockLight hold the light of the sections set in the SkyLightMask and BlockLightMask, from
+	// the bottom up. Each holds LightSectionSize bytes.
+	SkyLight, BlockLight [][]byte
+}
+
+// WriteTo writes the light to the writer passed in the format of the Java network protocol.
+func (l *Light) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, mask := range [][]int64{l.SkyLightMask, l.BlockLightMask, l.EmptySkyLightMask, l.EmptyBlockLightMask} {
+		writeVarInt(&buf, int32(len(mask)))
+		for _, v := range mask {
+			_ = binary.Write(&buf, binary.BigEndian, v)
+		}
+	}
+	for _, arrays := range [][][]byte{l.SkyLight, l.BlockLight} {
+		writeVarInt(&buf, int32(len(arrays)))
+		for _, arr := range arrays {
+			if len(arr) != LightSectionSize {
+				return 0, fmt.Errorf("light array length %d does not match %d", len(arr), LightSectionSize)
+			}
+			writeVarInt(&buf, LightSectionSize)
+			buf.Write(arr)
+		}
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// ReadFrom reads light in the format of the Java network protocol from the reader passed.
+func (l *Light) ReadFrom(r io.Reader) (int64, error) {
+	nr := &networkReader{r: r}
+	for _, mask := range []*[]int64{&l.SkyLightMask, &l.BlockLightMask, &l.EmptySkyLightMask, &l.EmptyBlockLightMask} {
+		v, err := nr.readLongs(-1)
+		if err != nil {
+			return nr.n, err
+		}
+		*mask = v
+	}
+	for _, arrays := range []*[][]byte{&l.SkyLight, &l.BlockLight} {
+		count, err := nr.readVarInt()
+		if err != nil {
+			return nr.n, err
+		}
+		// Light is sent for at most every section of the world and the two sections around it.
+		if count < 0 || count > 4096>>4+2 {
+			return nr.n, fmt.Errorf("invalid light array count %v", count)
+		}
+		*arrays = make([][]byte, count)
+		for i := range *arrays {
+			length, err := nr.readVarInt()
+			if err != nil {
+				return nr.n, err
+			}
+			if length != LightSectionSize {
+				return nr.n, fmt.Errorf("light array length %d does not match %d", length, LightSectionSize)
+			}
+			arr := make([]byte, LightSectionSize)
+			if err := nr.read(arr); err != nil {
+				return nr.n, err
+			}
+			(*arrays)[i] = arr
+		}
+	}
+	return nr.n, nil
+}
+
+// writeVarInt writes a VarInt, as used by the Java network protocol, to the buffer passed.
+func writeVarInt(buf *bytes.Buffer, v int32) {
+	u := uint32(v)
+	for u >= 0x80 {
+		buf.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	buf.WriteByte(byte(u))
+}
+
+// networkReader reads values of the Java network protocol from a reader, counting the number of bytes read.
+type networkReader struct {
+	r io.Reader
+	n int64
+}
+
+// read reads exactly len(b) bytes into b.
+func (r *networkReader) read(b []byte) error {
+	n, err := io.ReadFull(r.r, b)
+	r.n += int64(n)
+	return err
+}
+
+// readByte reads a single byte.
+func (r *networkReader) readByte() (byte, error) {
+	var b [1]byte
+	err := r.read(b[:])
+	return b[0], err
+}
+
+// readVarInt reads a VarInt of at most five bytes.
+func (r *networkReader) readVarInt() (int32, error) {
+	var v uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint32(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(v), nil
+		}
+	}
+	return 0, fmt.Errorf("varint is too big")
+}
+
+// readLongs reads a VarInt prefixed array of big endian longs. If expected is not negative, the length of the
+// array must be equal to it.
+func (r *networkReader) readLongs(expected int) ([]int64, error) {
+	length, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if expected >= 0 && int(length) != expected {
+		return nil, &DataLengthError{Length: int(length), Expected: expected}
+	}
+	// Masks and storages never need more longs than a 4096 entry storage with 64 bits per entry.
+	if length < 0 || length > 4096 {
+		return nil, fmt.Errorf("invalid long array length %v", length)
+	}
+	b := make([]byte, int(length)*8)
+	if err := r.read(b); err != nil {
+		return nil, err
+	}
+	longs := make([]int64, length)
+	for i := range longs {
+		longs[i] = int64(binary.BigEndian.Uint64(b[i*8:]))
+	}
+	return longs, nil
+}
@@ -0,0 +1,323 @@
+package column
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestDataPaletteNetwork(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		paletteType PaletteType
+		// distinct is the number of distinct states in the data palette written.
+		distinct int32
+		// spanning makes the data palette written use spanning storage, as decoded from Java worlds before 1.16.
+		spanning bool
+		// palette and bits are the palette kind and the number of bits per entry expected after reading.
+		palette Palette
+		bits    int32
+	}{
+		{name: "chunk singleton", paletteType: ChunkPaletteType(), distinct: 1, palette: &SingletonPalette{}, bits: 0},
+		{name: "chunk list", paletteType: ChunkPaletteType(), distinct: 2, palette: &ListPalette{}, bits: 4},
+		{name: "chunk full list", paletteType: ChunkPaletteType(), distinct: 16, palette: &ListPalette{}, bits: 4},
+		{name: "chunk map", paletteType: ChunkPaletteType(), distinct: 17, palette: &MapPalette{}, bits: 5},
+		{name: "chunk full map", paletteType: ChunkPaletteType(), distinct: 256, palette: &MapPalette{}, bits: 8},
+		{name: "chunk global", paletteType: ChunkPaletteType(), distinct: 257, palette: &GlobalPalette{}, bits: 15},
+		{name: "chunk spanning", paletteType: ChunkPaletteType(), distinct: 40, spanning: true, palette: &MapPalette{}, bits: 6},
+		{name: "biome singleton", paletteType: BiomePaletteType(), distinct: 1, palette: &SingletonPalette{}, bits: 0},
+		{name: "biome list", paletteType: BiomePaletteType(), distinct: 2, palette: &ListPalette{}, bits: 1},
+		{name: "biome map", paletteType: BiomePaletteType(), distinct: 8, palette: &MapPalette{}, bits: 3},
+		{name: "biome global", paletteType: BiomePaletteType(), distinct: 9, palette: &GlobalPalette{}, bits: 6},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			expected := make([]int32, test.paletteType.StorageSize)
+			for i := range expected {
+				// States are spread over the storage and are not equal to their palette IDs.
+				expected[i] = (int32(i) % test.distinct) * 3
+			}
+			d := NewEmptyDataPalette(test.paletteType, test.paletteType.GlobalBitsPerEntry)
+			if test.spanning {
+				d.storage = NewEmptySpanningBitStorage(test.paletteType.MinimumBitsPerEntry, test.paletteType.StorageSize)
+			}
+			if err := d.EncodeAll(expected); err != nil {
+				t.Fatal(err)
+			}
+			if test.spanning && !d.Storage().Spanning() {
+				t.Fatal("expected spanning storage before writing")
+			}
+
+			read := checkNetworkRoundTrip(t, d, test.paletteType)
+			checkDataPalette(t, read, expected)
+			if got, want := fmt.Sprintf("%T", read.Palette()), fmt.Sprintf("%T", test.palette); got != want {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+			if bits := read.Storage().BitsPerEntry(); bits != test.bits {
+				t.Fatalf("expected %v bits per entry, got %v", test.bits, bits)
+			}
+		})
+	}
+}
+
+func TestDataPaletteNetworkWidePalette(t *testing.T) {
+	// Java worlds may hold local palettes with more bits than the network format allows for a local palette, such
+	// as a map palette of 9 bits. Clients read those as global palettes, so the states are written instead.
+	entries := make([]int32, 300)
+	for i := range entries {
+		entries[i] = int32(i) * 5
+	}
+	storage := NewEmptySpanningBitStorage(9, ChunkPaletteType().StorageSize)
+	expected := make([]int32, ChunkPaletteType().StorageSize)
+	for i := range expected {
+		id := int32(i) % int32(len(entries))
+		if err := storage.Set(int32(i), id); err != nil {
+			t.Fatal(err)
+		}
+		expected[i] = entries[id]
+	}
+	d := NewFilledDataPalette(ChunkPaletteType(), ChunkPaletteType().GlobalBitsPerEntry, NewFilledMapPalette(9, entries), storage)
+
+	read := checkNetworkRoundTrip(t, d, ChunkPaletteType())
+	checkDataPalette(t, read, expected)
+	if _, ok := read.Palette().(*GlobalPalette); !ok {
+		t.Fatalf("expected *column.GlobalPalette, got %T", read.Palette())
+	}
+	if bits := read.Storage().BitsPerEntry(); bits != ChunkPaletteType().GlobalBitsPerEntry {
+		t.Fatalf("expected %v bits per entry, got %v", ChunkPaletteType().GlobalBitsPerEntry, bits)
+	}
+}
+
+func TestDataPaletteNetworkErrors(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteByte(4)
+	writeVarInt(&buf, 1)
+	writeVarInt(&buf, 0)
+	writeVarInt(&buf, 100)
+	if _, err := NewEmptyChunkDataPalette().ReadFrom(&buf); !errors.Is(err, ErrDataLengthMismatch) {
+		t.Fatalf("expected ErrDataLengthMismatch, got %v", err)
+	}
+
+	buf.Reset()
+	buf.WriteByte(4)
+	writeVarInt(&buf, 17)
+	if _, err := NewEmptyChunkDataPalette().ReadFrom(&buf); !errors.Is(err, ErrPaletteFull) {
+		t.Fatalf("expected ErrPaletteFull, got %v", err)
+	}
+
+	buf.Reset()
+	buf.WriteByte(33)
+	if _, err := NewEmptyChunkDataPalette().ReadFrom(&buf); err == nil {
+		t.Fatal("expected an error for 33 bits per entry")
+	}
+
+	// The data of a palette that is cut short must not be read as valid.
+	d := NewEmptyChunkDataPalette()
+	if err := d.EncodeAll(make([]int32, ChunkPaletteType().StorageSize)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Set(BlockPos{1, 2, 3}, 7); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEmptyChunkDataPalette().ReadFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Fatal("expected an error for truncated data")
+	}
+}
+
+func TestSectionNetwork(t *testing.T) {
+	s := NewEmptySection()
+	for i := int32(0); i < 300; i++ {
+		if _, err := s.Blocks.Set(positionOf(i*13%4096, ChunkPaletteType()), i+1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.BlockCount = 300
+	if _, err := s.Biomes.Set(BlockPos{1, 2, 3}, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := s.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("expected %v bytes written, got %v", buf.Len(), n)
+	}
+	size := buf.Len()
+	var read Section
+	if n, err = read.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(size) || buf.Len() != 0 {
+		t.Fatalf("expected %v bytes read, got %v", size, n)
+	}
+	if read.BlockCount != s.BlockCount {
+		t.Fatalf("expected block count %v, got %v", s.BlockCount, read.BlockCount)
+	}
+	checkSameStates(t, read.Blocks, s.Blocks)
+	checkSameStates(t, read.Biomes, s.Biomes)
+}
+
+func TestSectionFixture(t *testing.T) {
+	// testdata/section.bin was encoded by hand following the chunk section format of the protocol documentation
+	// (wiki.vg, Chunk_Format), not with this package: a section with stone (1) in its bottom layer and air (0)
+	// above, a 4 bit list palette of air and stone, and a single valued biome palette of biome 1.
+	fixture, err := os.ReadFile("testdata/section.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Section
+	n, err := s.ReadFrom(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(fixture)) {
+		t.Fatalf("expected %v bytes read, got %v", len(fixture), n)
+	}
+	if s.BlockCount != 256 {
+		t.Fatalf("expected block count 256, got %v", s.BlockCount)
+	}
+	blocks := make([]int32, ChunkPaletteType().StorageSize)
+	for i := 0; i < 256; i++ {
+		blocks[i] = 1
+	}
+	checkDataPalette(t, s.Blocks, blocks)
+	biomes := make([]int32, BiomePaletteType().StorageSize)
+	for i := range biomes {
+		biomes[i] = 1
+	}
+	checkDataPalette(t, s.Biomes, biomes)
+
+	// Writing the section read must produce the fixture again, byte for byte.
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), fixture) {
+		t.Fatalf("expected the section written to match the fixture")
+	}
+}
+
+func TestLightNetwork(t *testing.T) {
+	sky, block := make([]byte, LightSectionSize), make([]byte, LightSectionSize)
+	for i := range sky {
+		sky[i], block[i] = byte(i), byte(i*7)
+	}
+	for _, l := range []*Light{
+		{},
+		{
+			SkyLightMask:        []int64{0b1011},
+			BlockLightMask:      []int64{0b0010},
+			EmptySkyLightMask:   []int64{0b0100},
+			EmptyBlockLightMask: []int64{0b1101, -1},
+			SkyLight:            [][]byte{sky, block, sky},
+			BlockLight:          [][]byte{block},
+		},
+	} {
+		var buf bytes.Buffer
+		n, err := l.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(buf.Len()) {
+			t.Fatalf("expected %v bytes written, got %v", buf.Len(), n)
+		}
+		size := buf.Len()
+		var read Light
+		if n, err = read.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(size) || buf.Len() != 0 {
+			t.Fatalf("expected %v bytes read, got %v", size, n)
+		}
+		if got, want := fmt.Sprint(read), fmt.Sprint(*l); got != want {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestLightNetworkErrors(t *testing.T) {
+	l := &Light{SkyLight: [][]byte{make([]byte, LightSectionSize-1)}}
+	if _, err := l.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error writing a short light array")
+	}
+
+	// lightWithArray returns encoded light holding a single sky light array with the length prefix passed.
+	lightWithArray := func(length int32) *bytes.Buffer {
+		var buf bytes.Buffer
+		for i := 0; i < 4; i++ {
+			writeVarInt(&buf, 0)
+		}
+		writeVarInt(&buf, 1)
+		writeVarInt(&buf, length)
+		buf.Write(make([]byte, LightSectionSize))
+		writeVarInt(&buf, 0)
+		return &buf
+	}
+	if _, err := new(Light).ReadFrom(lightWithArray(LightSectionSize)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := new(Light).ReadFrom(lightWithArray(LightSectionSize / 2)); err == nil {
+		t.Fatal("expected an error reading a light array of the wrong length")
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < 4; i++ {
+		writeVarInt(&buf, 0)
+	}
+	writeVarInt(&buf, 4096>>4+3)
+	if _, err := new(Light).ReadFrom(&buf); err == nil {
+		t.Fatal("expected an error reading too many light arrays")
+	}
+
+	// Masks are VarInt prefixed arrays of big endian longs, which must not be cut short.
+	buf.Reset()
+	writeVarInt(&buf, 2)
+	_ = binary.Write(&buf, binary.BigEndian, int64(1))
+	if _, err := new(Light).ReadFrom(&buf); err == nil {
+		t.Fatal("expected an error reading a truncated mask")
+	}
+}
+
+// checkNetworkRoundTrip writes the data palette passed, checks the number of bytes written and read, and returns
+// the data palette read back from the bytes written.
+func checkNetworkRoundTrip(t *testing.T, d *DataPalette, paletteType PaletteType) *DataPalette {
+	t.Helper()
+	var buf bytes.Buffer
+	n, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := buf.Len()
+	if n != int64(size) {
+		t.Fatalf("expected %v bytes written, got %v", size, n)
+	}
+	read := NewEmptyDataPalette(paletteType, paletteType.GlobalBitsPerEntry)
+	if n, err = read.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(size) || buf.Len() != 0 {
+		t.Fatalf("expected %v bytes read, got %v", size, n)
+	}
+	if read.Storage().Spanning() {
+		t.Fatal("expected padded storage after reading")
+	}
+	return read
+}
+
+// checkSameStates checks that two data palettes of the same type hold the same states.
+func checkSameStates(t *testing.T, got, want *DataPalette) {
+	t.Helper()
+	expected := make([]int32, want.paletteType.StorageSize)
+	if err := want.DecodeAll(expected); err != nil {
+		t.Fatal(err)
+	}
+	checkDataPalette(t, got, expected)
+}
@@ -5,6 +5,7 @@ package column
 
 import (
 	"math"
+	"math/bits"
 )
 
 // Palette is a palette implementation for mapping block states to storage IDs.
@@ -12,9 +13,9 @@ type Palette interface {
 	// Size returns the known number of block states in the palette.
 	Size() int32
 	// StateToID converts the block state to a storage ID. If it is not mapped, then the palette will attempt
-	// to map it. If all else fails, it will return -1.
-	StateToID(state int32) int32
-	// IDToState converts the storage ID to a block state. If it is not mapped, then it will return -1.
+	// to map it. If the palette is full, false is returned.
+	StateToID(state int32) (int32, bool)
+	// IDToState converts the storage ID to a block state. If it is not mapped, then it will return 0.
 	IDToState(id int32) int32
 }
 
@@ -26,26 +27,43 @@ type PaletteType struct {
 	MaximumBitsPerEntry int32
 	// StorageSize is the number of bits used to store the palette.
 	StorageSize int32
+	// GlobalBitsPerEntry is the number of bits per entry used once the palette grows beyond MaximumBitsPerEntry
+	// and switches to the global palette. It must be large enough to hold the largest ID of the registry.
+	GlobalBitsPerEntry int32
 }
 
-// BiomePaletteType returns a biome palette type implementation.
+// BiomePaletteType returns a biome palette type implementation. Its global palette is sized for the 64 biomes of
+// Minecraft 1.19.
 func BiomePaletteType() PaletteType {
 	return PaletteType{
 		MinimumBitsPerEntry: 1,
 		MaximumBitsPerEntry: 3,
 		StorageSize:         64,
+		GlobalBitsPerEntry:  6,
 	}
 }
 
-// ChunkPaletteType returns a chunk palette type implementation.
+// ChunkPaletteType returns a chunk palette type implementation. Its global palette is sized for the block states
+// of Minecraft 1.19, which need 15 bits.
 func ChunkPaletteType() PaletteType {
 	return PaletteType{
 		MinimumBitsPerEntry: 4,
 		MaximumBitsPerEntry: 8,
 		StorageSize:         4096,
+		GlobalBitsPerEntry:  15,
 	}
 }
 
+// WithRegistrySize returns the palette type with its global palette sized for a registry of the size passed. The
+// global palette always uses more bits than the largest non-global palette.
+func (t PaletteType) WithRegistrySize(size int32) PaletteType {
+	t.GlobalBitsPerEntry = int32(bits.Len32(uint32(size - 1)))
+	if t.GlobalBitsPerEntry <= t.MaximumBitsPerEntry {
+		t.GlobalBitsPerEntry = t.MaximumBitsPerEntry + 1
+	}
+	return t
+}
+
 // GlobalPalette is a global palette that maps one to one.
 type GlobalPalette struct{}
 
@@ -60,13 +78,12 @@ func (*GlobalPalette) Size() int32 {
 }
 
 // StateToID converts the block state to a storage ID. If it is not mapped, then the palette will attempt
-// to map it. If all else fails, it will return false as it's second return value.
-func (*GlobalPalette) StateToID(state int32) int32 {
-	return state
+// to map it. Every state that is not negative maps to itself.
+func (*GlobalPalette) StateToID(state int32) (int32, bool) {
+	return state, state >= 0
 }
 
-// IDToState converts the storage ID to a block state. If it is not mapped, then it will return false as
-// it's second return value.
+// IDToState converts the storage ID to a block state, which is the storage ID itself.
 func (*GlobalPalette) IDToState(id int32) int32 {
 	return id
 }
@@ -94,8 +111,10 @@ func NewListPalette(bitsPerEntry int32) *ListPalette {
 // NewFilledListPalette returns a new filled list palette.
 func NewFilledListPalette(bitsPerEntry int32, data []int32) *ListPalette {
 	maxId := int32((1 << bitsPerEntry) - 1)
+	filled := make([]int32, maxId+1)
+	copy(filled, data)
 	return &ListPalette{
-		data:   data,
+		data:   filled,
 		maxId:  maxId,
 		nextId: int32(len(data)),
 	}
@@ -107,26 +126,24 @@ func (p *ListPalette) Size() int32 {
 }
 
 // StateToID converts the block state to a storage ID. If it is not mapped, then the palette will attempt
-// to map it. If all else fails, it will return false as it's second return value.
-func (p *ListPalette) StateToID(state int32) (id int32) {
-	id = -1
+// to map it. If the palette is full, false is returned.
+func (p *ListPalette) StateToID(state int32) (int32, bool) {
 	for i := int32(0); i < p.nextId; i++ {
 		if p.data[i] == state {
-			id = i
-			break
+			return i, true
 		}
 	}
-	if id == -1 && p.Size() < p.maxId+1 {
-		id = p.nextId
+	if p.Size() < p.maxId+1 {
+		id := p.nextId
 		p.data[id] = state
 
 		p.nextId++
+		return id, true
 	}
-	return id
+	return 0, false
 }
 
-// IDToState converts the storage ID to a block state. If it is not mapped, then it will return false as
-// it's second return value.
+// IDToState converts the storage ID to a block state. If it is not mapped, then it will return 0.
 func (p *ListPalette) IDToState(id int32) int32 {
 	if id >= 0 && id < p.Size() {
 		return p.data[id]
@@ -155,7 +172,9 @@ func NewMapPalette(bitsPerEntry int32) *MapPalette {
 		nextId:    1,
 		maxId:     maxId,
 		idToState: make([]int32, maxId+1),
-		stateToID: make(map[int32]int32),
+		// State 0 is mapped to the first ID, as done by list palettes, so that resizing a palette holding state 0
+		// does not map it a second time and leave a duplicate entry.
+		stateToID: map[int32]int32{0: 0},
 	}
 }
 
@@ -182,8 +201,8 @@ func (p *MapPalette) Size() int32 {
 }
 
 // StateToID converts the block state to a storage ID. If it is not mapped, then the palette will attempt
-// to map it. If all else fails, it will return false as it's second return value.
-func (p *MapPalette) StateToID(state int32) int32 {
+// to map it. If the palette is full, false is returned.
+func (p *MapPalette) StateToID(state int32) (int32, bool) {
 	id, ok := p.stateToID[state]
 	if !ok && p.Size() < p.maxId+1 {
 		id, ok = p.nextId, true
@@ -192,14 +211,10 @@ func (p *MapPalette) StateToID(state int32) int32 {
 		p.idToState[id] = state
 		p.stateToID[state] = id
 	}
-	if !ok {
-		return -1
-	}
-	return id
+	return id, ok
 }
 
-// IDToState converts the storage ID to a block state. If it is not mapped, then it will return false as
-// it's second return value.
+// IDToState converts the storage ID to a block state. If it is not mapped, then it will return 0.
 func (p *MapPalette) IDToState(id int32) int32 {
 	if id >= 0 && id < p.Size() {
 		return p.idToState[id]
@@ -225,16 +240,12 @@ func (p *SingletonPalette) Size() int32 {
 }
 
 // StateToID converts the block state to a storage ID. If it is not mapped, then the palette will attempt
-// to map it. If all else fails, it will return false as it's second return value.
-func (p *SingletonPalette) StateToID(state int32) int32 {
-	if p.state == state {
-		return 0
-	}
-	return -1
+// to map it. Singleton palettes only map their own state, so false is returned for any other state.
+func (p *SingletonPalette) StateToID(state int32) (int32, bool) {
+	return 0, p.state == state
 }
 
-// IDToState converts the storage ID to a block state. If it is not mapped, then it will return false as
-// it's second return value.
+// IDToState converts the storage ID to a block state. If it is not mapped, then it will return 0.
 func (p *SingletonPalette) IDToState(id int32) int32 {
 	if id == 0 {
 		return p.state
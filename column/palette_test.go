@@ -0,0 +1,49 @@
+package column
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewPaletteStateZero(t *testing.T) {
+	// New list and map palettes start out with state 0 at ID 0, as the storage they are created with is filled
+	// with zeroes. Mapping state 0 must not take up a second ID.
+	for _, p := range []Palette{NewListPalette(4), NewMapPalette(5)} {
+		id, ok := p.StateToID(0)
+		if !ok || id != 0 {
+			t.Fatalf("%T: expected state 0 at ID 0, got %v (%v)", p, id, ok)
+		}
+		if id, ok := p.StateToID(7); !ok || id != 1 {
+			t.Fatalf("%T: expected state 7 at ID 1, got %v (%v)", p, id, ok)
+		}
+		if p.Size() != 2 {
+			t.Fatalf("%T: expected size 2, got %v", p, p.Size())
+		}
+	}
+}
+
+func TestDataPaletteResizeStateZero(t *testing.T) {
+	// A full list palette holding state 0 is resized to a map palette. Each of its 16 states must take up a
+	// single ID of the map palette, leaving room for 240 more states before resizing to a global palette.
+	d := NewEmptyChunkDataPalette()
+	for i := int32(0); i < 17; i++ {
+		if _, err := d.Set(positionOf(i, ChunkPaletteType()), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, ok := d.Palette().(*MapPalette)
+	if !ok {
+		t.Fatalf("expected *column.MapPalette, got %T", d.Palette())
+	}
+	if p.Size() != 17 {
+		t.Fatalf("expected 17 states in the map palette, got %v", p.Size())
+	}
+	for i := int32(17); i < 256; i++ {
+		if _, err := d.Set(positionOf(i, ChunkPaletteType()), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fmt.Sprintf("%T", d.Palette()); got != "*column.MapPalette" {
+		t.Fatalf("expected 256 states to fit in a map palette, got %v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/items"
+	"github.com/justtaldevelops/mcanvil/states"
+	"sort"
+	"strings"
+)
+
+// block translates a Java block argument, such as "oak_stairs[facing=north]", to the Bedrock block name and
+// block state list. Properties left out of the Java argument take the values of the nearest state that can be
+// converted.
+func (t Translator) block(arg string) (string, string, error) {
+	if strings.Contains(arg, "{") {
+		return "", "", fmt.Errorf("block nbt is not supported: %v", arg)
+	}
+	if strings.HasPrefix(arg, "#") {
+		return "", "", fmt.Errorf("block tags are not supported: %v", arg)
+	}
+	state, err := states.ParseJava(strings.ReplaceAll(namespaced(arg), " ", ""))
+	if err != nil {
+		return "", "", err
+	}
+	mapper := t.mapper()
+	converted, _, ok := mapper.ConvertToBedrock(state)
+	if !ok {
+		nearest, ok := mapper.Nearest(state)
+		if !ok {
+			return "", "", fmt.Errorf("unknown block %v", arg)
+		}
+		converted, _, _ = mapper.ConvertToBedrock(nearest)
+	}
+	return converted.Name, t.blockStates(converted.Properties), nil
+}
+
+// blockStates formats the properties of a Bedrock block state as a block state list for commands, such as
+// ["upside_down_bit"=true,"weirdo_direction"=3].
+func (t Translator) blockStates(properties map[string]any) string {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	separator := ":"
+	if t.protocol() >= BlockStateEqualsProtocol {
+		separator = "="
+	}
+	entries := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := properties[k]
+		if s, ok := value.(string); ok {
+			value = `"` + s + `"`
+		}
+		entries = append(entries, fmt.Sprintf(`"%v"%v%v`, k, separator, value))
+	}
+	return "[" + strings.Join(entries, ",") + "]"
+}
+
+// item translates a Java item argument, such as "white_wool", to the Bedrock item name and data value.
+func item(arg string) (string, int16, error) {
+	if strings.ContainsAny(arg, "{[") {
+		return "", 0, fmt.Errorf("item nbt and components are not supported: %v", arg)
+	}
+	if strings.HasPrefix(arg, "#") {
+		return "", 0, fmt.Errorf("item tags are not supported: %v", arg)
+	}
+	name, data := items.ConvertToBedrock(namespaced(arg))
+	return name, data, nil
+}
+
+// namespaced adds the minecraft namespace to the identifier passed if it has none.
+func namespaced(id string) string {
+	name := id
+	if i := strings.IndexAny(id, "[{"); i != -1 {
+		name = id[:i]
+	}
+	if strings.Contains(name, ":") {
+		return id
+	}
+	return "minecraft:" + id
+}
@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/text"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ExecuteProtocol is the Bedrock protocol version of Minecraft 1.19.50, which replaced the execute syntax of
+	// Bedrock with the syntax used by Java. Older versions use the legacy "execute <target> <position>" syntax.
+	ExecuteProtocol = 560
+	// BlockStateEqualsProtocol is the Bedrock protocol version of Minecraft 1.19.70, which separates the keys
+	// and values of block states in commands with "=" rather than ":".
+	BlockStateEqualsProtocol = 575
+)
+
+// unsupported holds the Java commands that have no Bedrock equivalent.
+var unsupported = map[string]bool{
+	"advancement": true, "attribute": true, "bossbar": true, "data": true, "datapack": true, "debug": true,
+	"forceload": true, "item": true, "jfr": true, "loot": true, "perf": true, "publish": true, "recipe": true,
+	"return": true, "spectate": true, "team": true, "teammsg": true, "tm": true, "trigger": true,
+	"worldborder": true,
+}
+
+// Translator translates Java commands, such as those in command blocks, to Bedrock commands. It handles the
+// most common differences between the editions: selector arguments, the execute syntax, block states and
+// item names. The zero value is valid and translates for the default Bedrock protocol version.
+type Translator struct {
+	// Blocks is the mapper used to translate block states. If nil, the default mapper of the states package is
+	// used.
+	Blocks *states.Mapper
+	// Protocol is the Bedrock protocol version to translate for, which selects the execute and block state
+	// syntax. It defaults to the protocol version supported by gophertunnel.
+	Protocol int32
+	// Text holds the options used to convert the text components of tellraw and title commands.
+	Text text.Options
+}
+
+// Translate translates a Java command to Bedrock. An error is returned if the command cannot be translated
+// without changing its behaviour, for example because it uses NBT or a command that Bedrock does not have.
+func (t Translator) Translate(command string) (string, error) {
+	prefix := ""
+	if strings.HasPrefix(command, "/") {
+		prefix, command = "/", command[1:]
+	}
+	args := split(command)
+	if len(args) == 0 {
+		return prefix + command, nil
+	}
+	translated, err := t.translate(args)
+	if err != nil {
+		return "", err
+	}
+	return prefix + strings.Join(translated, " "), nil
+}
+
+// translate translates the arguments of a Java command, the first of which is the name of the command.
+func (t Translator) translate(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing command")
+	}
+	name := strings.TrimPrefix(args[0], "minecraft:")
+	if unsupported[name] {
+		return nil, fmt.Errorf("command %v does not exist on bedrock", name)
+	}
+	switch name {
+	case "execute":
+		if t.protocol() < ExecuteProtocol {
+			return t.legacyExecute(args)
+		}
+		return t.execute(args)
+	case "give":
+		return t.give(args)
+	case "clear":
+		return t.clear(args)
+	case "setblock":
+		return t.setblock(args)
+	case "fill":
+		return t.fill(args)
+	case "clone":
+		return t.clone(args)
+	case "summon":
+		return t.summon(args)
+	case "effect":
+		return t.effect(args)
+	case "tellraw":
+		return t.tellraw(args)
+	case "title":
+		return t.title(args)
+	}
+	// Other commands have the same syntax in both editions, apart from their selectors.
+	return selectors(append([]string{name}, args[1:]...))
+}
+
+// give translates "give <targets> <item> [count]" to "give <target> <item> [count] [data]".
+func (t Translator) give(args []string) ([]string, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("give requires a target and item")
+	}
+	target, err := selector(args[1])
+	if err != nil {
+		return nil, err
+	}
+	name, data, err := item(args[2])
+	if err != nil {
+		return nil, err
+	}
+	count := "1"
+	if len(args) > 3 {
+		count = args[3]
+	}
+	return []string{"give", target, name, count, strconv.Itoa(int(data))}, nil
+}
+
+// clear translates "clear [targets] [item] [maxCount]" to "clear [target] [item] [data] [maxCount]".
+func (t Translator) clear(args []string) ([]string, error) {
+	translated, err := selectors(args[:minInt(len(args), 2)])
+	if err != nil || len(args) < 3 {
+		return translated, err
+	}
+	name, data, err := item(args[2])
+	if err != nil {
+		return nil, err
+	}
+	translated = append(translated, name, strconv.Itoa(int(data)))
+	return append(translated, args[3:]...), nil
+}
+
+// setblock translates "setblock <pos> <block> [mode]" to "setblock <pos> <block> <states> [mode]".
+func (t Translator) setblock(args []string) ([]string, error) {
+	if len(args) < 5 {
+		return nil, fmt.Errorf("setblock requires a position and block")
+	}
+	name, blockStates, err := t.block(args[4])
+	if err != nil {
+		return nil, err
+	}
+	translated := append([]string{"setblock"}, args[1:4]...)
+	translated = append(translated, name, blockStates)
+	return append(translated, args[5:]...), nil
+}
+
+// fill translates "fill <from> <to> <block> [mode] [filter]" to "fill <from> <to> <block> <states> [mode]
+// [filter] [filter states]".
+func (t Translator) fill(args []string) ([]string, error) {
+	if len(args) < 8 {
+		return nil, fmt.Errorf("fill requires two positions and a block")
+	}
+	name, blockStates, err := t.block(args[7])
+	if err != nil {
+		return nil, err
+	}
+	translated := append([]string{"fill"}, args[1:7]...)
+	translated = append(translated, name, blockStates)
+	if len(args) > 8 {
+		translated = append(translated, args[8])
+	}
+	if len(args) > 9 {
+		if args[8] != "replace" {
+			return nil, fmt.Errorf("fill mode %v does not take a filter", args[8])
+		}
+		filter, filterStates, err := t.block(args[9])
+		if err != nil {
+			return nil, err
+		}
+		translated = append(translated, filter, filterStates)
+	}
+	return translated, nil
+}
+
+// clone translates "clone <begin> <end> <destination> filtered <filter> [mode]" to "clone <begin> <end>
+// <destination> filtered <mode> <filter> <states>". Other masks are the same in both editions.
+func (t Translator) clone(args []string) ([]string, error) {
+	if len(args) > 1 && (args[1] == "from" || args[1] == "to") {
+		return nil, fmt.Errorf("cloning between dimensions is not supported")
+	}
+	if len(args) < 11 || args[10] != "filtered" {
+		return args, nil
+	}
+	if len(args) < 12 {
+		return nil, fmt.Errorf("filtered clone requires a filter")
+	}
+	filter, filterStates, err := t.block(args[11])
+	if err != nil {
+		return nil, err
+	}
+	mode := "normal"
+	if len(args) > 12 {
+		mode = args[12]
+	}
+	return append(args[:11:11], mode, filter, filterStates), nil
+}
+
+// summon translates "summon <entity> [pos]". Entities summoned with NBT cannot be translated.
+func (t Translator) summon(args []string) ([]string, error) {
+	if len(args) > 5 {
+		return nil, fmt.Errorf("summoning entities with nbt is not supported")
+	}
+	return args, nil
+}
+
+// effect translates "effect give <targets> <effect> [seconds] [amplifier] [hideParticles]" and "effect clear
+// <targets>" to "effect <target> <effect> [seconds] [amplifier] [hideParticles]" and "effect <target> clear".
+func (t Translator) effect(args []string) ([]string, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("effect requires an action and target")
+	}
+	target, err := selector(args[2])
+	if err != nil {
+		return nil, err
+	}
+	switch args[1] {
+	case "give":
+		if len(args) < 4 {
+			return nil, fmt.Errorf("effect give requires an effect")
+		}
+		effect := strings.TrimPrefix(args[3], "minecraft:")
+		if len(args) > 4 && args[4] == "infinite" {
+			return nil, fmt.Errorf("infinite effects are not supported")
+		}
+		return append([]string{"effect", target, effect}, args[4:]...), nil
+	case "clear":
+		if len(args) > 3 {
+			return nil, fmt.Errorf("clearing a single effect is not supported")
+		}
+		return []string{"effect", target, "clear"}, nil
+	}
+	return nil, fmt.Errorf("unknown effect action %v", args[1])
+}
+
+// tellraw translates "tellraw <targets> <message>", converting the text component to Bedrock raw text.
+func (t Translator) tellraw(args []string) ([]string, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("tellraw requires a target and message")
+	}
+	target, err := selector(args[1])
+	if err != nil {
+		return nil, err
+	}
+	message, err := t.rawText(strings.Join(args[2:], " "))
+	if err != nil {
+		return nil, err
+	}
+	return []string{"tellraw", target, message}, nil
+}
+
+// title translates "title <targets> title|subtitle|actionbar <message>" to titleraw. Other title actions are
+// the same in both editions.
+func (t Translator) title(args []string) ([]string, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("title requires a target and action")
+	}
+	target, err := selector(args[1])
+	if err != nil {
+		return nil, err
+	}
+	switch args[2] {
+	case "title", "subtitle", "actionbar":
+		if len(args) < 4 {
+			return nil, fmt.Errorf("title %v requires a message", args[2])
+		}
+		message, err := t.rawText(strings.Join(args[3:], " "))
+		if err != nil {
+			return nil, err
+		}
+		return []string{"titleraw", target, args[2], message}, nil
+	}
+	return append([]string{"title", target}, args[2:]...), nil
+}
+
+// rawText converts a Java JSON text component to Bedrock raw text JSON, as used by tellraw and titleraw.
+func (t Translator) rawText(component string) (string, error) {
+	c, err := text.Parse(component)
+	if err != nil {
+		return "", err
+	}
+	if c.Score != nil || c.Selector != "" {
+		return "", fmt.Errorf("score and selector components are not supported")
+	}
+	data, err := json.Marshal(map[string]any{"rawtext": []any{map[string]any{"text": c.Bedrock(t.Text)}}})
+	return string(data), err
+}
+
+// mapper returns the block state mapper of the Translator.
+func (t Translator) mapper() *states.Mapper {
+	if t.Blocks == nil {
+		return states.DefaultMapper()
+	}
+	return t.Blocks
+}
+
+// protocol returns the Bedrock protocol version to translate for.
+func (t Translator) protocol() int32 {
+	if t.Protocol == 0 {
+		return protocol.CurrentProtocol
+	}
+	return t.Protocol
+}
+
+// minInt returns the smaller of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
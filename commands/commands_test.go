@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		protocol          int32
+		command, expected string
+	}{
+		{575, "", ""},
+		{575, "/say hi @a[distance=..5]", "/say hi @a[r=5]"},
+		{575, "kill @e[type=minecraft:zombie,distance=2..10,limit=3,sort=furthest]", "kill @e[type=minecraft:zombie,rm=2,r=10,c=-3]"},
+		{575, "tp @n[x_rotation=-90..0,gamemode=survival] 0 64 0", "tp @e[rxm=-90,rx=0,m=survival,c=1] 0 64 0"},
+		{575, "tp @a[sort=random,limit=2] @s", "tp @r[c=2] @s"},
+		{575, "effect give @p[level=5,y_rotation=..45] minecraft:speed 30 1 true", "effect @p[lm=5,l=5,ry=45] speed 30 1 true"},
+		{575, "effect clear @a", "effect @a clear"},
+		{575, "give @p white_wool", "give @p minecraft:wool 1 0"},
+		{575, "give @s[name=Steve] minecraft:granite 16", "give @s[name=Steve] minecraft:stone 16 1"},
+		{575, "clear @a granite 4", "clear @a minecraft:stone 1 4"},
+		{575, "clear @a[tag=x]", "clear @a[tag=x]"},
+		{575, "setblock ~ ~1 ~ oak_stairs[facing=north,half=top,shape=straight,waterlogged=false] replace", `setblock ~ ~1 ~ minecraft:oak_stairs ["upside_down_bit"=true,"weirdo_direction"=3] replace`},
+		{575, "setblock 1 2 3 minecraft:oak_stairs[facing=north, half=top]", `setblock 1 2 3 minecraft:oak_stairs ["upside_down_bit"=true,"weirdo_direction"=3]`},
+		{575, "fill 0 0 0 5 5 5 granite replace stone", `fill 0 0 0 5 5 5 minecraft:stone ["stone_type"="granite"] replace minecraft:stone ["stone_type"="stone"]`},
+		{560, "fill 0 0 0 5 5 5 granite", `fill 0 0 0 5 5 5 minecraft:stone ["stone_type":"granite"]`},
+		{575, "clone 0 0 0 1 1 1 5 5 5 filtered stone move", `clone 0 0 0 1 1 1 5 5 5 filtered move minecraft:stone ["stone_type"="stone"]`},
+		{575, "clone 0 0 0 1 1 1 5 5 5 masked force", "clone 0 0 0 1 1 1 5 5 5 masked force"},
+		{575, "summon zombie ~ ~ ~", "summon zombie ~ ~ ~"},
+		{575, "tellraw @a[tag=vip] [\"Hello \", {\"text\": \"world\", \"color\": \"red\"}]", `tellraw @a[tag=vip] {"rawtext":[{"text":"Hello §r§cworld"}]}`},
+		{575, `title @a subtitle {"text":"Sub"}`, `titleraw @a subtitle {"rawtext":[{"text":"Sub"}]}`},
+		{575, "title @p times 10 70 20", "title @p times 10 70 20"},
+		{
+			575, "execute as @a[tag=x] at @s positioned ~ ~1 ~ if block ~ ~-1 ~ minecraft:granite run say hi",
+			`execute as @a[tag=x] at @s positioned ~ ~1 ~ if block ~ ~-1 ~ minecraft:stone ["stone_type"="granite"] run say hi`,
+		},
+		{
+			575, `execute in the_nether unless entity @e[type=pig] if score @s points matches 1.. run tellraw @a {"text":"Hi","color":"red"}`,
+			`execute in nether unless entity @e[type=pig] if score @s points matches 1.. run tellraw @a {"rawtext":[{"text":"§cHi"}]}`,
+		},
+		{575, "execute positioned as @p[distance=..3] facing entity @e[limit=1] eyes run kill @s", "execute positioned as @p[r=3] facing entity @e[c=1] eyes run kill @s"},
+		{575, "execute if score @s a < @p b align xz anchored eyes rotated 90 0 run say", "execute if score @s a < @p b align xz anchored eyes rotated 90 0 run say"},
+		{545, "execute as @a at @s run say hi", "execute @a ~ ~ ~ say hi"},
+		{
+			545, "execute at @p positioned ~ ~2 ~ if block ~ ~-1 ~ stone run setblock ~ ~ ~ granite",
+			`execute @p ~ ~2 ~ detect ~ ~-1 ~ minecraft:stone -1 setblock ~ ~ ~ minecraft:stone ["stone_type":"granite"]`,
+		},
+		{545, "execute as @e[type=cow] as @p run kill @s", "execute @e[type=cow] ~ ~ ~ execute @p ~ ~ ~ kill @s"},
+	} {
+		translated, err := Translator{Protocol: test.protocol}.Translate(test.command)
+		if err != nil {
+			t.Errorf("%v: %v", test.command, err)
+			continue
+		}
+		if translated != test.expected {
+			t.Errorf("%v:\nexpected %v\ngot      %v", test.command, test.expected, translated)
+		}
+	}
+}
+
+func TestTranslateUntranslatable(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		protocol int32
+		command  string
+	}{
+		{575, "data merge block ~ ~ ~ {Lock:\"key\"}"},
+		{575, "minecraft:team add red"},
+		{575, "setblock ~ ~ ~ chest{Items:[]}"},
+		{575, "setblock ~ ~ ~ #minecraft:logs"},
+		{575, "setblock ~ ~ ~ unknown_block"},
+		{575, "setblock ~ ~"},
+		{575, "give @p diamond_sword{Damage:5}"},
+		{575, "give @p diamond_sword[damage=5]"},
+		{575, "clear @p #minecraft:logs"},
+		{575, "summon zombie ~ ~ ~ {NoAI:1b}"},
+		{575, "kill @e[type=#minecraft:skeletons]"},
+		{575, "kill @e[nbt={OnGround:1b}]"},
+		{575, "kill @e[sort=random]"},
+		{575, "kill @x"},
+		{575, "kill @e[type=zombie"},
+		{575, "effect give @a speed infinite"},
+		{575, "effect clear @a speed"},
+		{575, "fill 0 0 0 1 1 1 stone hollow stone"},
+		{575, "clone from minecraft:overworld 0 0 0 1 1 1 to minecraft:the_end 0 0 0"},
+		{575, `tellraw @a {"selector":"@p"}`},
+		{575, "tellraw @a {"},
+		{575, "execute store result score @s x run say hi"},
+		{575, "execute positioned over world_surface run say hi"},
+		{575, "execute in minecraft:custom run say hi"},
+		{575, "execute if predicate minecraft:test run say hi"},
+		{575, "execute as @a run data get entity @s"},
+		{575, "execute if block ~ ~ ~ chest{Items:[]} run say hi"},
+		{545, "execute if entity @p run say hi"},
+		{545, "execute if block ~ ~ ~ oak_stairs[facing=north] run say hi"},
+		{545, "execute as @a"},
+	} {
+		if translated, err := (Translator{Protocol: test.protocol}).Translate(test.command); err == nil {
+			t.Errorf("%v: expected an error, got %v", test.command, translated)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+	args := split(`tellraw @a[name="A B",tag=x] {"text": "a b"} 'c d'`)
+	expected := []string{"tellraw", `@a[name="A B",tag=x]`, `{"text": "a b"}`, "'c d'"}
+	if len(args) != len(expected) {
+		t.Fatalf("expected %q, got %q", expected, args)
+	}
+	for i := range args {
+		if args[i] != expected[i] {
+			t.Errorf("argument %v: expected %q, got %q", i, expected[i], args[i])
+		}
+	}
+}
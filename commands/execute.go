@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dimensions maps Java dimension IDs to the names used by the Bedrock "execute in" subcommand.
+var dimensions = map[string]string{
+	"minecraft:overworld":  "overworld",
+	"minecraft:the_nether": "nether",
+	"minecraft:the_end":    "the_end",
+}
+
+// execute translates a Java execute command to the Bedrock execute syntax introduced in 1.19.50, which is the
+// same apart from selectors, block states and a few subcommands Bedrock does not have.
+func (t Translator) execute(args []string) ([]string, error) {
+	translated := []string{"execute"}
+	for i := 1; i < len(args); {
+		sub := args[i]
+		switch sub {
+		case "as", "at":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("execute %v requires a target", sub)
+			}
+			target, err := selector(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			translated, i = append(translated, sub, target), i+2
+		case "positioned", "rotated":
+			n := 3
+			if sub == "rotated" || (i+1 < len(args) && args[i+1] == "as") {
+				n = 2
+			} else if i+1 < len(args) && args[i+1] == "over" {
+				return nil, fmt.Errorf("execute positioned over is not supported")
+			}
+			if i+n >= len(args) {
+				return nil, fmt.Errorf("execute %v requires %v arguments", sub, n)
+			}
+			values, err := selectors(args[i+1 : i+n+1])
+			if err != nil {
+				return nil, err
+			}
+			translated, i = append(append(translated, sub), values...), i+n+1
+		case "align", "anchored":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("execute %v requires an argument", sub)
+			}
+			translated, i = append(translated, sub, args[i+1]), i+2
+		case "facing":
+			// Both "facing <pos>" and "facing entity <target> <anchor>" take three arguments.
+			if i+3 >= len(args) {
+				return nil, fmt.Errorf("execute facing requires 3 arguments")
+			}
+			values, err := selectors(args[i+1 : i+4])
+			if err != nil {
+				return nil, err
+			}
+			translated, i = append(append(translated, sub), values...), i+4
+		case "in":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("execute in requires a dimension")
+			}
+			dimension, ok := dimensions[namespaced(args[i+1])]
+			if !ok {
+				return nil, fmt.Errorf("unknown dimension %v", args[i+1])
+			}
+			translated, i = append(translated, sub, dimension), i+2
+		case "if", "unless":
+			condition, n, err := t.condition(args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			translated, i = append(append(translated, sub), condition...), i+n+1
+		case "run":
+			command, err := t.translate(args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			return append(append(translated, sub), command...), nil
+		default:
+			return nil, fmt.Errorf("execute %v is not supported", sub)
+		}
+	}
+	return translated, nil
+}
+
+// condition translates the condition of an "execute if" or "execute unless" subcommand. The translated condition
+// is returned with the number of arguments it used.
+func (t Translator) condition(args []string) ([]string, int, error) {
+	if len(args) == 0 {
+		return nil, 0, fmt.Errorf("execute if requires a condition")
+	}
+	switch args[0] {
+	case "block":
+		if len(args) < 5 {
+			return nil, 0, fmt.Errorf("execute if block requires a position and block")
+		}
+		name, blockStates, err := t.block(args[4])
+		if err != nil {
+			return nil, 0, err
+		}
+		return append(append([]string{}, args[:4]...), name, blockStates), 5, nil
+	case "blocks":
+		if len(args) < 11 {
+			return nil, 0, fmt.Errorf("execute if blocks requires three positions and a mode")
+		}
+		return args[:11], 11, nil
+	case "entity":
+		if len(args) < 2 {
+			return nil, 0, fmt.Errorf("execute if entity requires a target")
+		}
+		target, err := selector(args[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		return []string{"entity", target}, 2, nil
+	case "score":
+		n := 6
+		if len(args) > 3 && args[3] == "matches" {
+			n = 5
+		}
+		if len(args) < n {
+			return nil, 0, fmt.Errorf("execute if score requires %v arguments", n)
+		}
+		values, err := selectors(args[:n])
+		if err != nil {
+			return nil, 0, err
+		}
+		return values, n, nil
+	}
+	return nil, 0, fmt.Errorf("execute if %v is not supported", args[0])
+}
+
+// legacyExecute translates a Java execute command to the legacy Bedrock syntax used before 1.19.50, "execute
+// <target> <position> [detect <position> <block> <data>] <command>". Every change of target becomes a nested
+// execute command. Only as, at, positioned and "if block" subcommands without block states can be translated.
+func (t Translator) legacyExecute(args []string) ([]string, error) {
+	type step struct {
+		target string
+		pos    []string
+		detect []string
+	}
+	var steps []*step
+	here := []string{"~", "~", "~"}
+	last := func() *step {
+		if len(steps) == 0 {
+			steps = append(steps, &step{target: "@s", pos: here})
+		}
+		return steps[len(steps)-1]
+	}
+	for i := 1; i < len(args); {
+		sub := args[i]
+		switch sub {
+		case "as", "at":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("execute %v requires a target", sub)
+			}
+			target, err := selector(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			// The legacy syntax always runs as and at the target, so "as <target> at @s" is a single step.
+			if !(sub == "at" && target == "@s" && len(steps) > 0 && steps[len(steps)-1].detect == nil) {
+				steps = append(steps, &step{target: target, pos: here})
+			}
+			i += 2
+		case "positioned":
+			if i+3 >= len(args) || args[i+1] == "as" || args[i+1] == "over" {
+				return nil, fmt.Errorf("execute positioned %v is not supported by legacy execute", strings.Join(args[i+1:minInt(i+4, len(args))], " "))
+			}
+			s := last()
+			if s.detect != nil || strings.Join(s.pos, " ") != "~ ~ ~" {
+				s = &step{target: "@s", pos: here}
+				steps = append(steps, s)
+			}
+			s.pos, i = args[i+1:i+4], i+4
+		case "if":
+			if i+5 >= len(args) || args[i+1] != "block" {
+				return nil, fmt.Errorf("execute if %v is not supported by legacy execute", args[minInt(i+1, len(args)-1)])
+			}
+			if strings.Contains(args[i+5], "[") {
+				return nil, fmt.Errorf("block states are not supported by legacy execute")
+			}
+			name, _, err := t.block(args[i+5])
+			if err != nil {
+				return nil, err
+			}
+			s := last()
+			if s.detect != nil {
+				s = &step{target: "@s", pos: here}
+				steps = append(steps, s)
+			}
+			s.detect, i = append(append([]string{"detect"}, args[i+2:i+5]...), name, "-1"), i+6
+		case "run":
+			command, err := t.translate(args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			var translated []string
+			for _, s := range steps {
+				translated = append(append(append(translated, "execute", s.target), s.pos...), s.detect...)
+			}
+			return append(translated, command...), nil
+		default:
+			return nil, fmt.Errorf("execute %v is not supported by legacy execute", sub)
+		}
+	}
+	return nil, fmt.Errorf("execute without run is not supported by legacy execute")
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selector translates a Java target selector, such as "@e[type=zombie,distance=..5]", to Bedrock. Arguments that
+// are not selectors are returned as they are.
+func selector(arg string) (string, error) {
+	if len(arg) < 2 || arg[0] != '@' {
+		return arg, nil
+	}
+	base, list := arg[:2], ""
+	if len(arg) > 2 {
+		if arg[2] != '[' || !strings.HasSuffix(arg, "]") {
+			return "", fmt.Errorf("invalid selector %v", arg)
+		}
+		list = arg[3 : len(arg)-1]
+	}
+	var (
+		args        []string
+		limit, sort string
+		hasLimit    bool
+	)
+	switch base {
+	case "@p", "@a", "@r", "@e", "@s":
+	case "@n":
+		// Bedrock has no selector for the nearest entity, but the nearest is selected first by @e.
+		base, limit, hasLimit = "@e", "1", true
+	default:
+		return "", fmt.Errorf("unknown selector %v", base)
+	}
+	for _, entry := range splitList(list) {
+		key, value := keyValue(entry)
+		switch key {
+		case "type", "name", "tag", "x", "y", "z", "dx", "dy", "dz", "scores":
+			if key == "type" && strings.Contains(value, "#") {
+				return "", fmt.Errorf("entity type tags are not supported: %v", value)
+			}
+			args = append(args, key+"="+value)
+		case "distance":
+			args = append(args, rangeArgs(value, "rm", "r")...)
+		case "level":
+			args = append(args, rangeArgs(value, "lm", "l")...)
+		case "x_rotation":
+			args = append(args, rangeArgs(value, "rxm", "rx")...)
+		case "y_rotation":
+			args = append(args, rangeArgs(value, "rym", "ry")...)
+		case "gamemode":
+			args = append(args, "m="+value)
+		case "limit":
+			limit, hasLimit = value, true
+		case "sort":
+			sort = value
+		default:
+			return "", fmt.Errorf("selector argument %v is not supported", key)
+		}
+	}
+	switch sort {
+	case "", "nearest", "arbitrary":
+	case "furthest":
+		// Bedrock selects the furthest entities first with a negative count.
+		if hasLimit {
+			limit = "-" + limit
+		}
+	case "random":
+		if base != "@a" && base != "@r" {
+			return "", fmt.Errorf("random order is only supported for players")
+		}
+		base = "@r"
+	default:
+		return "", fmt.Errorf("unknown selector sort %v", sort)
+	}
+	if hasLimit {
+		args = append(args, "c="+limit)
+	}
+	if len(args) == 0 {
+		return base, nil
+	}
+	return base + "[" + strings.Join(args, ",") + "]", nil
+}
+
+// selectors translates every argument passed that is a selector.
+func selectors(args []string) ([]string, error) {
+	translated := make([]string, 0, len(args))
+	for _, arg := range args {
+		s, err := selector(arg)
+		if err != nil {
+			return nil, err
+		}
+		translated = append(translated, s)
+	}
+	return translated, nil
+}
+
+// rangeArgs translates a Java range, such as "1..5", "..5" or "3", to a pair of Bedrock minimum and maximum
+// selector arguments.
+func rangeArgs(value, minKey, maxKey string) []string {
+	min, max, ok := strings.Cut(value, "..")
+	if !ok {
+		max = min
+	}
+	var args []string
+	if min != "" {
+		args = append(args, minKey+"="+min)
+	}
+	if max != "" {
+		args = append(args, maxKey+"="+max)
+	}
+	return args
+}
@@ -0,0 +1,84 @@
+package commands
+
+import "strings"
+
+// split splits a command into its arguments, which are separated by spaces. Spaces inside brackets, braces and
+// quotes do not separate arguments, so that selectors, block states, NBT and JSON stay whole.
+func split(command string) []string {
+	var (
+		args  []string
+		b     strings.Builder
+		depth int
+		quote rune
+	)
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == '\\' && i+1 < len(runes) {
+				b.WriteRune(r)
+				i++
+				r = runes[i]
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ' ' && depth <= 0:
+			if b.Len() > 0 {
+				args = append(args, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		args = append(args, b.String())
+	}
+	return args
+}
+
+// splitList splits the contents of a selector or block state list, such as "type=zombie,limit=1", at the commas
+// outside brackets, braces and quotes.
+func splitList(list string) []string {
+	var (
+		entries []string
+		start   int
+		depth   int
+		quote   rune
+	)
+	runes := []rune(list)
+	for i, r := range runes {
+		switch {
+		case quote != 0:
+			if r == quote && runes[i-1] != '\\' {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			entries = append(entries, strings.TrimSpace(string(runes[start:i])))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(string(runes[start:])); last != "" {
+		entries = append(entries, last)
+	}
+	return entries
+}
+
+// keyValue splits an entry of a selector or block state list into its key and value.
+func keyValue(entry string) (string, string) {
+	key, value, _ := strings.Cut(entry, "=")
+	return strings.TrimSpace(key), strings.TrimSpace(value)
+}
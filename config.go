@@ -0,0 +1,154 @@
+package mcanvil
+
+import (
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/justtaldevelops/mcanvil/biomes"
+	"github.com/justtaldevelops/mcanvil/commands"
+	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/text"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// Config holds the settings used when converting an Anvil world to Bedrock. The zero value is valid and uses the
+// default settings.
+type Config struct {
+	// Mapper is the mapper used to convert Java block states to Bedrock block states. If nil, the mapper
+	// registered in the states package for the Protocol is used.
+	Mapper *states.Mapper
+	// BiomeMapper is the mapper used to convert Java biomes to Bedrock biomes. If nil, the mapper registered in
+	// the biomes package for the Protocol is used.
+	BiomeMapper *biomes.Mapper
+	// DataVersion overrides the Java data version of chunks passed to block entity converters, which decides
+	// the format of the items and text they hold. If zero, the DataVersion stored in each chunk is used. It does
+	// not select mappers: chunks of older Java versions are upgraded when they are read, and converted using the
+	// same mappers as current chunks.
+	DataVersion int32
+	// Protocol is the Bedrock protocol version to select mappers and block entity formats for. It defaults to
+	// the protocol version supported by gophertunnel. Note that converted states must still be known to the block
+	// registry of dragonfly.
+	Protocol int32
+	// Liquids is the table deciding which Java block states hold liquids, and what is placed on the second
+	// Bedrock block layer for them. If nil, the default liquid rules of the states package are used.
+	Liquids *states.Liquids
+	// Fallback is the policy applied to Java block states and biomes that cannot be mapped to Bedrock. By
+	// default, the conversion fails on the first one found.
+	Fallback FallbackPolicy
+	// FallbackBlock is the Java block state used in place of unmapped block states with FallbackReplace, or when
+	// FallbackNearest finds no match. It defaults to stone.
+	FallbackBlock states.Block
+	// FallbackBiome is the Java biome used in place of unmapped biomes with FallbackReplace, or when
+	// FallbackNearest finds no match. It defaults to plains.
+	FallbackBiome string
+	// JavaRange is the vertical range of the Java world, as set by the min_y and height of its dimension type.
+	// Sections outside of it are not converted. If zero, the range stored in the level.dat is used when
+	// converting a Level. Otherwise, the range is derived from the yPos of each chunk: chunks starting below Y 0
+	// span 384 blocks, others 256.
+	JavaRange cube.Range
+	// YShift is the number of blocks by which the world is moved vertically when converting, for example -64 to
+	// move a world from 0-255 to the bottom of the Bedrock range. It must be a multiple of 16. Sections that end
+	// up outside the Bedrock range are clipped.
+	YShift int
+	// Text holds the options used to convert Java text components, such as the text on signs, to Bedrock
+	// formatted text.
+	Text text.Options
+	// Commands is the translator used for the commands of command blocks. If nil, commands are kept as they are.
+	// The block state mapper and protocol of the Config are used if the translator does not set them. Commands
+	// that cannot be translated are kept as they are and listed in the Report.
+	Commands *commands.Translator
+}
+
+// FallbackPolicy specifies how Java block states and biomes that cannot be mapped to Bedrock are handled.
+type FallbackPolicy int
+
+const (
+	// FallbackNone makes the conversion fail when a block state or biome cannot be mapped.
+	FallbackNone FallbackPolicy = iota
+	// FallbackReplace replaces unmapped block states and biomes with the FallbackBlock and FallbackBiome.
+	FallbackReplace
+	// FallbackNearest replaces unmapped block states with the nearest mapped state with the same name, dropping
+	// properties that do not match, and unmapped biomes with the biome of the closest name.
+	FallbackNearest
+	// FallbackSkip leaves out unmapped block states and biomes, leaving air and the default biome in their place.
+	FallbackSkip
+)
+
+// mapper returns the block state mapper to use for converting chunks.
+func (conf Config) mapper() *states.Mapper {
+	if conf.Mapper != nil {
+		return conf.Mapper
+	}
+	return states.MapperFor(conf.protocol())
+}
+
+// biomeMapper returns the biome mapper to use for converting chunks.
+func (conf Config) biomeMapper() *biomes.Mapper {
+	if conf.BiomeMapper != nil {
+		return conf.BiomeMapper
+	}
+	return biomes.MapperFor(conf.protocol())
+}
+
+// dataVersion returns the Java data version to pass to block entity converters, given the data version of a
+// chunk.
+func (conf Config) dataVersion(dataVersion int32) int32 {
+	if conf.DataVersion != 0 {
+		return conf.DataVersion
+	}
+	return dataVersion
+}
+
+// protocol returns the Bedrock protocol version to convert to.
+func (conf Config) protocol() int32 {
+	if conf.Protocol == 0 {
+		return protocol.CurrentProtocol
+	}
+	return conf.Protocol
+}
+
+// commands returns the command translator to use for chunks converted with the block state mapper passed, or nil
+// if commands are not translated.
+func (conf Config) commands(mapper *states.Mapper) *commands.Translator {
+	if conf.Commands == nil {
+		return nil
+	}
+	t := *conf.Commands
+	if t.Blocks == nil {
+		t.Blocks = mapper
+	}
+	if t.Protocol == 0 {
+		t.Protocol = conf.protocol()
+	}
+	return &t
+}
+
+// liquids returns the liquid table of the Config, falling back to the default liquid rules.
+func (conf Config) liquids() *states.Liquids {
+	if conf.Liquids == nil {
+		return states.DefaultLiquids()
+	}
+	return conf.Liquids
+}
+
+// javaRange returns the vertical range of the Java world the chunk passed is in.
+func (conf Config) javaRange(c *Chunk) cube.Range {
+	if conf.JavaRange != (cube.Range{}) {
+		return conf.JavaRange
+	}
+	return c.Range()
+}
+
+// fallbackBlock returns the fallback block state of the Config, or stone if it is not set.
+func (conf Config) fallbackBlock() states.Block {
+	if conf.FallbackBlock.Name == "" {
+		return states.Block{Name: "minecraft:stone"}
+	}
+	return conf.FallbackBlock
+}
+
+// fallbackBiome returns the fallback biome of the Config, or plains if it is not set.
+func (conf Config) fallbackBiome() string {
+	if conf.FallbackBiome == "" {
+		return "minecraft:plains"
+	}
+	return conf.FallbackBiome
+}
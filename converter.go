@@ -0,0 +1,431 @@
+package mcanvil
+
+import (
+	"fmt"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/justtaldevelops/mcanvil/biomes"
+	"github.com/justtaldevelops/mcanvil/blockentity"
+	"github.com/justtaldevelops/mcanvil/column"
+	"github.com/justtaldevelops/mcanvil/states"
+)
+
+// converter converts Java chunks to Bedrock chunks using the settings of a Config. A converter may be shared
+// by multiple goroutines.
+type converter struct {
+	conf    Config
+	liquids *states.Liquids
+	report  *Report
+	cache   *blockCache
+
+	airRuntimeID uint32
+}
+
+// newConverter creates a new converter using the Config passed.
+func newConverter(conf Config) (*converter, error) {
+	if conf.YShift%16 != 0 {
+		return nil, fmt.Errorf("y shift %v is not a multiple of 16", conf.YShift)
+	}
+	airRuntimeID, ok := chunk.StateToRuntimeID("minecraft:air", nil)
+	if !ok {
+		return nil, fmt.Errorf("could not find air runtime id")
+	}
+	return &converter{
+		conf:         conf,
+		liquids:      conf.liquids(),
+		report:       newReport(),
+		cache:        newBlockCache(),
+		airRuntimeID: airRuntimeID,
+	}, nil
+}
+
+// mappers holds the block state and biome mappers used to convert a single chunk.
+type mappers struct {
+	blocks *states.Mapper
+	biomes *biomes.Mapper
+}
+
+// convertChunk converts a Java chunk to a Bedrock chunk and the NBT of its Bedrock block entities.
+func (conv *converter) convertChunk(c *Chunk) (*chunk.Chunk, []map[string]any, error) {
+	m := mappers{blocks: conv.conf.mapper(), biomes: conv.conf.biomeMapper()}
+	javaRange, bedrockRange := conv.conf.javaRange(c), world.Overworld.Range()
+	ch := chunk.New(conv.airRuntimeID, bedrockRange)
+	offsetX, offsetZ := c.XPos<<4, c.ZPos<<4
+	sections := make(map[int8]sectionBlocks, len(c.Sections))
+	for i := range c.Sections {
+		s := &c.Sections[i]
+		if len(s.BlockStates.Palette) == 0 {
+			// Sections that only hold light data have no block states to convert.
+			continue
+		}
+		javaY := int(s.SectionY()) << 4
+		if javaY < javaRange.Min() || javaY > javaRange.Max() {
+			// Java keeps light sections above and below the world, which never hold blocks.
+			continue
+		}
+		bedrockY := javaY + conv.conf.YShift
+		if bedrockY < bedrockRange.Min() || bedrockY+15 > bedrockRange.Max() {
+			conv.report.addClippedSection()
+			continue
+		}
+		dataPalette, err := conv.blockPalette(s, c.DataVersion, m.blocks)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		palette, indices, err := paletteIndices(dataPalette)
+		if err != nil {
+			return nil, nil, &SectionError{Y: s.SectionY(), Err: err}
+		}
+		sections[s.SectionY()] = sectionBlocks{palette: palette, indices: indices}
+		blocks, err := conv.bedrockBlocks(palette, m.blocks)
+		if err != nil {
+			return nil, nil, err
+		}
+		offsetY := int16(bedrockY)
+		sub := ch.SubChunk(offsetY)
+		for i, index := range indices {
+			b := blocks[index]
+			if b.air {
+				// Chunks are already prefilled with air.
+				continue
+			}
+			x, y, z := byte(i&15), byte(i>>8), byte((i>>4)&15)
+			sub.SetBlock(x, y, z, 0, b.rid)
+			if b.liquid {
+				sub.SetBlock(x, y, z, 1, b.liquidRID)
+			}
+		}
+
+		biomePalette, err := conv.biomePalette(s, m.biomes)
+		if err != nil {
+			return nil, nil, err
+		}
+		names, indices, err := biomeIndices(biomePalette)
+		if err != nil {
+			return nil, nil, &SectionError{Y: s.SectionY(), Err: err}
+		}
+		biomeIDs, err := bedrockBiomes(names, m.biomes)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, index := range indices {
+			bedrockID := biomeIDs[index]
+			if bedrockID < 0 {
+				// Chunks use the ocean biome by default.
+				continue
+			}
+			baseX, baseY, baseZ := int32(i&3), int32(i>>4), int32((i>>2)&3)
+			for blockX := baseX << 2; blockX < (baseX<<2)+4; blockX++ {
+				for blockZ := baseZ << 2; blockZ < (baseZ<<2)+4; blockZ++ {
+					for blockY := baseY << 2; blockY < (baseY<<2)+4; blockY++ {
+						ch.SetBiome(byte(offsetX+blockX), int16(blockY)+offsetY, byte(offsetZ+blockZ), uint32(bedrockID))
+					}
+				}
+			}
+		}
+	}
+
+	ch.Compact()
+	blockEntities, err := conv.convertBlockEntities(c, sections, m, javaRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, blockEntities, nil
+}
+
+// sectionBlocks holds the block states of a section after applying the fallback policy, as a palette of Java
+// state IDs and the palette index of every block.
+type sectionBlocks struct {
+	palette, indices []int32
+}
+
+// block returns the Java block state at the position passed, relative to the section.
+func (s sectionBlocks) block(x, y, z int) (states.Block, error) {
+	id := s.palette[s.indices[y<<8|z<<4|x]]
+	state, ok := states.IDToJavaState(id)
+	if !ok {
+		return states.Block{}, fmt.Errorf("could not find state for id: %d", id)
+	}
+	return state, nil
+}
+
+// bedrockBlock holds the Bedrock runtime IDs that a Java block state converts to.
+type bedrockBlock struct {
+	// rid is the runtime ID of the block, and liquidRID that of the liquid in the block if liquid is true.
+	rid, liquidRID uint32
+	// air is true if the block is air, which does not need to be set in new chunks.
+	air, liquid bool
+}
+
+// bedrockBlocks converts every Java state ID of a section palette to the Bedrock blocks it is made up of, so that
+// each distinct state is only converted once per section.
+func (conv *converter) bedrockBlocks(palette []int32, mapper *states.Mapper) ([]bedrockBlock, error) {
+	blocks := make([]bedrockBlock, len(palette))
+	for i, id := range palette {
+		b, err := conv.bedrockBlock(id, mapper)
+		if err != nil {
+			return nil, err
+		}
+		blocks[i] = b
+	}
+	return blocks, nil
+}
+
+// bedrockBlock converts a Java state ID to the Bedrock blocks it is made up of, using the cache of the converter
+// for states that were converted before.
+func (conv *converter) bedrockBlock(id int32, mapper *states.Mapper) (bedrockBlock, error) {
+	if b, ok := conv.cache.load(mapper, id); ok {
+		return b, nil
+	}
+	javaState, ok := states.IDToJavaState(id)
+	if !ok {
+		return bedrockBlock{}, fmt.Errorf("could not find state for id: %d", id)
+	}
+	var b bedrockBlock
+	if javaState.Name == "minecraft:air" {
+		b.air = true
+		conv.cache.store(mapper, id, b)
+		return b, nil
+	}
+	// The liquid layer is decided by the liquid rules rather than by the waterlogged flag of the mapper, as
+	// those also cover blocks that are always filled with water, such as seagrass.
+	bedrockState, _, ok := mapper.ConvertToBedrock(javaState)
+	if !ok {
+		return bedrockBlock{}, fmt.Errorf("could not find bedrock state for java state: %v", javaState)
+	}
+	rid, ok := chunk.StateToRuntimeID(bedrockState.Name, bedrockState.Properties)
+	if !ok {
+		return bedrockBlock{}, fmt.Errorf("could not find bedrock runtime id for state: %v", bedrockState)
+	}
+	b.rid = rid
+	if liquid, ok := conv.liquids.Liquid(javaState); ok {
+		liquidRID, ok := chunk.StateToRuntimeID(liquid.Name, liquid.Properties)
+		if !ok {
+			return bedrockBlock{}, fmt.Errorf("could not find bedrock runtime id for liquid: %v", liquid)
+		}
+		b.liquidRID, b.liquid = liquidRID, true
+	}
+	conv.cache.store(mapper, id, b)
+	return b, nil
+}
+
+// bedrockBiomes converts every Java biome name of a section palette to a Bedrock biome ID. The ocean biome, which
+// chunks use by default, is returned as -1.
+func bedrockBiomes(names []string, mapper *biomes.Mapper) ([]int64, error) {
+	ids := make([]int64, len(names))
+	for i, name := range names {
+		if name == "minecraft:ocean" {
+			ids[i] = -1
+			continue
+		}
+		bedrockID, ok := mapper.ConvertToBedrock(name)
+		if !ok {
+			return nil, fmt.Errorf("could not find bedrock id for biome name: %v", name)
+		}
+		ids[i] = int64(bedrockID)
+	}
+	return ids, nil
+}
+
+// convertBlockEntities converts the block entities of a Java chunk to Bedrock. Block entities without a
+// registered converter are left out and recorded in the report. The block of each block entity is looked up in
+// the sections passed, which hold the states of the chunk after applying the fallback policy.
+func (conv *converter) convertBlockEntities(c *Chunk, sections map[int8]sectionBlocks, m mappers, javaRange cube.Range) ([]map[string]any, error) {
+	bedrockRange, translator := world.Overworld.Range(), conv.conf.commands(m.blocks)
+	blockEntities := make([]map[string]any, 0, len(c.BlockEntities))
+	for _, data := range c.BlockEntities {
+		pos, ok := blockentity.Position(data)
+		if !ok {
+			return nil, fmt.Errorf("block entity %v has no position", blockentity.ID(data))
+		}
+		if pos.Y() < javaRange.Min() || pos.Y() > javaRange.Max() {
+			continue
+		}
+		bedrockPos := pos.Add(cube.Pos{0, conv.conf.YShift})
+		if bedrockPos.OutOfBounds(bedrockRange) {
+			// The section holding the block entity was clipped.
+			continue
+		}
+		state := airState
+		if section, ok := sections[int8(pos.Y()>>4)]; ok {
+			var err error
+			if state, err = section.block(pos.X()&15, pos.Y()&15, pos.Z()&15); err != nil {
+				return nil, err
+			}
+		}
+		converted, ok, err := blockentity.ToBedrock(data, blockentity.Context{
+			Block:       state,
+			Pos:         bedrockPos,
+			DataVersion: conv.conf.dataVersion(c.DataVersion),
+			Protocol:    conv.conf.protocol(),
+			Text:        conv.conf.Text,
+			Commands:    translator,
+			Untranslatable: func(command string, err error) {
+				conv.report.addUntranslatableCommand(command, err)
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			conv.report.addUnconvertedBlockEntity(blockentity.ID(data))
+			continue
+		}
+		blockEntities = append(blockEntities, converted)
+	}
+	return blockEntities, nil
+}
+
+// blockPalette decodes the block states of a section into a data palette of Java state IDs, applying the
+// fallback policy to states that cannot be mapped to Bedrock. The block states are unpacked using the format of
+// the Java data version passed.
+func (conv *converter) blockPalette(s *SubChunk, dataVersion int32, mapper *states.Mapper) (*column.DataPalette, error) {
+	rawBlockPalette := make([]int32, 0, len(s.BlockStates.Palette))
+	unmapped := make(map[int32]states.Block)
+	for i, state := range s.BlockStates.Palette {
+		id, ok, err := conv.resolveBlock(state, mapper)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unmapped[int32(i)] = state
+		}
+		rawBlockPalette = append(rawBlockPalette, id)
+	}
+	p, err := decodeBlockPalette(rawBlockPalette, s.BlockStates.Data, dataVersion)
+	if err != nil {
+		return nil, &SectionError{Y: s.SectionY(), Err: err}
+	}
+	if len(unmapped) > 0 {
+		counts, err := paletteCounts(len(rawBlockPalette), p.Storage())
+		if err != nil {
+			return nil, err
+		}
+		for i, state := range unmapped {
+			conv.report.addUnmappedBlock(state.String(), counts[i])
+		}
+	}
+	return p, nil
+}
+
+// biomePalette decodes the biomes of a section into a data palette of Java biome IDs, applying the fallback
+// policy to biomes that cannot be mapped to Bedrock.
+func (conv *converter) biomePalette(s *SubChunk, mapper *biomes.Mapper) (*column.DataPalette, error) {
+	rawBiomePalette := make([]int32, 0, len(s.Biomes.Palette))
+	unmapped := make(map[int32]string)
+	for i, name := range s.Biomes.Palette {
+		id, ok, err := conv.resolveBiome(name, mapper)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unmapped[int32(i)] = name
+		}
+		rawBiomePalette = append(rawBiomePalette, id)
+	}
+	p, err := decodeBiomePalette(rawBiomePalette, s.Biomes.Data)
+	if err != nil {
+		return nil, &SectionError{Y: s.SectionY(), Err: err}
+	}
+	if len(unmapped) > 0 {
+		counts, err := paletteCounts(len(rawBiomePalette), p.Storage())
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range unmapped {
+			conv.report.addUnmappedBiome(name, counts[i])
+		}
+	}
+	return p, nil
+}
+
+// resolveBlock returns the Java state ID to convert in place of the Java state passed. If the state cannot be
+// mapped to Bedrock, the fallback policy is applied and false is returned.
+func (conv *converter) resolveBlock(state states.Block, mapper *states.Mapper) (int32, bool, error) {
+	if id, ok := conv.blockID(state, mapper); ok {
+		return id, true, nil
+	}
+	switch conv.conf.Fallback {
+	case FallbackReplace:
+	case FallbackNearest:
+		if nearest, ok := mapper.Nearest(state); ok {
+			if id, ok := conv.blockID(nearest, mapper); ok {
+				return id, false, nil
+			}
+		}
+	case FallbackSkip:
+		id, _ := states.JavaStateToID(airState)
+		return id, false, nil
+	default:
+		return 0, false, fmt.Errorf("could not find bedrock state for java state: %v", state)
+	}
+	id, ok := conv.blockID(conv.conf.fallbackBlock(), mapper)
+	if !ok {
+		return 0, false, fmt.Errorf("fallback block %v cannot be converted", conv.conf.fallbackBlock())
+	}
+	return id, false, nil
+}
+
+// blockID returns the Java state ID of the state passed, if the mapper can convert it to a valid Bedrock state.
+func (conv *converter) blockID(state states.Block, mapper *states.Mapper) (int32, bool) {
+	id, ok := states.JavaStateToID(state)
+	if !ok {
+		return 0, false
+	}
+	_, err := conv.bedrockBlock(id, mapper)
+	return id, err == nil
+}
+
+// resolveBiome returns the Java biome ID to convert in place of the Java biome passed. If the biome cannot be
+// mapped to Bedrock, the fallback policy is applied and false is returned.
+func (conv *converter) resolveBiome(name string, mapper *biomes.Mapper) (int32, bool, error) {
+	if id, ok := biomeID(name, mapper); ok {
+		return id, true, nil
+	}
+	switch conv.conf.Fallback {
+	case FallbackReplace:
+	case FallbackNearest:
+		if nearest, ok := mapper.Nearest(name); ok {
+			if id, ok := biomeID(nearest, mapper); ok {
+				return id, false, nil
+			}
+		}
+	case FallbackSkip:
+		id, _ := biomes.JavaNameToID("minecraft:ocean")
+		return id, false, nil
+	default:
+		return 0, false, fmt.Errorf("could not find bedrock id for biome name: %v", name)
+	}
+	id, ok := biomeID(conv.conf.fallbackBiome(), mapper)
+	if !ok {
+		return 0, false, fmt.Errorf("fallback biome %v cannot be converted", conv.conf.fallbackBiome())
+	}
+	return id, false, nil
+}
+
+// biomeID returns the Java biome ID of the biome passed, if the mapper can convert it to Bedrock.
+func biomeID(name string, mapper *biomes.Mapper) (int32, bool) {
+	id, ok := biomes.JavaNameToID(name)
+	if !ok {
+		return 0, false
+	}
+	_, ok = mapper.ConvertToBedrock(name)
+	return id, ok
+}
+
+// paletteCounts counts how many entries of the storage passed refer to each of the n palette indices.
+func paletteCounts(n int, storage *column.BitStorage) ([]int, error) {
+	values := make([]int32, storage.Capacity())
+	if err := storage.DecodeAll(values); err != nil {
+		return nil, err
+	}
+	counts := make([]int, n)
+	for _, v := range values {
+		if int(v) < n {
+			counts[v]++
+		}
+	}
+	return counts, nil
+}
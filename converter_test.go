@@ -0,0 +1,285 @@
+package mcanvil
+
+import (
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/justtaldevelops/mcanvil/biomes"
+	"github.com/justtaldevelops/mcanvil/column"
+	"github.com/justtaldevelops/mcanvil/states"
+	"math/bits"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertBlockEntityFallback(t *testing.T) {
+	// The chest at 0, 0, 0 shares its section with a state that cannot be mapped. The block of the chest must be
+	// looked up in the section as resolved by the fallback policy, rather than decoded again without it.
+	chest := states.Block{Name: "minecraft:chest", Properties: map[string]any{"facing": "north", "type": "single", "waterlogged": "false"}}
+	values := make([]int32, 4096)
+	for i := 1; i < len(values); i++ {
+		values[i] = 1
+	}
+	c := testConvertChunk(t, []states.Block{chest, {Name: "mymod:thing"}}, values)
+	c.BlockEntities = []map[string]any{{"id": "minecraft:chest", "x": int32(0), "y": int32(0), "z": int32(0), "Items": []any{}}}
+
+	for _, policy := range []FallbackPolicy{FallbackReplace, FallbackNearest, FallbackSkip} {
+		conv, err := newConverter(Config{Fallback: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, blockEntities, err := conv.convertChunk(c)
+		if err != nil {
+			t.Fatalf("policy %v: %v", policy, err)
+		}
+		if len(blockEntities) != 1 || blockEntities[0]["id"] != "Chest" {
+			t.Errorf("policy %v: expected a chest, got %v", policy, blockEntities)
+		}
+		if n := conv.report.UnmappedBlocks()["mymod:thing"]; n != 4095 {
+			t.Errorf("policy %v: expected 4095 unmapped blocks, got %v", policy, n)
+		}
+	}
+}
+
+func TestConvertFallback(t *testing.T) {
+	grass := states.Block{Name: "minecraft:grass_block", Properties: map[string]any{"snowy": "false", "mymod:mossy": "true"}}
+	for _, test := range []struct {
+		name string
+		conf Config
+		// block and biome are the unmapped Java block state and biome in the upper half of the section.
+		block states.Block
+		biome string
+		// expectedBlock and expectedBiome are the Java block state and biome expected to be converted in their
+		// place. If expectedBiome is empty, the default Bedrock biome is expected.
+		expectedBlock states.Block
+		expectedBiome string
+		// fails is true if the conversion is expected to fail.
+		fails bool
+	}{
+		{name: "none", conf: Config{Fallback: FallbackNone}, block: states.Block{Name: "mymod:thing"}, biome: "minecraft:plains", fails: true},
+		{name: "none biome", conf: Config{Fallback: FallbackNone}, block: states.Block{Name: "minecraft:dirt"}, biome: "mymod:glade", fails: true},
+		{
+			name: "replace", conf: Config{Fallback: FallbackReplace}, block: states.Block{Name: "mymod:thing"}, biome: "mymod:glade",
+			expectedBlock: states.Block{Name: "minecraft:stone"}, expectedBiome: "minecraft:plains",
+		},
+		{
+			name:  "replace custom",
+			conf:  Config{Fallback: FallbackReplace, FallbackBlock: states.Block{Name: "minecraft:dirt"}, FallbackBiome: "minecraft:desert"},
+			block: states.Block{Name: "mymod:thing"}, biome: "mymod:glade",
+			expectedBlock: states.Block{Name: "minecraft:dirt"}, expectedBiome: "minecraft:desert",
+		},
+		{
+			name: "nearest", conf: Config{Fallback: FallbackNearest}, block: grass, biome: "mymod:dark_forest_glade",
+			expectedBlock: states.Block{Name: "minecraft:grass_block", Properties: map[string]any{"snowy": "false"}}, expectedBiome: "minecraft:dark_forest",
+		},
+		{
+			name: "nearest namespace", conf: Config{Fallback: FallbackNearest}, block: grass, biome: "mymod:desert",
+			expectedBlock: states.Block{Name: "minecraft:grass_block", Properties: map[string]any{"snowy": "false"}}, expectedBiome: "minecraft:desert",
+		},
+		{
+			name: "nearest without match", conf: Config{Fallback: FallbackNearest}, block: states.Block{Name: "mymod:thing"}, biome: "mymod:glade",
+			expectedBlock: states.Block{Name: "minecraft:stone"}, expectedBiome: "minecraft:plains",
+		},
+		{
+			name: "skip", conf: Config{Fallback: FallbackSkip}, block: states.Block{Name: "mymod:thing"}, biome: "mymod:glade",
+			expectedBlock: airState,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Blocks below Y 8 are dirt, and blocks above it are the block of the test. The same goes for the
+			// biomes of the 32 cells below and above Y 8.
+			values := make([]int32, 4096)
+			for i := 2048; i < len(values); i++ {
+				values[i] = 1
+			}
+			c := testConvertChunk(t, []states.Block{{Name: "minecraft:dirt"}, test.block}, values)
+			c.Sections[0].Biomes.Palette = []string{"minecraft:forest", test.biome}
+			c.Sections[0].Biomes.Data = []int64{-1 << 32}
+
+			conv, err := newConverter(test.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch, _, err := conv.convertChunk(c)
+			if test.fails {
+				if err == nil {
+					t.Fatal("expected the conversion to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for y, java := range map[int16]states.Block{0: {Name: "minecraft:dirt"}, 15: test.expectedBlock} {
+				expected := bedrockRuntimeID(t, java)
+				if rid := ch.Block(3, y, 3, 0); rid != expected {
+					name, properties, _ := chunk.RuntimeIDToState(rid)
+					t.Errorf("y %v: expected %v, got %v %v", y, java, name, properties)
+				}
+			}
+			forest, _ := biomes.ConvertToBedrock("minecraft:forest")
+			expectedBiome := ch.Biome(0, -64, 0)
+			if test.expectedBiome != "" {
+				expectedBiome, _ = biomes.ConvertToBedrock(test.expectedBiome)
+			}
+			if lower, upper := ch.Biome(3, 0, 3), ch.Biome(3, 15, 3); lower != forest || upper != expectedBiome {
+				t.Errorf("expected biomes [%v %v], got [%v %v]", forest, expectedBiome, lower, upper)
+			}
+
+			unmappedBlocks, unmappedBiomes := conv.report.UnmappedBlocks(), conv.report.UnmappedBiomes()
+			if len(unmappedBlocks) != 1 || unmappedBlocks[test.block.String()] != 2048 {
+				t.Errorf("expected 2048 unmapped blocks of %v, got %v", test.block, unmappedBlocks)
+			}
+			if len(unmappedBiomes) != 1 || unmappedBiomes[test.biome] != 32 {
+				t.Errorf("expected 32 unmapped cells of %v, got %v", test.biome, unmappedBiomes)
+			}
+		})
+	}
+}
+
+func TestConvertCustomMapper(t *testing.T) {
+	c := testConvertChunk(t, []states.Block{{Name: "minecraft:dirt"}, {Name: "mymod:thing"}}, make([]int32, 4096))
+	c.Sections[0].BlockStates.Data[0] = 1
+
+	blocks := states.NewMapper(states.DefaultMapper())
+	blocks.Map(states.Block{Name: "mymod:thing"}, states.Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "granite"}})
+	if err := blocks.LoadJSON(strings.NewReader(`{"minecraft:dirt": {"bedrock_identifier": "minecraft:stone", "bedrock_states": {"stone_type": "stone"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	biomeMapper := biomes.NewMapper(biomes.DefaultMapper())
+	desert, _ := biomes.ConvertToBedrock("minecraft:desert")
+	biomeMapper.Map("minecraft:plains", desert)
+
+	for _, test := range []struct {
+		conf Config
+		// expectedBlocks are the Java states expected to be converted at 0, 0, 0 and 1, 0, 0.
+		expectedBlocks [2]states.Block
+		expectedBiome  string
+	}{
+		{Config{Fallback: FallbackReplace}, [2]states.Block{{Name: "minecraft:stone"}, {Name: "minecraft:dirt"}}, "minecraft:plains"},
+		{Config{Mapper: blocks, BiomeMapper: biomeMapper}, [2]states.Block{{Name: "minecraft:granite"}, {Name: "minecraft:stone"}}, "minecraft:desert"},
+	} {
+		conv, err := newConverter(test.conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch, _, err := conv.convertChunk(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, java := range test.expectedBlocks {
+			expected := bedrockRuntimeID(t, java)
+			if rid := ch.Block(uint8(x), 0, 0, 0); rid != expected {
+				name, properties, _ := chunk.RuntimeIDToState(rid)
+				t.Errorf("mapper %p, x %v: expected %v, got %v %v", test.conf.Mapper, x, java, name, properties)
+			}
+		}
+		if expected, _ := biomes.ConvertToBedrock(test.expectedBiome); ch.Biome(0, 0, 0) != expected {
+			t.Errorf("mapper %p: expected biome %v, got %v", test.conf.BiomeMapper, test.expectedBiome, ch.Biome(0, 0, 0))
+		}
+	}
+}
+
+func TestConvertYShift(t *testing.T) {
+	chest := states.Block{Name: "minecraft:chest", Properties: map[string]any{"facing": "north", "type": "single", "waterlogged": "false"}}
+	for _, test := range []struct {
+		name string
+		yPos int32
+		conf Config
+		// sections are the Y of the sections of the Java chunk, each holding only chests and a chest block entity
+		// at its lowest block.
+		sections []int8
+		// expected are the Bedrock Y of the sections expected to be converted, in the order of sections.
+		expected []int
+		clipped  int
+	}{
+		{name: "none", yPos: -4, sections: []int8{-4, 0, 19}, expected: []int{-64, 0, 304}},
+		{name: "down", yPos: 0, conf: Config{YShift: -64}, sections: []int8{0, 15}, expected: []int{-64, 176}},
+		{name: "up", yPos: -4, conf: Config{YShift: 64}, sections: []int8{-4, 15, 16}, expected: []int{0, 304}, clipped: 1},
+		{name: "down clipped", yPos: -4, conf: Config{YShift: -16}, sections: []int8{-4, -3}, expected: []int{-64}, clipped: 1},
+		// Sections outside the Java range are light sections, which are left out without being clipped.
+		{name: "java range", yPos: -4, conf: Config{JavaRange: cube.Range{0, 255}}, sections: []int8{-1, 0, 16}, expected: []int{0}},
+		{name: "custom height", yPos: -8, conf: Config{JavaRange: cube.Range{-128, 383}}, sections: []int8{-8, -4, 19, 23}, expected: []int{-64, 304}, clipped: 2},
+		{name: "custom height up", yPos: -8, conf: Config{JavaRange: cube.Range{-128, 383}, YShift: 64}, sections: []int8{-8, 19}, expected: []int{-64}, clipped: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := testConvertChunk(t, []states.Block{chest}, nil)
+			c.YPos = test.yPos
+			section := c.Sections[0]
+			c.Sections = nil
+			for _, y := range test.sections {
+				section.Y = byte(y)
+				c.Sections = append(c.Sections, section)
+				c.BlockEntities = append(c.BlockEntities, map[string]any{"id": "minecraft:chest", "x": int32(0), "y": int32(y) << 4, "z": int32(0), "Items": []any{}})
+			}
+
+			conv, err := newConverter(test.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch, blockEntities, err := conv.convertChunk(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var converted []int
+			for i, sub := range ch.Sub() {
+				if !sub.Empty() {
+					converted = append(converted, i<<4+ch.Range().Min())
+				}
+			}
+			var convertedBlockEntities []int
+			for _, data := range blockEntities {
+				convertedBlockEntities = append(convertedBlockEntities, int(data["y"].(int32)))
+			}
+			if !reflect.DeepEqual(converted, test.expected) || !reflect.DeepEqual(convertedBlockEntities, test.expected) {
+				t.Errorf("expected sections and block entities at %v, got %v and %v", test.expected, converted, convertedBlockEntities)
+			}
+			rid := bedrockRuntimeID(t, chest)
+			for _, y := range test.expected {
+				if ch.Block(0, int16(y), 0, 0) != rid || ch.Block(15, int16(y+15), 15, 0) != rid {
+					t.Errorf("expected chests in the section at %v", y)
+				}
+			}
+			if n := conv.report.ClippedSections(); n != test.clipped {
+				t.Errorf("expected %v clipped sections, got %v", test.clipped, n)
+			}
+		})
+	}
+
+	if _, err := newConverter(Config{YShift: 8}); err == nil {
+		t.Error("expected an error for a y shift that is not a multiple of 16")
+	}
+}
+
+// bedrockRuntimeID returns the runtime ID of the Bedrock block that the Java state passed converts to.
+func bedrockRuntimeID(t *testing.T, java states.Block) uint32 {
+	t.Helper()
+	bedrock, _, ok := states.ConvertToBedrock(java)
+	if !ok {
+		t.Fatalf("could not convert %v", java)
+	}
+	rid, ok := chunk.StateToRuntimeID(bedrock.Name, bedrock.Properties)
+	if !ok {
+		t.Fatalf("could not find runtime id of %v", bedrock)
+	}
+	return rid
+}
+
+// testConvertChunk returns a full 1.19 chunk at 0, 0 with a single section at Y 0, holding the palette passed
+// and the palette index of every block in values. The biomes of the section are plains.
+func testConvertChunk(t *testing.T, palette []states.Block, values []int32) *Chunk {
+	t.Helper()
+	c := &Chunk{DataVersion: 3105, YPos: -4, Status: "full", Sections: []SubChunk{{Y: 0}}}
+	c.Sections[0].BlockStates.Palette = palette
+	if len(palette) > 1 {
+		storage := column.NewEmptyBitStorage(maxInt32(4, int32(bits.Len(uint(len(palette)-1)))), 4096)
+		if err := storage.EncodeAll(values); err != nil {
+			t.Fatal(err)
+		}
+		c.Sections[0].BlockStates.Data = storage.Data()
+	}
+	c.Sections[0].Biomes.Palette = []string{"minecraft:plains"}
+	return c
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	_ "embed"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	//go:embed entities.json
+	entityMappingData []byte
+
+	// javaToBedrockEntity is a map between Java entity IDs and Bedrock entity IDs. Entities that Bedrock does not
+	// have are mapped to an empty string.
+	javaToBedrockEntity = make(map[string]string)
+	// bedrockToJavaEntity is a map between Bedrock entity IDs and Java entity IDs.
+	bedrockToJavaEntity = make(map[string]string)
+)
+
+func init() {
+	gjson.ParseBytes(entityMappingData).ForEach(func(key, value gjson.Result) bool {
+		javaToBedrockEntity[key.String()] = value.String()
+		if value.Type != gjson.Null {
+			bedrockToJavaEntity[value.String()] = key.String()
+		}
+		return true
+	})
+}
+
+// ConvertToBedrock converts a Java entity ID, such as "minecraft:zombified_piglin", to a Bedrock entity ID.
+// Entities that are not in the mappings are assumed to have the same ID in both editions. False is returned if
+// Bedrock does not have the entity.
+func ConvertToBedrock(id string) (string, bool) {
+	if bedrockID, ok := javaToBedrockEntity[id]; ok {
+		return bedrockID, bedrockID != ""
+	}
+	return id, true
+}
+
+// ConvertToJava converts a Bedrock entity ID to a Java entity ID. Entities that are not in the mappings are
+// assumed to have the same ID in both editions.
+func ConvertToJava(id string) string {
+	if javaID, ok := bedrockToJavaEntity[id]; ok {
+		return javaID
+	}
+	return id
+}
@@ -0,0 +1,40 @@
+package mcanvil
+
+import (
+	"fmt"
+	"github.com/df-mc/dragonfly/server/block/cube"
+)
+
+// SectionError is returned when the block states or biomes of a section could not be decoded or converted. It
+// wraps the error that occurred, which is often one of the errors of the column package, such as
+// column.ErrValueTooLarge, so that callers may use errors.Is and errors.As to decide whether to skip the section.
+type SectionError struct {
+	// Y is the Y of the section.
+	Y int8
+	// Err is the error that occurred.
+	Err error
+}
+
+// Error returns the message of the error.
+func (e *SectionError) Error() string {
+	return fmt.Sprintf("section %v: %v", e.Y, e.Err)
+}
+
+// Unwrap returns the error that occurred in the section.
+func (e *SectionError) Unwrap() error {
+	return e.Err
+}
+
+// PositionError is returned when a block or biome is read or written at a position outside of the vertical range
+// of a chunk or level.
+type PositionError struct {
+	// Pos is the world position passed.
+	Pos cube.Pos
+	// Range is the vertical range that the position lies outside of.
+	Range cube.Range
+}
+
+// Error returns the message of the error.
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("position %v is outside of the vertical range %v to %v", e.Pos, e.Range.Min(), e.Range.Max())
+}
@@ -0,0 +1,448 @@
+package mcanvil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+	"github.com/justtaldevelops/mcanvil/column"
+	"math/bits"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// update is set to rewrite golden files with the output of the tests, rather than comparing against them.
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// testStates holds the Java block states of 1.19 that test worlds are made of. Every state has the same Bedrock
+// state in all versions of the block mappings.
+var testStates = []map[string]any{
+	{"Name": "minecraft:air"},
+	{"Name": "minecraft:stone"},
+	{"Name": "minecraft:granite"},
+	{"Name": "minecraft:dirt"},
+	{"Name": "minecraft:grass_block", "Properties": map[string]any{"snowy": "false"}},
+	{"Name": "minecraft:grass_block", "Properties": map[string]any{"snowy": "true"}},
+	{"Name": "minecraft:water", "Properties": map[string]any{"level": "0"}},
+	{"Name": "minecraft:water", "Properties": map[string]any{"level": "1"}},
+	{"Name": "minecraft:lava", "Properties": map[string]any{"level": "0"}},
+	{"Name": "minecraft:oak_stairs", "Properties": map[string]any{"facing": "north", "half": "top", "shape": "straight", "waterlogged": "false"}},
+	{"Name": "minecraft:oak_stairs", "Properties": map[string]any{"facing": "north", "half": "top", "shape": "straight", "waterlogged": "true"}},
+	{"Name": "minecraft:seagrass"},
+	{"Name": "minecraft:kelp", "Properties": map[string]any{"age": "0"}},
+	{"Name": "minecraft:bubble_column", "Properties": map[string]any{"drag": "false"}},
+	{"Name": "minecraft:dirt_path"},
+	{"Name": "minecraft:cauldron"},
+	{"Name": "minecraft:water_cauldron", "Properties": map[string]any{"level": "2"}},
+	{"Name": "minecraft:spawner"},
+	{"Name": "minecraft:chest", "Properties": map[string]any{"facing": "north", "type": "single", "waterlogged": "false"}},
+}
+
+// legacyTestStates holds the Java block states of 1.15 that legacy chunks of test worlds are made of. Grass paths
+// and cauldrons are renamed when the chunks are upgraded.
+var legacyTestStates = []map[string]any{
+	{"Name": "minecraft:air"},
+	{"Name": "minecraft:stone"},
+	{"Name": "minecraft:granite"},
+	{"Name": "minecraft:dirt"},
+	{"Name": "minecraft:grass_block", "Properties": map[string]any{"snowy": "false"}},
+	{"Name": "minecraft:grass_block", "Properties": map[string]any{"snowy": "true"}},
+	{"Name": "minecraft:water", "Properties": map[string]any{"level": "0"}},
+	{"Name": "minecraft:water", "Properties": map[string]any{"level": "1"}},
+	{"Name": "minecraft:lava", "Properties": map[string]any{"level": "0"}},
+	{"Name": "minecraft:oak_stairs", "Properties": map[string]any{"facing": "north", "half": "top", "shape": "straight", "waterlogged": "false"}},
+	{"Name": "minecraft:oak_stairs", "Properties": map[string]any{"facing": "north", "half": "top", "shape": "straight", "waterlogged": "true"}},
+	{"Name": "minecraft:seagrass"},
+	{"Name": "minecraft:kelp", "Properties": map[string]any{"age": "0"}},
+	{"Name": "minecraft:bubble_column", "Properties": map[string]any{"drag": "false"}},
+	{"Name": "minecraft:grass_path"},
+	{"Name": "minecraft:cauldron", "Properties": map[string]any{"level": "0"}},
+	{"Name": "minecraft:cauldron", "Properties": map[string]any{"level": "2"}},
+}
+
+// testBiomes holds the Java biomes that test worlds are made of.
+var testBiomes = []string{
+	"minecraft:plains", "minecraft:forest", "minecraft:desert", "minecraft:taiga",
+	"minecraft:swamp", "minecraft:river", "minecraft:beach", "minecraft:jungle",
+	"minecraft:savanna", "minecraft:badlands", "minecraft:mushroom_fields", "minecraft:dark_forest",
+}
+
+// writeTestWorld writes a small world with known contents to a temporary directory and returns its path. The
+// world holds a 1.19 chunk with sections of every palette kind and block entities, chunks of 1.15 to 1.18 that
+// must be upgraded and an incomplete chunk that must not be converted.
+func writeTestWorld(tb testing.TB) string {
+	chunks := releaseTestChunks(tb)
+	chunks[[2]int32{0, 0}] = testChunk(tb)
+	chunks[[2]int32{1, 0}] = legacyTestChunk(tb)
+	chunks[[2]int32{0, 1}] = map[string]any{"DataVersion": int32(3105), "xPos": int32(0), "yPos": int32(-4), "zPos": int32(1), "Status": "features"}
+	return writeWorld(tb, chunks)
+}
+
+// testChunk returns a 1.19 chunk at 0, 0 with sections of every palette kind. The sections in order hold a
+// single state, a list palette, a map palette, a palette needing more bits than any map palette, liquids and
+// block entities, and only air. The first section holds more biomes than a biome map palette can.
+func testChunk(tb testing.TB) map[string]any {
+	wide := make([]map[string]any, 300)
+	for i := range wide {
+		// Vanilla never saves palettes holding the same state twice, but it does not prevent reading them.
+		wide[i] = testStates[i%len(testStates)]
+	}
+	sections := []any{
+		// Java keeps biome palettes local however many biomes they hold, rather than switching to global IDs.
+		testSection(tb, -4, testStates[1:2], testBiomes, nil),
+		testSection(tb, -3, testStates[1:5], testBiomes[:2], func(x, y, z int) int { return (x + y + z) % 4 }),
+		testSection(tb, -2, testStates, testBiomes[:4], func(x, y, z int) int { return (x*7 + y*3 + z) % len(testStates) }),
+		testSection(tb, -1, wide, testBiomes[:8], func(x, y, z int) int { return (y<<8 | z<<4 | x) % len(wide) }),
+		testSection(tb, 0, []map[string]any{testStates[0], testStates[6], testStates[10], testStates[11], testStates[12], testStates[17], testStates[18]}, testBiomes[:1], func(x, y, z int) int {
+			switch {
+			case y == 1 && z == 1 && x == 1:
+				return 6 // The chest.
+			case y == 1 && z == 1 && x == 2:
+				return 5 // The spawner.
+			case y > 8:
+				return 0
+			}
+			return 1 + (x+z)%4
+		}),
+		testSection(tb, 1, testStates[:1], testBiomes[:1], nil),
+	}
+	blockEntities := []any{
+		map[string]any{
+			"id": "minecraft:chest", "x": int32(1), "y": int32(1), "z": int32(1), "keepPacked": byte(0),
+			"CustomName": `{"text":"Loot"}`,
+			"Items": []any{
+				map[string]any{"Slot": byte(0), "id": "minecraft:stone", "Count": byte(3)},
+				map[string]any{"Slot": byte(13), "id": "minecraft:dirt", "Count": byte(64)},
+			},
+		},
+		map[string]any{
+			"id": "minecraft:mob_spawner", "x": int32(2), "y": int32(1), "z": int32(1), "keepPacked": byte(0),
+			"Delay": int16(20), "SpawnData": map[string]any{"entity": map[string]any{"id": "minecraft:pig"}},
+		},
+	}
+	return map[string]any{
+		"DataVersion":    int32(3105),
+		"xPos":           int32(0),
+		"yPos":           int32(-4),
+		"zPos":           int32(0),
+		"Status":         "full",
+		"sections":       sections,
+		"block_entities": blockEntities,
+	}
+}
+
+// testSection returns a 1.19 section at the Y passed, holding the states passed as its palette. The palette
+// index at each position is returned by index, which may be nil if the palette holds only one state. Biomes
+// are laid out in order over the cells of the section.
+func testSection(tb testing.TB, y int, palette []map[string]any, biomes []string, index func(x, y, z int) int) map[string]any {
+	blockStates := map[string]any{"palette": toAny(palette)}
+	if len(palette) > 1 {
+		values := make([]int32, 4096)
+		for i := range values {
+			values[i] = int32(index(i&15, i>>8, (i>>4)&15))
+		}
+		blockStates["data"] = packTestData(tb, int32(len(palette)), 4, values, false)
+	}
+	biomePalette := make([]any, len(biomes))
+	for i, name := range biomes {
+		biomePalette[i] = name
+	}
+	biomeStates := map[string]any{"palette": biomePalette}
+	if len(biomes) > 1 {
+		values := make([]int32, 64)
+		for i := range values {
+			values[i] = int32(i % len(biomes))
+		}
+		biomeStates["data"] = packTestData(tb, int32(len(biomes)), 0, values, false)
+	}
+	return map[string]any{"Y": byte(y), "block_states": blockStates, "biomes": biomeStates}
+}
+
+// legacyTestChunk returns a 1.15 chunk at 1, 0, which packs its block states spanning longs and keeps its
+// biomes in a single array. Its top section holds only granite, which 1.15 still packs in 4 bits per block.
+func legacyTestChunk(tb testing.TB) map[string]any {
+	values := make([]int32, 4096)
+	for i := range values {
+		values[i] = int32(i % len(legacyTestStates))
+	}
+	halves := make([]int32, 4096)
+	for i := range halves {
+		halves[i] = int32(i >> 11)
+	}
+	// Arrays are encoded as int array tags, as vanilla does, while slices would be encoded as lists.
+	var biomes [1024]int32
+	for i := range biomes {
+		// Plains in the lowest section of the chunk, and forests above it.
+		biomes[i] = 1
+		if i >= 64 {
+			biomes[i] = 4
+		}
+	}
+	return map[string]any{
+		"DataVersion": int32(2230),
+		"Level": map[string]any{
+			"xPos":   int32(1),
+			"zPos":   int32(0),
+			"Status": "full",
+			"Biomes": biomes,
+			"Sections": []any{
+				map[string]any{"Y": byte(0), "Palette": toAny(legacyTestStates), "BlockStates": packTestData(tb, int32(len(legacyTestStates)), 4, values, true)},
+				map[string]any{"Y": byte(1), "Palette": toAny(legacyTestStates[:2]), "BlockStates": packTestData(tb, 2, 4, halves, true)},
+				map[string]any{"Y": byte(2), "Palette": toAny(legacyTestStates[2:3]), "BlockStates": packTestData(tb, 1, 4, make([]int32, 4096), true)},
+			},
+		},
+	}
+}
+
+// releaseTestChunks returns chunks saved by Minecraft 1.16, 1.17 and 1.18, starting at 2, 0. Each holds the
+// block states and biomes of its release, which are upgraded to their current names before converting them.
+func releaseTestChunks(tb testing.TB) map[[2]int32]map[string]any {
+	values := make([]int32, 4096)
+	for i := range values {
+		values[i] = int32(i % len(legacyTestStates))
+	}
+	// Mountains and snowy tundras were renamed to windswept hills and snowy plains in 1.18.
+	var biomes1165 [1024]int32
+	for i := range biomes1165 {
+		biomes1165[i] = 3
+		if i&3 == 0 {
+			biomes1165[i] = 12
+		}
+	}
+	states117 := testStates[:17]
+	values117 := make([]int32, 4096)
+	for i := range values117 {
+		values117[i] = int32((i >> 4) % len(states117))
+	}
+	var biomes117 [1024]int32
+	for i := range biomes117 {
+		biomes117[i] = 21
+		if i >= 512 {
+			biomes117[i] = 5
+		}
+	}
+	return map[[2]int32]map[string]any{
+		{2, 0}: {
+			"DataVersion": int32(2586),
+			"Level": map[string]any{
+				"xPos": int32(2), "zPos": int32(0), "Status": "full", "Biomes": biomes1165,
+				"Sections": []any{
+					map[string]any{"Y": byte(2), "Palette": toAny(legacyTestStates), "BlockStates": packTestData(tb, int32(len(legacyTestStates)), 4, values, false)},
+				},
+			},
+		},
+		{3, 0}: {
+			"DataVersion": int32(2730),
+			"Level": map[string]any{
+				"xPos": int32(3), "zPos": int32(0), "Status": "full", "Biomes": biomes117,
+				"Sections": []any{
+					map[string]any{"Y": byte(3), "Palette": toAny(states117), "BlockStates": packTestData(tb, int32(len(states117)), 4, values117, false)},
+				},
+			},
+		},
+		{4, 0}: {
+			"DataVersion": int32(2975),
+			"xPos":        int32(4), "yPos": int32(-4), "zPos": int32(0), "Status": "full",
+			"sections": []any{
+				testSection(tb, -4, testStates[1:5], []string{"minecraft:windswept_hills", "minecraft:meadow", "minecraft:grove"}, func(x, y, z int) int { return (x * z) % 4 }),
+			},
+		},
+	}
+}
+
+// packTestData packs the palette indices passed into longs, using enough bits for a palette of the size passed
+// and at least the minimum number of bits passed.
+func packTestData(tb testing.TB, size, min int32, values []int32, spanning bool) any {
+	n := maxInt32(int32(bits.Len(uint(size-1))), min)
+	storage := column.NewEmptyBitStorage(n, int32(len(values)))
+	if spanning {
+		storage = column.NewEmptySpanningBitStorage(n, int32(len(values)))
+	}
+	if err := storage.EncodeAll(values); err != nil {
+		tb.Fatal(err)
+	}
+	return longArray(storage.Data())
+}
+
+// toAny returns the compounds passed as a list of NBT values.
+func toAny(compounds []map[string]any) []any {
+	l := make([]any, len(compounds))
+	for i, c := range compounds {
+		l[i] = c
+	}
+	return l
+}
+
+// dumpBedrock returns a readable dump of the contents of the Bedrock world in the directory passed. It lists
+// every key of its LevelDB database, followed by the blocks, biomes and block entities of every chunk. As the
+// encoding of states and block entities depends on map order, their contents are dumped rather than their bytes.
+func dumpBedrock(t *testing.T, dir string) string {
+	db, err := leveldb.OpenFile(path.Join(dir, "db"), &opt.Options{Compression: opt.FlateCompression, ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		buf       strings.Builder
+		positions []world.ChunkPos
+	)
+	iter := db.NewIterator(nil, nil)
+	for iter.Next() {
+		key, value := iter.Key(), iter.Value()
+		if len(key) != 9 && len(key) != 10 {
+			fmt.Fprintf(&buf, "key %q\n", key)
+			continue
+		}
+		pos := world.ChunkPos{int32(binary.LittleEndian.Uint32(key)), int32(binary.LittleEndian.Uint32(key[4:]))}
+		if len(positions) == 0 || positions[len(positions)-1] != pos {
+			positions = append(positions, pos)
+		}
+		fmt.Fprintf(&buf, "key %v %v tag %#x", pos[0], pos[1], key[8])
+		if len(key) == 10 {
+			fmt.Fprintf(&buf, " sub %v", int8(key[9]))
+		}
+		if len(value) <= 8 {
+			fmt.Fprintf(&buf, " = %x", value)
+		}
+		buf.WriteString("\n")
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	prov, err := mcdb.New(dir, opt.FlateCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prov.Close()
+	for _, pos := range positions {
+		c, ok, err := prov.LoadChunk(pos, world.Overworld)
+		if err != nil || !ok {
+			t.Fatalf("chunk %v: %v (exists: %v)", pos, err, ok)
+		}
+		fmt.Fprintf(&buf, "chunk %v %v\n", pos[0], pos[1])
+		for i, sub := range c.Sub() {
+			y := int16(c.SubY(int16(i)))
+			if sub.Empty() {
+				continue
+			}
+			for layer := range sub.Layers() {
+				fmt.Fprintf(&buf, "  sub %v layer %v\n", y>>4, layer)
+				dumpCounts(&buf, func(x, z uint8, y int16) string {
+					name, properties, _ := chunk.RuntimeIDToState(c.Block(x, y, z, uint8(layer)))
+					return name + formatNBT(properties)
+				}, y)
+			}
+			fmt.Fprintf(&buf, "  sub %v biomes\n", y>>4)
+			dumpCounts(&buf, func(x, z uint8, y int16) string {
+				return fmt.Sprint(c.Biome(x, y, z))
+			}, y)
+		}
+		blockEntities, err := prov.LoadBlockNBT(pos, world.Overworld)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range blockEntities {
+			fmt.Fprintf(&buf, "  block entity %v\n", formatNBT(data))
+		}
+	}
+	return buf.String()
+}
+
+// dumpCounts writes the number of blocks of a sub-chunk starting at the Y passed with each value returned by f,
+// followed by a hash of the values of all blocks, so that changes in their layout are caught too.
+func dumpCounts(buf *strings.Builder, f func(x, z uint8, y int16) string, baseY int16) {
+	counts, hash := make(map[string]int), sha256.New()
+	for y := baseY; y < baseY+16; y++ {
+		for z := uint8(0); z < 16; z++ {
+			for x := uint8(0); x < 16; x++ {
+				v := f(x, z, y)
+				counts[v]++
+				hash.Write([]byte(v + "\n"))
+			}
+		}
+	}
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	for _, v := range values {
+		fmt.Fprintf(buf, "    %v: %v\n", v, counts[v])
+	}
+	fmt.Fprintf(buf, "    hash: %v\n", hex.EncodeToString(hash.Sum(nil)[:8]))
+}
+
+// formatNBT formats an NBT value with its type and map keys in sorted order.
+func formatNBT(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			entries[i] = k + ": " + formatNBT(v[k])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case []any:
+		entries := make([]string, len(v))
+		for i, e := range v {
+			entries[i] = formatNBT(e)
+		}
+		return "[" + strings.Join(entries, ", ") + "]"
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprintf("%T(%v)", v, v)
+}
+
+// checkGolden compares the output passed with the golden file at the path passed, or rewrites the golden file
+// if the update flag is set.
+func checkGolden(t *testing.T, file, output string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(path.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(output), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if bytes.Equal(golden, []byte(output)) {
+		return
+	}
+	want, got := strings.Split(string(golden), "\n"), strings.Split(output, "\n")
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			t.Fatalf("output differs from %v at line %v:\nwant: %v\ngot:  %v", file, i+1, w, g)
+		}
+	}
+}
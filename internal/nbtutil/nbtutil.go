@@ -0,0 +1,19 @@
+// Package nbtutil implements helpers for reading the values of decoded NBT tags.
+package nbtutil
+
+// Int returns the value of any integer tag as an int64.
+func Int(v any) (int64, bool) {
+	switch v := v.(type) {
+	case uint8:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+// Package versions implements the registry used to select block state and biome mappers by the Bedrock protocol
+// version targeted. Mappers are not selected by Java data version: chunks of older Java versions are upgraded to
+// the current format and names when they are read, so a single set of Java mappings covers all of them.
+package versions
+
+import "sync"
+
+// Registry holds values registered for a Bedrock protocol version. The zero value is an empty registry ready for
+// use. A Registry is safe for concurrent use.
+type Registry[T any] struct {
+	mu      sync.RWMutex
+	entries []entry[T]
+}
+
+// entry is a value registered for a Bedrock protocol version.
+type entry[T any] struct {
+	protocol int32
+	value    T
+}
+
+// Register registers a value for the Bedrock protocol version passed. A value registered earlier for the same
+// version is replaced.
+func (r *Registry[T]) Register(protocol int32, value T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.entries {
+		if e.protocol == protocol {
+			r.entries[i].value = value
+			return
+		}
+	}
+	r.entries = append(r.entries, entry[T]{protocol: protocol, value: value})
+}
+
+// Lookup returns the registered value best suited for the Bedrock protocol version passed, which is the one
+// registered for the newest protocol version not newer than the one passed. If no registered version is old
+// enough, the oldest one is used instead. False is returned if no value was registered at all.
+func (r *Registry[T]) Lookup(protocol int32) (T, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var nearest, oldest *entry[T]
+	for i, e := range r.entries {
+		if e.protocol <= protocol && (nearest == nil || e.protocol > nearest.protocol) {
+			nearest = &r.entries[i]
+		}
+		if oldest == nil || e.protocol < oldest.protocol {
+			oldest = &r.entries[i]
+		}
+	}
+	if nearest != nil {
+		return nearest.value, true
+	}
+	if oldest != nil {
+		return oldest.value, true
+	}
+	var zero T
+	return zero, false
+}
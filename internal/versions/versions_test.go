@@ -0,0 +1,35 @@
+package versions
+
+import "testing"
+
+func TestRegistryLookup(t *testing.T) {
+	var r Registry[string]
+	if _, ok := r.Lookup(527); ok {
+		t.Fatalf("expected no value in an empty registry")
+	}
+	r.Register(527, "1.19.0")
+	r.Register(475, "1.18.0")
+	r.Register(560, "1.19.50")
+	for _, test := range []struct {
+		protocol int32
+		expected string
+	}{
+		{protocol: 527, expected: "1.19.0"},
+		{protocol: 544, expected: "1.19.0"},
+		{protocol: 475, expected: "1.18.0"},
+		{protocol: 503, expected: "1.18.0"},
+		// Protocols older than any registered use the oldest one registered.
+		{protocol: 440, expected: "1.18.0"},
+		{protocol: 560, expected: "1.19.50"},
+		{protocol: 589, expected: "1.19.50"},
+	} {
+		if v, ok := r.Lookup(test.protocol); !ok || v != test.expected {
+			t.Errorf("protocol %v: expected %q, got %q", test.protocol, test.expected, v)
+		}
+	}
+
+	r.Register(527, "replaced")
+	if v, _ := r.Lookup(544); v != "replaced" {
+		t.Fatalf("expected replaced value, got %q", v)
+	}
+}
@@ -0,0 +1,100 @@
+package items
+
+import (
+	_ "embed"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	//go:embed items.json
+	itemMappingData []byte
+	//go:embed enchantments.json
+	enchantmentMappingData []byte
+	//go:embed potions.json
+	potionMappingData []byte
+
+	// javaToBedrockItem is a map between a Java item ID and a Bedrock item.
+	javaToBedrockItem = make(map[string]bedrockItem)
+	// bedrockToJavaItem is a map between a Bedrock item and a Java item ID.
+	bedrockToJavaItem = make(map[bedrockItem]string)
+	// javaToBedrockEnchantment is a map between a Java enchantment ID and a Bedrock enchantment ID.
+	javaToBedrockEnchantment = make(map[string]int16)
+	// bedrockToJavaEnchantment is a map between a Bedrock enchantment ID and a Java enchantment ID.
+	bedrockToJavaEnchantment = make(map[int16]string)
+	// javaToBedrockPotion is a map between a Java potion ID and a Bedrock potion ID.
+	javaToBedrockPotion = make(map[string]int16)
+	// bedrockToJavaPotion is a map between a Bedrock potion ID and a Java potion ID.
+	bedrockToJavaPotion = make(map[int16]string)
+)
+
+// bedrockItem is a Bedrock item type, made up of a name and data value.
+type bedrockItem struct {
+	name string
+	data int16
+}
+
+func init() {
+	gjson.ParseBytes(itemMappingData).ForEach(func(key, value gjson.Result) bool {
+		item := bedrockItem{name: value.Get("bedrock_id").String(), data: int16(value.Get("bedrock_data").Int())}
+		javaToBedrockItem[key.String()] = item
+		if _, ok := bedrockToJavaItem[item]; !ok {
+			bedrockToJavaItem[item] = key.String()
+		}
+		return true
+	})
+	gjson.ParseBytes(enchantmentMappingData).ForEach(func(key, value gjson.Result) bool {
+		javaToBedrockEnchantment[key.String()] = int16(value.Int())
+		bedrockToJavaEnchantment[int16(value.Int())] = key.String()
+		return true
+	})
+	gjson.ParseBytes(potionMappingData).ForEach(func(key, value gjson.Result) bool {
+		javaToBedrockPotion[key.String()] = int16(value.Int())
+		bedrockToJavaPotion[int16(value.Int())] = key.String()
+		return true
+	})
+}
+
+// ConvertToBedrock converts a Java item ID to a Bedrock item name and data value. Items that are not in the
+// mappings are assumed to have the same name in both editions.
+func ConvertToBedrock(id string) (string, int16) {
+	if item, ok := javaToBedrockItem[id]; ok {
+		return item.name, item.data
+	}
+	return id, 0
+}
+
+// ConvertToJava converts a Bedrock item name and data value to a Java item ID. Items that are not in the
+// mappings are assumed to have the same name in both editions.
+func ConvertToJava(name string, data int16) string {
+	if id, ok := bedrockToJavaItem[bedrockItem{name: name, data: data}]; ok {
+		return id
+	}
+	if id, ok := bedrockToJavaItem[bedrockItem{name: name}]; ok {
+		return id
+	}
+	return name
+}
+
+// EnchantmentToBedrock converts a Java enchantment ID, such as "minecraft:sharpness", to a Bedrock enchantment ID.
+func EnchantmentToBedrock(id string) (int16, bool) {
+	e, ok := javaToBedrockEnchantment[id]
+	return e, ok
+}
+
+// EnchantmentToJava converts a Bedrock enchantment ID to a Java enchantment ID.
+func EnchantmentToJava(id int16) (string, bool) {
+	e, ok := bedrockToJavaEnchantment[id]
+	return e, ok
+}
+
+// PotionToBedrock converts a Java potion ID, such as "minecraft:long_swiftness", to a Bedrock potion ID.
+func PotionToBedrock(id string) (int16, bool) {
+	p, ok := javaToBedrockPotion[id]
+	return p, ok
+}
+
+// PotionToJava converts a Bedrock potion ID to a Java potion ID.
+func PotionToJava(id int16) (string, bool) {
+	p, ok := bedrockToJavaPotion[id]
+	return p, ok
+}
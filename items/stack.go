@@ -0,0 +1,613 @@
+package items
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/internal/nbtutil"
+	"github.com/justtaldevelops/mcanvil/text"
+	"sort"
+)
+
+// ComponentsDataVersion is the Java data version of Minecraft 1.20.5, which replaced the "tag" compound of item
+// stacks with data components.
+const ComponentsDataVersion = 3837
+
+// TextNBTDataVersion is the Java data version of Minecraft 1.21.5, since which text components, such as the custom
+// name and lore of items, are stored as NBT rather than as JSON.
+const TextNBTDataVersion = 4325
+
+// stack is an edition independent representation of an item stack, holding all data that is translated.
+type stack struct {
+	// id is the Java item ID.
+	id    string
+	count int
+	// slot is the slot the stack is in, if hasSlot is true.
+	slot    uint8
+	hasSlot bool
+
+	// damage is the durability used up of the item.
+	damage int32
+	// name and lore are Java JSON text components.
+	name string
+	lore []string
+
+	enchantments       map[string]int16
+	storedEnchantments map[string]int16
+
+	potion string
+	// color is the RGB dyed color of the item, such as for leather armour, if hasColor is true.
+	color    int32
+	hasColor bool
+
+	book *book
+
+	repairCost  int32
+	unbreakable bool
+}
+
+// book holds the contents of a writable or written book. Pages of written books are Java JSON text components,
+// while pages of writable books are plain text.
+type book struct {
+	title, author string
+	generation    int32
+	pages         []string
+}
+
+// ToBedrock converts a Java item stack of the Java data version passed to a Bedrock item stack. Both the "tag"
+// format and the data components format used since 1.20.5 are supported. Names, lore and book pages are read as
+// NBT text components for data versions of TextNBTDataVersion and up, and as JSON for older versions. They are
+// converted using the text options passed.
+func ToBedrock(item map[string]any, dataVersion int32, opts text.Options) (map[string]any, error) {
+	s, err := parseJava(item, dataVersion)
+	if err != nil {
+		return nil, err
+	}
+	return s.bedrock(opts), nil
+}
+
+// ToJava converts a Bedrock item stack to a Java item stack for the Java data version passed. Data components
+// are used for data versions of ComponentsDataVersion and up, and the "tag" compound for older versions.
+func ToJava(item map[string]any, dataVersion int32) (map[string]any, error) {
+	s, err := parseBedrock(item)
+	if err != nil {
+		return nil, err
+	}
+	if dataVersion >= ComponentsDataVersion {
+		return s.javaComponents(), nil
+	}
+	return s.javaTag(), nil
+}
+
+// parseJava parses a Java item stack of the Java data version passed in either the "tag" or data components
+// format.
+func parseJava(item map[string]any, dataVersion int32) (stack, error) {
+	id, ok := item["id"].(string)
+	if !ok {
+		return stack{}, fmt.Errorf("item has no id")
+	}
+	s := stack{id: id, count: 1}
+	if count, ok := nbtutil.Int(item["Count"]); ok {
+		s.count = int(count)
+	} else if count, ok := nbtutil.Int(item["count"]); ok {
+		s.count = int(count)
+	}
+	if slot, ok := nbtutil.Int(item["Slot"]); ok {
+		s.slot, s.hasSlot = uint8(slot), true
+	}
+
+	if components, ok := item["components"].(map[string]any); ok {
+		if err := s.parseComponents(components, dataVersion); err != nil {
+			return stack{}, err
+		}
+	} else if tag, ok := item["tag"].(map[string]any); ok {
+		s.parseTag(tag)
+	}
+	return s, nil
+}
+
+// parseTag parses the "tag" compound of a Java item stack from before 1.20.5.
+func (s *stack) parseTag(tag map[string]any) {
+	if damage, ok := nbtutil.Int(tag["Damage"]); ok {
+		s.damage = int32(damage)
+	}
+	if display, ok := tag["display"].(map[string]any); ok {
+		s.name, _ = display["Name"].(string)
+		s.lore = stringList(display["Lore"])
+		if color, ok := nbtutil.Int(display["color"]); ok {
+			s.color, s.hasColor = int32(color), true
+		}
+	}
+	s.enchantments = enchantmentList(tag["Enchantments"])
+	s.storedEnchantments = enchantmentList(tag["StoredEnchantments"])
+	s.potion, _ = tag["Potion"].(string)
+	if pages, ok := tag["pages"]; ok {
+		s.book = &book{pages: stringList(pages)}
+		s.book.title, _ = tag["title"].(string)
+		s.book.author, _ = tag["author"].(string)
+		if generation, ok := nbtutil.Int(tag["generation"]); ok {
+			s.book.generation = int32(generation)
+		}
+	}
+	if repairCost, ok := nbtutil.Int(tag["RepairCost"]); ok {
+		s.repairCost = int32(repairCost)
+	}
+	if unbreakable, ok := nbtutil.Int(tag["Unbreakable"]); ok {
+		s.unbreakable = unbreakable != 0
+	}
+}
+
+// parseComponents parses the data components of a Java item stack from 1.20.5 and up. Text components are
+// decoded from NBT for data versions of TextNBTDataVersion and up.
+func (s *stack) parseComponents(components map[string]any, dataVersion int32) error {
+	if damage, ok := nbtutil.Int(components["minecraft:damage"]); ok {
+		s.damage = int32(damage)
+	}
+	var err error
+	if s.name, err = jsonText(components["minecraft:custom_name"], dataVersion); err != nil {
+		return fmt.Errorf("custom name: %w", err)
+	}
+	if s.lore, err = jsonTextList(components["minecraft:lore"], dataVersion); err != nil {
+		return fmt.Errorf("lore: %w", err)
+	}
+	s.enchantments = enchantmentLevels(components["minecraft:enchantments"])
+	s.storedEnchantments = enchantmentLevels(components["minecraft:stored_enchantments"])
+	switch potion := components["minecraft:potion_contents"].(type) {
+	case string:
+		s.potion = potion
+	case map[string]any:
+		s.potion, _ = potion["potion"].(string)
+	}
+	switch color := components["minecraft:dyed_color"].(type) {
+	case map[string]any:
+		if rgb, ok := nbtutil.Int(color["rgb"]); ok {
+			s.color, s.hasColor = int32(rgb), true
+		}
+	default:
+		if rgb, ok := nbtutil.Int(color); ok {
+			s.color, s.hasColor = int32(rgb), true
+		}
+	}
+	if content, ok := components["minecraft:written_book_content"].(map[string]any); ok {
+		pages, err := jsonTextList(filterableRaw(content["pages"]), dataVersion)
+		if err != nil {
+			return fmt.Errorf("pages: %w", err)
+		}
+		s.book = &book{pages: pages}
+		if title := filterable([]any{content["title"]}); len(title) > 0 {
+			s.book.title = title[0]
+		}
+		s.book.author, _ = content["author"].(string)
+		if generation, ok := nbtutil.Int(content["generation"]); ok {
+			s.book.generation = int32(generation)
+		}
+	} else if content, ok := components["minecraft:writable_book_content"].(map[string]any); ok {
+		s.book = &book{pages: filterable(content["pages"])}
+	}
+	if repairCost, ok := nbtutil.Int(components["minecraft:repair_cost"]); ok {
+		s.repairCost = int32(repairCost)
+	}
+	_, s.unbreakable = components["minecraft:unbreakable"]
+	return nil
+}
+
+// parseBedrock parses a Bedrock item stack.
+func parseBedrock(item map[string]any) (stack, error) {
+	name, ok := item["Name"].(string)
+	if !ok {
+		return stack{}, fmt.Errorf("item has no name")
+	}
+	data, _ := nbtutil.Int(item["Damage"])
+	count, _ := nbtutil.Int(item["Count"])
+	s := stack{id: ConvertToJava(name, int16(data)), count: int(count)}
+	if slot, ok := nbtutil.Int(item["Slot"]); ok {
+		s.slot, s.hasSlot = uint8(slot), true
+	}
+	switch name {
+	case "minecraft:potion", "minecraft:splash_potion", "minecraft:lingering_potion":
+		s.potion, _ = PotionToJava(int16(data))
+	case "minecraft:arrow":
+		if data > 0 {
+			s.id = "minecraft:tipped_arrow"
+			s.potion, _ = PotionToJava(int16(data - 1))
+		}
+	}
+
+	tag, _ := item["tag"].(map[string]any)
+	if damage, ok := nbtutil.Int(tag["Damage"]); ok {
+		s.damage = int32(damage)
+	}
+	if display, ok := tag["display"].(map[string]any); ok {
+		if name, ok := display["Name"].(string); ok {
+			s.name = text.ToJava(name)
+		}
+		for _, line := range stringList(display["Lore"]) {
+			s.lore = append(s.lore, text.ToJava(line))
+		}
+	}
+	if ench, ok := tag["ench"].([]any); ok {
+		enchantments := make(map[string]int16, len(ench))
+		for _, v := range ench {
+			e, _ := v.(map[string]any)
+			id, _ := nbtutil.Int(e["id"])
+			lvl, _ := nbtutil.Int(e["lvl"])
+			if javaID, ok := EnchantmentToJava(int16(id)); ok {
+				enchantments[javaID] = int16(lvl)
+			}
+		}
+		if s.id == "minecraft:enchanted_book" {
+			s.storedEnchantments = enchantments
+		} else {
+			s.enchantments = enchantments
+		}
+	}
+	if color, ok := nbtutil.Int(tag["customColor"]); ok {
+		s.color, s.hasColor = int32(color)&0xffffff, true
+	}
+	if pages, ok := tag["pages"].([]any); ok {
+		s.book = &book{}
+		for _, v := range pages {
+			page, _ := v.(map[string]any)
+			content, _ := page["text"].(string)
+			if s.id == "minecraft:written_book" {
+				content = text.ToJava(content)
+			}
+			s.book.pages = append(s.book.pages, content)
+		}
+		s.book.title, _ = tag["title"].(string)
+		s.book.author, _ = tag["author"].(string)
+		if generation, ok := nbtutil.Int(tag["generation"]); ok {
+			s.book.generation = int32(generation)
+		}
+	}
+	if repairCost, ok := nbtutil.Int(tag["RepairCost"]); ok {
+		s.repairCost = int32(repairCost)
+	}
+	if unbreakable, ok := nbtutil.Int(tag["Unbreakable"]); ok {
+		s.unbreakable = unbreakable != 0
+	}
+	return s, nil
+}
+
+// bedrock encodes the stack as a Bedrock item stack, converting text using the options passed.
+func (s stack) bedrock(opts text.Options) map[string]any {
+	name, data := ConvertToBedrock(s.id)
+	switch s.id {
+	case "minecraft:potion", "minecraft:splash_potion", "minecraft:lingering_potion":
+		data, _ = PotionToBedrock(s.potion)
+	case "minecraft:tipped_arrow":
+		name = "minecraft:arrow"
+		if potion, ok := PotionToBedrock(s.potion); ok {
+			data = potion + 1
+		}
+	}
+	item := map[string]any{
+		"Name":        name,
+		"Count":       uint8(s.count),
+		"Damage":      data,
+		"WasPickedUp": uint8(0),
+	}
+	if s.hasSlot {
+		item["Slot"] = s.slot
+	}
+
+	tag := make(map[string]any)
+	if s.damage != 0 {
+		tag["Damage"] = s.damage
+	}
+	display := make(map[string]any)
+	if s.name != "" {
+		display["Name"] = text.ToBedrock(s.name, opts)
+	}
+	if len(s.lore) > 0 {
+		lore := make([]any, 0, len(s.lore))
+		for _, line := range s.lore {
+			lore = append(lore, text.ToBedrock(line, opts))
+		}
+		display["Lore"] = lore
+	}
+	if len(display) > 0 {
+		tag["display"] = display
+	}
+	// Bedrock stores the enchantments of enchanted books like those of any other item.
+	enchantments := s.enchantments
+	if len(s.storedEnchantments) > 0 {
+		enchantments = s.storedEnchantments
+	}
+	if len(enchantments) > 0 {
+		ench := make([]any, 0, len(enchantments))
+		for _, id := range sortedKeys(enchantments) {
+			if bedrockID, ok := EnchantmentToBedrock(id); ok {
+				ench = append(ench, map[string]any{"id": bedrockID, "lvl": enchantments[id]})
+			}
+		}
+		tag["ench"] = ench
+	}
+	if s.hasColor {
+		tag["customColor"] = int32(uint32(s.color) | 0xff000000)
+	}
+	if s.book != nil {
+		pages := make([]any, 0, len(s.book.pages))
+		for _, page := range s.book.pages {
+			if s.id == "minecraft:written_book" {
+				page = text.ToBedrock(page, opts)
+			}
+			pages = append(pages, map[string]any{"text": page, "photoname": ""})
+		}
+		tag["pages"] = pages
+		if s.id == "minecraft:written_book" {
+			tag["title"], tag["author"], tag["generation"] = s.book.title, s.book.author, s.book.generation
+		}
+	}
+	if s.repairCost != 0 {
+		tag["RepairCost"] = s.repairCost
+	}
+	if s.unbreakable {
+		tag["Unbreakable"] = uint8(1)
+	}
+	if len(tag) > 0 {
+		item["tag"] = tag
+	}
+	return item
+}
+
+// javaTag encodes the stack as a Java item stack with a "tag" compound, as used before 1.20.5.
+func (s stack) javaTag() map[string]any {
+	item := map[string]any{"id": s.id, "Count": uint8(s.count)}
+	if s.hasSlot {
+		item["Slot"] = s.slot
+	}
+
+	tag := make(map[string]any)
+	if s.damage != 0 {
+		tag["Damage"] = s.damage
+	}
+	display := make(map[string]any)
+	if s.name != "" {
+		display["Name"] = s.name
+	}
+	if len(s.lore) > 0 {
+		display["Lore"] = anySlice(s.lore)
+	}
+	if s.hasColor {
+		display["color"] = s.color
+	}
+	if len(display) > 0 {
+		tag["display"] = display
+	}
+	if len(s.enchantments) > 0 {
+		tag["Enchantments"] = enchantmentListTag(s.enchantments)
+	}
+	if len(s.storedEnchantments) > 0 {
+		tag["StoredEnchantments"] = enchantmentListTag(s.storedEnchantments)
+	}
+	if s.potion != "" {
+		tag["Potion"] = s.potion
+	}
+	if s.book != nil {
+		tag["pages"] = anySlice(s.book.pages)
+		if s.id == "minecraft:written_book" {
+			tag["title"], tag["author"], tag["generation"] = s.book.title, s.book.author, s.book.generation
+		}
+	}
+	if s.repairCost != 0 {
+		tag["RepairCost"] = s.repairCost
+	}
+	if s.unbreakable {
+		tag["Unbreakable"] = uint8(1)
+	}
+	if len(tag) > 0 {
+		item["tag"] = tag
+	}
+	return item
+}
+
+// javaComponents encodes the stack as a Java item stack with data components, as used since 1.20.5.
+func (s stack) javaComponents() map[string]any {
+	item := map[string]any{"id": s.id, "count": int32(s.count)}
+	if s.hasSlot {
+		item["Slot"] = s.slot
+	}
+
+	components := make(map[string]any)
+	if s.damage != 0 {
+		components["minecraft:damage"] = s.damage
+	}
+	if s.name != "" {
+		components["minecraft:custom_name"] = s.name
+	}
+	if len(s.lore) > 0 {
+		components["minecraft:lore"] = anySlice(s.lore)
+	}
+	if len(s.enchantments) > 0 {
+		components["minecraft:enchantments"] = enchantmentLevelsTag(s.enchantments)
+	}
+	if len(s.storedEnchantments) > 0 {
+		components["minecraft:stored_enchantments"] = enchantmentLevelsTag(s.storedEnchantments)
+	}
+	if s.potion != "" {
+		components["minecraft:potion_contents"] = map[string]any{"potion": s.potion}
+	}
+	if s.hasColor {
+		components["minecraft:dyed_color"] = map[string]any{"rgb": s.color}
+	}
+	if s.book != nil {
+		pages := make([]any, 0, len(s.book.pages))
+		for _, page := range s.book.pages {
+			pages = append(pages, map[string]any{"raw": page})
+		}
+		if s.id == "minecraft:written_book" {
+			components["minecraft:written_book_content"] = map[string]any{
+				"title":      map[string]any{"raw": s.book.title},
+				"author":     s.book.author,
+				"generation": s.book.generation,
+				"pages":      pages,
+			}
+		} else {
+			components["minecraft:writable_book_content"] = map[string]any{"pages": pages}
+		}
+	}
+	if s.repairCost != 0 {
+		components["minecraft:repair_cost"] = s.repairCost
+	}
+	if s.unbreakable {
+		components["minecraft:unbreakable"] = map[string]any{}
+	}
+	if len(components) > 0 {
+		item["components"] = components
+	}
+	return item
+}
+
+// enchantmentList parses a Java list of enchantment compounds, as used before 1.20.5.
+func enchantmentList(v any) map[string]int16 {
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	enchantments := make(map[string]int16, len(list))
+	for _, e := range list {
+		m, _ := e.(map[string]any)
+		id, _ := m["id"].(string)
+		lvl, _ := nbtutil.Int(m["lvl"])
+		enchantments[id] = int16(lvl)
+	}
+	return enchantments
+}
+
+// enchantmentLevels parses Java enchantment levels, as used since 1.20.5. The levels are either held directly or
+// in a "levels" compound.
+func enchantmentLevels(v any) map[string]int16 {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if levels, ok := m["levels"].(map[string]any); ok {
+		m = levels
+	}
+	enchantments := make(map[string]int16, len(m))
+	for id, lvl := range m {
+		if lvl, ok := nbtutil.Int(lvl); ok {
+			enchantments[id] = int16(lvl)
+		}
+	}
+	return enchantments
+}
+
+// enchantmentListTag encodes enchantments as a Java list of enchantment compounds.
+func enchantmentListTag(enchantments map[string]int16) []any {
+	list := make([]any, 0, len(enchantments))
+	for _, id := range sortedKeys(enchantments) {
+		list = append(list, map[string]any{"id": id, "lvl": enchantments[id]})
+	}
+	return list
+}
+
+// enchantmentLevelsTag encodes enchantments as a Java enchantments component.
+func enchantmentLevelsTag(enchantments map[string]int16) map[string]any {
+	levels := make(map[string]any, len(enchantments))
+	for id, lvl := range enchantments {
+		levels[id] = int32(lvl)
+	}
+	return map[string]any{"levels": levels}
+}
+
+// jsonText returns a Java text component stored in an item as a JSON text component. Since TextNBTDataVersion,
+// text components are stored as NBT, which is decoded and encoded as JSON. Before, they are JSON strings.
+func jsonText(v any, dataVersion int32) (string, error) {
+	switch {
+	case v == nil:
+		return "", nil
+	case dataVersion < TextNBTDataVersion:
+		s, _ := v.(string)
+		return s, nil
+	}
+	c, err := text.FromNBT(v)
+	if err != nil {
+		return "", err
+	}
+	return c.JSON(), nil
+}
+
+// jsonTextList returns a list of Java text components stored in an item as JSON text components, in the same way
+// as jsonText.
+func jsonTextList(v any, dataVersion int32) ([]string, error) {
+	if dataVersion < TextNBTDataVersion {
+		return stringList(v), nil
+	}
+	list, _ := v.([]any)
+	values := make([]string, 0, len(list))
+	for _, e := range list {
+		s, err := jsonText(e, dataVersion)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// filterableRaw returns the raw values of a list of filterable values, which are either the values themselves or
+// compounds holding the value in "raw".
+func filterableRaw(v any) any {
+	list, ok := v.([]any)
+	if !ok {
+		return v
+	}
+	values := make([]any, 0, len(list))
+	for _, e := range list {
+		if m, ok := e.(map[string]any); ok {
+			if raw, ok := m["raw"]; ok {
+				e = raw
+			}
+		}
+		values = append(values, e)
+	}
+	return values
+}
+
+// filterable returns the raw values of a list of filterable strings, which are either plain strings or compounds
+// holding a "raw" string.
+func filterable(v any) []string {
+	list, _ := v.([]any)
+	values := make([]string, 0, len(list))
+	for _, e := range list {
+		switch e := e.(type) {
+		case string:
+			values = append(values, e)
+		case map[string]any:
+			raw, _ := e["raw"].(string)
+			values = append(values, raw)
+		}
+	}
+	return values
+}
+
+// stringList returns all strings in a list tag.
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	values := make([]string, 0, len(list))
+	for _, e := range list {
+		if s, ok := e.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// anySlice converts a slice of strings to a list tag.
+func anySlice(values []string) []any {
+	list := make([]any, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
+
+// sortedKeys returns the keys of the enchantments passed in sorted order.
+func sortedKeys(enchantments map[string]int16) []string {
+	keys := make([]string, 0, len(enchantments))
+	for k := range enchantments {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,356 @@
+package items
+
+import (
+	"github.com/justtaldevelops/mcanvil/text"
+	"reflect"
+	"testing"
+)
+
+func TestToBedrock(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name string
+		item map[string]any
+		// dataVersion is the Java data version of the item. It only matters for text components, which are
+		// stored as JSON in older versions.
+		dataVersion int32
+		expected    map[string]any
+	}{
+		{
+			name: "tag",
+			item: map[string]any{"id": "minecraft:diamond_sword", "Count": uint8(1), "Slot": uint8(3), "tag": map[string]any{
+				"Damage":  int32(12),
+				"display": map[string]any{"Name": `{"text":"Blade","color":"red"}`, "Lore": []any{`"Sharp"`}},
+				"Enchantments": []any{
+					map[string]any{"id": "minecraft:unbreaking", "lvl": int16(3)},
+					map[string]any{"id": "minecraft:sharpness", "lvl": int16(5)},
+					map[string]any{"id": "minecraft:unknown", "lvl": int16(1)},
+				},
+				"RepairCost":  int32(3),
+				"Unbreakable": uint8(1),
+			}},
+			expected: bedrockStack("minecraft:diamond_sword", 1, 0, map[string]any{
+				"Damage":      int32(12),
+				"display":     map[string]any{"Name": "§cBlade", "Lore": []any{"Sharp"}},
+				"ench":        []any{enchantment(9, 5), enchantment(17, 3)},
+				"RepairCost":  int32(3),
+				"Unbreakable": uint8(1),
+			}, uint8(3)),
+		},
+		{
+			name: "components",
+			item: map[string]any{"id": "minecraft:diamond_sword", "count": int32(1), "components": map[string]any{
+				"minecraft:damage":       int32(12),
+				"minecraft:custom_name":  `"Blade"`,
+				"minecraft:lore":         []any{`{"text":"Sharp","italic":true}`},
+				"minecraft:enchantments": map[string]any{"levels": map[string]any{"minecraft:sharpness": int32(5)}},
+				"minecraft:repair_cost":  int32(3),
+				"minecraft:unbreakable":  map[string]any{},
+			}},
+			expected: bedrockStack("minecraft:diamond_sword", 1, 0, map[string]any{
+				"Damage":      int32(12),
+				"display":     map[string]any{"Name": "Blade", "Lore": []any{"§oSharp"}},
+				"ench":        []any{enchantment(9, 5)},
+				"RepairCost":  int32(3),
+				"Unbreakable": uint8(1),
+			}, nil),
+		},
+		{
+			name: "components with nbt text", dataVersion: TextNBTDataVersion,
+			item: map[string]any{"id": "minecraft:diamond_sword", "count": int32(1), "components": map[string]any{
+				"minecraft:custom_name": map[string]any{"text": "Blade", "color": "red"},
+				"minecraft:lore":        []any{"Sharp", map[string]any{"text": "Edge", "italic": uint8(1)}},
+			}},
+			expected: bedrockStack("minecraft:diamond_sword", 1, 0, map[string]any{
+				"display": map[string]any{"Name": "§cBlade", "Lore": []any{"Sharp", "§oEdge"}},
+			}, nil),
+		},
+		{
+			// Strings are literal text when stored as NBT, rather than JSON.
+			name: "components with nbt string text", dataVersion: TextNBTDataVersion,
+			item: map[string]any{"id": "minecraft:stick", "count": int32(1), "components": map[string]any{
+				"minecraft:custom_name": `"Wand"`,
+			}},
+			expected: bedrockStack("minecraft:stick", 1, 0, map[string]any{"display": map[string]any{"Name": `"Wand"`}}, nil),
+		},
+		{
+			name: "enchantments component without levels",
+			item: map[string]any{"id": "minecraft:elytra", "count": int32(1), "components": map[string]any{
+				"minecraft:enchantments": map[string]any{"minecraft:mending": int32(1)},
+			}},
+			expected: bedrockStack("minecraft:elytra", 1, 0, map[string]any{"ench": []any{enchantment(26, 1)}}, nil),
+		},
+		{
+			name: "enchanted book tag",
+			item: map[string]any{"id": "minecraft:enchanted_book", "Count": uint8(1), "tag": map[string]any{
+				"StoredEnchantments": []any{map[string]any{"id": "minecraft:mending", "lvl": int16(1)}},
+			}},
+			expected: bedrockStack("minecraft:enchanted_book", 1, 0, map[string]any{"ench": []any{enchantment(26, 1)}}, nil),
+		},
+		{
+			name: "enchanted book components",
+			item: map[string]any{"id": "minecraft:enchanted_book", "count": int32(1), "components": map[string]any{
+				"minecraft:stored_enchantments": map[string]any{"levels": map[string]any{"minecraft:unbreaking": int32(2)}},
+			}},
+			expected: bedrockStack("minecraft:enchanted_book", 1, 0, map[string]any{"ench": []any{enchantment(17, 2)}}, nil),
+		},
+		{
+			name:     "potion tag",
+			item:     map[string]any{"id": "minecraft:potion", "Count": uint8(1), "tag": map[string]any{"Potion": "minecraft:long_swiftness"}},
+			expected: bedrockStack("minecraft:potion", 1, 15, nil, nil),
+		},
+		{
+			name: "splash potion components",
+			item: map[string]any{"id": "minecraft:splash_potion", "count": int32(1), "components": map[string]any{
+				"minecraft:potion_contents": map[string]any{"potion": "minecraft:healing", "custom_color": int32(0xff0000)},
+			}},
+			expected: bedrockStack("minecraft:splash_potion", 1, 21, nil, nil),
+		},
+		{
+			name: "potion component holding only the potion",
+			item: map[string]any{"id": "minecraft:lingering_potion", "count": int32(2), "components": map[string]any{
+				"minecraft:potion_contents": "minecraft:swiftness",
+			}},
+			expected: bedrockStack("minecraft:lingering_potion", 2, 14, nil, nil),
+		},
+		{
+			name:     "tipped arrow tag",
+			item:     map[string]any{"id": "minecraft:tipped_arrow", "Count": uint8(64), "tag": map[string]any{"Potion": "minecraft:swiftness"}},
+			expected: bedrockStack("minecraft:arrow", 64, 15, nil, nil),
+		},
+		{
+			name: "tipped arrow components",
+			item: map[string]any{"id": "minecraft:tipped_arrow", "count": int32(8), "components": map[string]any{
+				"minecraft:potion_contents": map[string]any{"potion": "minecraft:healing"},
+			}},
+			expected: bedrockStack("minecraft:arrow", 8, 22, nil, nil),
+		},
+		{
+			name: "dyed tag",
+			item: map[string]any{"id": "minecraft:leather_chestplate", "Count": uint8(1), "tag": map[string]any{
+				"display": map[string]any{"color": int32(0x3c44aa)},
+			}},
+			expected: bedrockStack("minecraft:leather_chestplate", 1, 0, map[string]any{"customColor": int32(-0xc3bb56)}, nil),
+		},
+		{
+			name: "dyed components",
+			item: map[string]any{"id": "minecraft:leather_helmet", "count": int32(1), "components": map[string]any{
+				"minecraft:dyed_color": map[string]any{"rgb": int32(0x3c44aa), "show_in_tooltip": uint8(0)},
+			}},
+			expected: bedrockStack("minecraft:leather_helmet", 1, 0, map[string]any{"customColor": int32(-0xc3bb56)}, nil),
+		},
+		{
+			name: "dyed component holding only the colour",
+			item: map[string]any{"id": "minecraft:leather_boots", "count": int32(1), "components": map[string]any{
+				"minecraft:dyed_color": int32(0xf0f0f0),
+			}},
+			expected: bedrockStack("minecraft:leather_boots", 1, 0, map[string]any{"customColor": int32(-0xf0f10)}, nil),
+		},
+		{
+			name: "written book components",
+			item: map[string]any{"id": "minecraft:written_book", "count": int32(1), "components": map[string]any{
+				"minecraft:written_book_content": map[string]any{
+					"title":      map[string]any{"raw": "Title"},
+					"author":     "Author",
+					"generation": int32(1),
+					"pages":      []any{map[string]any{"raw": `"One"`}, `{"text":"Two","bold":true}`},
+				},
+			}},
+			expected: bedrockStack("minecraft:written_book", 1, 0, map[string]any{
+				"pages":      []any{page("One"), page("§lTwo")},
+				"title":      "Title",
+				"author":     "Author",
+				"generation": int32(1),
+			}, nil),
+		},
+		{
+			name: "written book components with nbt text", dataVersion: TextNBTDataVersion,
+			item: map[string]any{"id": "minecraft:written_book", "count": int32(1), "components": map[string]any{
+				"minecraft:written_book_content": map[string]any{
+					"title":  map[string]any{"raw": "Title"},
+					"author": "Author",
+					"pages":  []any{map[string]any{"raw": map[string]any{"text": "One", "bold": uint8(1)}}, "Two"},
+				},
+			}},
+			expected: bedrockStack("minecraft:written_book", 1, 0, map[string]any{
+				"pages":      []any{page("§lOne"), page("Two")},
+				"title":      "Title",
+				"author":     "Author",
+				"generation": int32(0),
+			}, nil),
+		},
+		{
+			name: "writable book tag",
+			item: map[string]any{"id": "minecraft:writable_book", "Count": uint8(1), "tag": map[string]any{
+				"pages": []any{"Plain {text}"},
+			}},
+			expected: bedrockStack("minecraft:writable_book", 1, 0, map[string]any{"pages": []any{page("Plain {text}")}}, nil),
+		},
+		{
+			name:     "renamed item",
+			item:     map[string]any{"id": "minecraft:white_wool", "Count": uint8(3)},
+			expected: bedrockStack("minecraft:wool", 3, 0, nil, nil),
+		},
+	} {
+		item, err := ToBedrock(test.item, test.dataVersion, text.Options{})
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(item, test.expected) {
+			t.Errorf("%v:\nexpected %#v\ngot      %#v", test.name, test.expected, item)
+		}
+	}
+	if _, err := ToBedrock(map[string]any{"Count": uint8(1)}, ComponentsDataVersion, text.Options{}); err == nil {
+		t.Error("expected an item without an id to fail")
+	}
+	invalid := map[string]any{"id": "minecraft:stick", "components": map[string]any{"minecraft:custom_name": []any{}}}
+	if _, err := ToBedrock(invalid, TextNBTDataVersion, text.Options{}); err == nil {
+		t.Error("expected an item with an invalid custom name to fail")
+	}
+}
+
+func TestToJava(t *testing.T) {
+	t.Parallel()
+	sword := bedrockStack("minecraft:diamond_sword", 1, 0, map[string]any{
+		"Damage":      int32(12),
+		"display":     map[string]any{"Name": "§cBlade", "Lore": []any{"Sharp"}},
+		"ench":        []any{enchantment(9, 5), enchantment(17, 3), enchantment(1000, 1)},
+		"RepairCost":  int32(3),
+		"Unbreakable": uint8(1),
+	}, uint8(3))
+	for _, test := range []struct {
+		name        string
+		item        map[string]any
+		dataVersion int32
+		expected    map[string]any
+	}{
+		{
+			name: "tag", item: sword, dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:diamond_sword", "Count": uint8(1), "Slot": uint8(3), "tag": map[string]any{
+				"Damage":  int32(12),
+				"display": map[string]any{"Name": `{"text":"Blade","color":"red"}`, "Lore": []any{`{"text":"Sharp"}`}},
+				"Enchantments": []any{
+					map[string]any{"id": "minecraft:sharpness", "lvl": int16(5)},
+					map[string]any{"id": "minecraft:unbreaking", "lvl": int16(3)},
+				},
+				"RepairCost":  int32(3),
+				"Unbreakable": uint8(1),
+			}},
+		},
+		{
+			name: "components", item: sword, dataVersion: ComponentsDataVersion,
+			expected: map[string]any{"id": "minecraft:diamond_sword", "count": int32(1), "Slot": uint8(3), "components": map[string]any{
+				"minecraft:damage":      int32(12),
+				"minecraft:custom_name": `{"text":"Blade","color":"red"}`,
+				"minecraft:lore":        []any{`{"text":"Sharp"}`},
+				"minecraft:enchantments": map[string]any{"levels": map[string]any{
+					"minecraft:sharpness": int32(5), "minecraft:unbreaking": int32(3),
+				}},
+				"minecraft:repair_cost": int32(3),
+				"minecraft:unbreakable": map[string]any{},
+			}},
+		},
+		{
+			name:        "enchanted book",
+			item:        bedrockStack("minecraft:enchanted_book", 1, 0, map[string]any{"ench": []any{enchantment(26, 1)}}, nil),
+			dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:enchanted_book", "Count": uint8(1), "tag": map[string]any{
+				"StoredEnchantments": []any{map[string]any{"id": "minecraft:mending", "lvl": int16(1)}},
+			}},
+		},
+		{
+			name: "potion tag", item: bedrockStack("minecraft:potion", 1, 15, nil, nil), dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:potion", "Count": uint8(1), "tag": map[string]any{"Potion": "minecraft:long_swiftness"}},
+		},
+		{
+			name: "potion components", item: bedrockStack("minecraft:splash_potion", 1, 21, nil, nil), dataVersion: ComponentsDataVersion,
+			expected: map[string]any{"id": "minecraft:splash_potion", "count": int32(1), "components": map[string]any{
+				"minecraft:potion_contents": map[string]any{"potion": "minecraft:healing"},
+			}},
+		},
+		{
+			name: "tipped arrow", item: bedrockStack("minecraft:arrow", 16, 15, nil, nil), dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:tipped_arrow", "Count": uint8(16), "tag": map[string]any{"Potion": "minecraft:swiftness"}},
+		},
+		{
+			name: "tipped arrow components", item: bedrockStack("minecraft:arrow", 16, 22, nil, nil), dataVersion: ComponentsDataVersion,
+			expected: map[string]any{"id": "minecraft:tipped_arrow", "count": int32(16), "components": map[string]any{
+				"minecraft:potion_contents": map[string]any{"potion": "minecraft:healing"},
+			}},
+		},
+		{
+			name: "arrow", item: bedrockStack("minecraft:arrow", 16, 0, nil, nil), dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:arrow", "Count": uint8(16)},
+		},
+		{
+			name:        "dyed tag",
+			item:        bedrockStack("minecraft:leather_chestplate", 1, 0, map[string]any{"customColor": int32(-0xc3bb56)}, nil),
+			dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:leather_chestplate", "Count": uint8(1), "tag": map[string]any{
+				"display": map[string]any{"color": int32(0x3c44aa)},
+			}},
+		},
+		{
+			name:        "dyed components",
+			item:        bedrockStack("minecraft:leather_chestplate", 1, 0, map[string]any{"customColor": int32(-0xc3bb56)}, nil),
+			dataVersion: ComponentsDataVersion,
+			expected: map[string]any{"id": "minecraft:leather_chestplate", "count": int32(1), "components": map[string]any{
+				"minecraft:dyed_color": map[string]any{"rgb": int32(0x3c44aa)},
+			}},
+		},
+		{
+			name: "written book components",
+			item: bedrockStack("minecraft:written_book", 1, 0, map[string]any{
+				"pages": []any{page("§lOne")}, "title": "Title", "author": "Author", "generation": int32(2),
+			}, nil),
+			dataVersion: ComponentsDataVersion,
+			expected: map[string]any{"id": "minecraft:written_book", "count": int32(1), "components": map[string]any{
+				"minecraft:written_book_content": map[string]any{
+					"title":      map[string]any{"raw": "Title"},
+					"author":     "Author",
+					"generation": int32(2),
+					"pages":      []any{map[string]any{"raw": `{"text":"One","bold":true}`}},
+				},
+			}},
+		},
+		{
+			name: "renamed item", item: bedrockStack("minecraft:wool", 2, 0, nil, nil), dataVersion: 3465,
+			expected: map[string]any{"id": "minecraft:white_wool", "Count": uint8(2)},
+		},
+	} {
+		item, err := ToJava(test.item, test.dataVersion)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(item, test.expected) {
+			t.Errorf("%v:\nexpected %#v\ngot      %#v", test.name, test.expected, item)
+		}
+	}
+	if _, err := ToJava(map[string]any{"Count": uint8(1)}, 3465); err == nil {
+		t.Error("expected an item without a name to fail")
+	}
+}
+
+// bedrockStack returns a Bedrock item stack holding the tag passed, if not nil, in the slot passed, if not nil.
+func bedrockStack(name string, count uint8, data int16, tag map[string]any, slot any) map[string]any {
+	item := map[string]any{"Name": name, "Count": count, "Damage": data, "WasPickedUp": uint8(0)}
+	if tag != nil {
+		item["tag"] = tag
+	}
+	if slot != nil {
+		item["Slot"] = slot
+	}
+	return item
+}
+
+// enchantment returns a Bedrock enchantment compound.
+func enchantment(id, lvl int16) map[string]any {
+	return map[string]any{"id": id, "lvl": lvl}
+}
+
+// page returns a Bedrock book page compound.
+func page(text string) map[string]any {
+	return map[string]any{"text": text, "photoname": ""}
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
 	"github.com/df-mc/dragonfly/server/world/mcdb"
+	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/upgrade"
 	"github.com/klauspost/compress/gzip"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
 	"io/ioutil"
@@ -13,10 +16,16 @@ import (
 	"sync"
 )
 
-// Level represents a Minecraft level for the Anvil format.
+// Level represents a Minecraft level for the Anvil format. It is safe for concurrent use: chunks read through the
+// level are only accessed while holding its chunk mutex, as Chunk itself is not safe for concurrent use.
 type Level struct {
 	dat     map[string]any
+	datPath string
 	regions []*Region
+
+	chunkMu sync.Mutex
+	// chunks is a cache of chunks read through the level. Chunks that do not exist are cached as nil.
+	chunks map[world.ChunkPos]*Chunk
 }
 
 // LoadLevel loads a level from the given path.
@@ -44,7 +53,7 @@ func LoadLevel(folderPath string) (*Level, error) {
 	}
 	_, _ = z.Close(), r.Close()
 
-	level := &Level{dat: data["Data"]}
+	level := &Level{dat: data["Data"], datPath: datPath, chunks: make(map[world.ChunkPos]*Chunk)}
 	regionFiles, err := ioutil.ReadDir(regionsPath)
 	if err != nil {
 		return nil, err
@@ -62,31 +71,338 @@ func LoadLevel(folderPath string) (*Level, error) {
 	return level, nil
 }
 
-// WriteBedrock converts and writes an anvil level to a Bedrock world provider.
-func (l *Level) WriteBedrock(prov *mcdb.Provider) error {
+// WriteBedrock converts and writes an anvil level to a Bedrock world provider, using the Config passed. A Report
+// of the conversion is returned.
+func (l *Level) WriteBedrock(prov *mcdb.Provider, conf Config) (*Report, error) {
+	if r, ok := l.javaRange(); ok && conf.JavaRange == (cube.Range{}) {
+		conf.JavaRange = r
+	}
+	conv, err := newConverter(conf)
+	if err != nil {
+		return nil, err
+	}
+	return conv.report, l.writeBedrock(prov, conv)
+}
+
+// writeBedrock converts and writes an anvil level to a Bedrock world provider using the converter passed. The
+// regions of the level are converted concurrently.
+func (l *Level) writeBedrock(prov *mcdb.Provider, conv *converter) error {
 	settings := prov.Settings()
 	settings.Name = l.dat["LevelName"].(string)
 	settings.Time = l.dat["DayTime"].(int64)
 	settings.Spawn = cube.Pos{
 		int(l.dat["SpawnX"].(int32)),
-		int(l.dat["SpawnY"].(int32)),
+		int(l.dat["SpawnY"].(int32)) + conv.conf.YShift,
 		int(l.dat["SpawnZ"].(int32)),
 	}
 	prov.SaveSettings(settings)
 
-	var wg sync.WaitGroup
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
 	for _, region := range l.regions {
 		wg.Add(1)
 
 		region := region
 		go func() {
-			err := region.WriteBedrock(prov)
-			if err != nil {
-				panic(err)
+			defer wg.Done()
+			if err := region.writeBedrock(prov, conv); err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("region %v, %v: %w", region.x, region.z, err)
+				}
+				errMu.Unlock()
 			}
-			wg.Done()
 		}()
 	}
 	wg.Wait()
+	return firstErr
+}
+
+// javaRange returns the vertical range of the overworld of the level, as set by its dimension type in the
+// level.dat. False is returned if the level.dat does not hold the dimension type, as is the case before 1.16.
+func (l *Level) javaRange() (cube.Range, bool) {
+	worldGenSettings, _ := l.dat["WorldGenSettings"].(map[string]any)
+	dimensions, _ := worldGenSettings["dimensions"].(map[string]any)
+	overworld, _ := dimensions["minecraft:overworld"].(map[string]any)
+	switch dimensionType := overworld["type"].(type) {
+	case string:
+		version, _ := l.dat["DataVersion"].(int32)
+		if version >= 2844 && (dimensionType == "minecraft:overworld" || dimensionType == "minecraft:overworld_caves") {
+			return cube.Range{-64, 319}, true
+		}
+		return cube.Range{0, 255}, true
+	case map[string]any:
+		minY, ok := dimensionType["min_y"].(int32)
+		height, otherOk := dimensionType["height"].(int32)
+		if ok && otherOk {
+			return cube.Range{int(minY), int(minY+height) - 1}, true
+		}
+		return cube.Range{0, 255}, true
+	}
+	return cube.Range{}, false
+}
+
+// yRange returns the vertical range of the chunk passed that blocks and biomes may be read from and written to:
+// the range set in the level.dat, or the range of the chunk if the level.dat does not hold one.
+func (l *Level) yRange(c *Chunk) cube.Range {
+	if r, ok := l.javaRange(); ok {
+		return r
+	}
+	return c.Range()
+}
+
+// Block returns the Java block state at the world position passed. An error is returned if the chunk holding
+// the position does not exist, or a *PositionError if the position is outside of the vertical range of the world.
+func (l *Level) Block(x, y, z int) (state states.Block, err error) {
+	err = l.withChunk(x, z, func(c *Chunk) error {
+		if err := c.checkY(uint8(x), y, uint8(z), l.yRange(c)); err != nil {
+			return err
+		}
+		state, err = c.block(uint8(x), int16(y), uint8(z))
+		return err
+	})
+	return state, err
+}
+
+// Biome returns the Java biome name at the world position passed. An error is returned if the chunk holding
+// the position does not exist, or a *PositionError if the position is outside of the vertical range of the world.
+func (l *Level) Biome(x, y, z int) (name string, err error) {
+	err = l.withChunk(x, z, func(c *Chunk) error {
+		if err := c.checkY(uint8(x), y, uint8(z), l.yRange(c)); err != nil {
+			return err
+		}
+		name, err = c.biome(uint8(x), int16(y), uint8(z))
+		return err
+	})
+	return name, err
+}
+
+// SetBlock sets the Java block state at the world position passed. The change is kept in memory until Save is
+// called. A *PositionError is returned if the position is outside of the vertical range of the world.
+func (l *Level) SetBlock(x, y, z int, state states.Block) error {
+	return l.withChunk(x, z, func(c *Chunk) error {
+		if err := c.checkY(uint8(x), y, uint8(z), l.yRange(c)); err != nil {
+			return err
+		}
+		return c.setBlock(uint8(x), int16(y), uint8(z), state)
+	})
+}
+
+// SetBiome sets the Java biome at the world position passed. Biomes are stored in cells of 4x4x4 blocks, so
+// the whole cell holding the position is changed. The change is kept in memory until Save is called. A
+// *PositionError is returned if the position is outside of the vertical range of the world.
+func (l *Level) SetBiome(x, y, z int, name string) error {
+	return l.withChunk(x, z, func(c *Chunk) error {
+		if err := c.checkY(uint8(x), y, uint8(z), l.yRange(c)); err != nil {
+			return err
+		}
+		return c.setBiome(uint8(x), int16(y), uint8(z), name)
+	})
+}
+
+// Fill sets all blocks in the box spanned by the two corners passed, inclusive, to the Java block state given.
+// The box is checked against the chunks it covers first, so that no block is set if part of it lies outside of
+// the world.
+func (l *Level) Fill(min, max cube.Pos, state states.Block) error {
+	if err := l.checkBox(min, max); err != nil {
+		return err
+	}
+	return l.forEach(min, max, func(x, y, z int) error {
+		return l.SetBlock(x, y, z, state)
+	})
+}
+
+// Replace replaces all blocks matching the from state in the box spanned by the two corners passed, inclusive,
+// with the to state. The number of blocks replaced is returned. Like Fill, no block is replaced if part of the
+// box lies outside of the world.
+func (l *Level) Replace(min, max cube.Pos, from, to states.Block) (int, error) {
+	fromID, ok := states.JavaStateToID(from)
+	if !ok {
+		return 0, fmt.Errorf("could not find block id for state %v", from)
+	}
+	if err := l.checkBox(min, max); err != nil {
+		return 0, err
+	}
+	var n int
+	err := l.forEach(min, max, func(x, y, z int) error {
+		current, err := l.Block(x, y, z)
+		if err != nil {
+			return err
+		}
+		if id, _ := states.JavaStateToID(current); id != fromID {
+			return nil
+		}
+		n++
+		return l.SetBlock(x, y, z, to)
+	})
+	return n, err
+}
+
+// Save writes all chunks modified through the level back to their region files. Chunks that were not modified
+// are left untouched.
+func (l *Level) Save() error {
+	l.chunkMu.Lock()
+	defer l.chunkMu.Unlock()
+	for pos, c := range l.chunks {
+		if c == nil || !c.dirty {
+			continue
+		}
+		r, ok := l.region(int(pos.X()>>5), int(pos.Z()>>5))
+		if !ok {
+			return fmt.Errorf("no region for chunk %v", pos)
+		}
+		if err := r.writeChunk(c); err != nil {
+			return fmt.Errorf("could not save chunk %v: %w", pos, err)
+		}
+		c.dirty = false
+	}
+	return nil
+}
+
+// Upgrade upgrades all chunks of the level saved by older versions to the current format in place, and updates
+// the DataVersion of the level.dat to match. The number of chunks upgraded is returned, along with the positions
+// of chunks that were left as they are because the upgrade package does not support their format, such as chunks
+// from before 1.13.
+func (l *Level) Upgrade() (upgraded int, unsupported []world.ChunkPos, err error) {
+	for _, r := range l.regions {
+		n, skipped, err := r.Upgrade()
+		upgraded, unsupported = upgraded+n, append(unsupported, skipped...)
+		if err != nil {
+			return upgraded, unsupported, fmt.Errorf("region %v, %v: %w", r.x, r.z, err)
+		}
+	}
+	if version, _ := l.dat["DataVersion"].(int32); version < upgrade.DataVersion() {
+		l.dat["DataVersion"] = upgrade.DataVersion()
+		if err := l.saveDat(); err != nil {
+			return upgraded, unsupported, fmt.Errorf("could not save level.dat: %w", err)
+		}
+	}
+	return upgraded, unsupported, nil
+}
+
+// saveDat writes the level.dat of the level back to its file. The file is first written next to the level.dat,
+// and then moved over it, so that the level.dat is not lost if writing fails.
+func (l *Level) saveDat() error {
+	f, err := os.Create(l.datPath + "_new")
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(f)
+	err = nbt.NewEncoderWithEncoding(w, nbt.BigEndian).Encode(map[string]any{"Data": l.dat})
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(l.datPath+"_new", l.datPath)
+}
+
+// checkBox checks that every chunk covered by the box spanned by the two corners passed exists, and that the
+// box lies within the vertical range of each of them.
+func (l *Level) checkBox(a, b cube.Pos) error {
+	minY, maxY := minInt(a.Y(), b.Y()), maxInt(a.Y(), b.Y())
+	for x := minInt(a.X(), b.X()) >> 4; x <= maxInt(a.X(), b.X())>>4; x++ {
+		for z := minInt(a.Z(), b.Z()) >> 4; z <= maxInt(a.Z(), b.Z())>>4; z++ {
+			err := l.withChunk(x<<4, z<<4, func(c *Chunk) error {
+				if err := c.checkY(0, minY, 0, l.yRange(c)); err != nil {
+					return err
+				}
+				return c.checkY(0, maxY, 0, l.yRange(c))
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// forEach calls f for every position in the box spanned by the two corners passed, inclusive, stopping at the
+// first error returned.
+func (l *Level) forEach(a, b cube.Pos, f func(x, y, z int) error) error {
+	min := cube.Pos{minInt(a.X(), b.X()), minInt(a.Y(), b.Y()), minInt(a.Z(), b.Z())}
+	max := cube.Pos{maxInt(a.X(), b.X()), maxInt(a.Y(), b.Y()), maxInt(a.Z(), b.Z())}
+	for x := min.X(); x <= max.X(); x++ {
+		for z := min.Z(); z <= max.Z(); z++ {
+			for y := min.Y(); y <= max.Y(); y++ {
+				if err := f(x, y, z); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
+
+// withChunk calls f with the chunk holding the world position passed, while holding the chunk mutex. Chunks
+// decode their sections lazily, so even reads must not run concurrently.
+func (l *Level) withChunk(x, z int, f func(c *Chunk) error) error {
+	l.chunkMu.Lock()
+	defer l.chunkMu.Unlock()
+	c, err := l.chunk(x, z)
+	if err != nil {
+		return err
+	}
+	return f(c)
+}
+
+// chunk returns the chunk holding the world position passed, reading it from its region if it was not yet
+// cached. The chunk mutex must be held while calling chunk.
+func (l *Level) chunk(x, z int) (*Chunk, error) {
+	pos := world.ChunkPos{int32(x >> 4), int32(z >> 4)}
+	if c, ok := l.chunks[pos]; ok {
+		if c == nil {
+			return nil, fmt.Errorf("chunk %v does not exist", pos)
+		}
+		return c, nil
+	}
+
+	r, ok := l.region(x>>9, z>>9)
+	if !ok {
+		l.chunks[pos] = nil
+		return nil, fmt.Errorf("chunk %v does not exist", pos)
+	}
+	c, ok, err := r.Chunk(pos.X(), pos.Z())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		l.chunks[pos] = nil
+		return nil, fmt.Errorf("chunk %v does not exist", pos)
+	}
+	l.chunks[pos] = &c
+	return &c, nil
+}
+
+// region returns the region at the region coordinates passed, if the level has one.
+func (l *Level) region(x, z int) (*Region, bool) {
+	for _, r := range l.regions {
+		if r.x == x && r.z == z {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// minInt returns the smaller of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the larger of two integers.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,23 +1,716 @@
 package mcanvil
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	mcnbt "github.com/Tnze/go-mc/nbt"
+	"github.com/Tnze/go-mc/save/region"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
 	"github.com/df-mc/dragonfly/server/world/mcdb"
 	"github.com/df-mc/goleveldb/leveldb/opt"
+	"github.com/justtaldevelops/mcanvil/column"
+	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/upgrade"
+	"github.com/klauspost/compress/gzip"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"os"
+	"path"
+	"reflect"
+	"sync"
 	"testing"
 )
 
 func TestLevel(t *testing.T) {
-	level, err := LoadLevel("Survival")
+	level, err := LoadLevel(writeTestWorld(t))
 	if err != nil {
 		t.Fatal(err)
 	}
-	prov, err := mcdb.New("world/", opt.FlateCompression)
+	dir := t.TempDir()
+	prov, err := mcdb.New(dir, opt.FlateCompression)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = level.WriteBedrock(prov)
+	if _, err := level.WriteBedrock(prov, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := prov.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, path.Join("testdata", "level.golden"), dumpBedrock(t, dir))
+}
+
+func TestLevelBedrockLayers(t *testing.T) {
+	level, err := LoadLevel(writeTestWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov, err := mcdb.New(t.TempDir(), opt.FlateCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prov.Close()
+	if _, err := level.WriteBedrock(prov, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	c, ok, err := prov.LoadChunk(world.ChunkPos{}, world.Overworld)
+	if err != nil || !ok {
+		t.Fatalf("could not load chunk: %v (exists: %v)", err, ok)
+	}
+
+	// Converting both Bedrock layers back must give a Java state with the same blocks on both layers.
+	var waterlogged int
+	liquids, mapper := states.DefaultLiquids(), states.DefaultMapper()
+	for y := -64; y < 48; y++ {
+		for x := 0; x < 16; x++ {
+			for z := 0; z < 16; z++ {
+				layers := [2]states.Block{}
+				for layer := range layers {
+					name, properties, _ := chunk.RuntimeIDToState(c.Block(uint8(x), int16(y), uint8(z), uint8(layer)))
+					layers[layer] = states.Block{Name: name, Properties: properties}
+				}
+				if layers[0].Name == "minecraft:air" {
+					continue
+				}
+				java, ok := liquids.ConvertToJava(mapper, layers[0], layers[1])
+				if !ok {
+					t.Fatalf("%v, %v, %v: could not convert %v to java", x, y, z, layers[0])
+				}
+				state, err := level.Block(x, y, z)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := liquids.Liquid(java); ok != (layers[1].Name != "minecraft:air") {
+					t.Errorf("%v, %v, %v: %v converted to %v, which was %v in java", x, y, z, layers, java, state)
+				}
+				if java.Properties["waterlogged"] == "true" {
+					waterlogged++
+				}
+			}
+		}
+	}
+	if waterlogged == 0 {
+		t.Error("expected waterlogged states to be converted back")
+	}
+}
+
+func TestLevelOutOfRange(t *testing.T) {
+	level, err := LoadLevel(writeTestWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pos := range []cube.Pos{
+		{0, 320, 0}, {0, -65, 0}, {3, 4032, 5}, {3, -4096, 5}, {0, 1 << 20, 0}, {0, math.MinInt32, 0},
+		// The chunk at 1, 0 was saved by 1.15, so its world spans 0 to 255.
+		{20, -1, 7}, {20, 256, 7},
+	} {
+		var posErr *PositionError
+		if _, err := level.Block(pos.X(), pos.Y(), pos.Z()); !errors.As(err, &posErr) || posErr.Pos != pos {
+			t.Errorf("block at %v: expected position error, got %v", pos, err)
+		}
+		if _, err := level.Biome(pos.X(), pos.Y(), pos.Z()); !errors.As(err, &posErr) || posErr.Pos != pos {
+			t.Errorf("biome at %v: expected position error, got %v", pos, err)
+		}
+	}
+	// Positions within the world but above the sections of the chunk hold air in plains.
+	if block, err := level.Block(0, 319, 0); err != nil || block.Name != "minecraft:air" {
+		t.Errorf("expected air at the top of the world, got %v (%v)", block, err)
+	}
+	if biome, err := level.Biome(0, 319, 0); err != nil || biome != plainsBiome {
+		t.Errorf("expected plains at the top of the world, got %v (%v)", biome, err)
+	}
+
+	// Chunks check positions against their own range, which 4032 would otherwise wrap around onto.
+	c := &Chunk{DataVersion: 3105, YPos: -4, Sections: []SubChunk{{Y: byte(0xfc)}}}
+	var posErr *PositionError
+	if _, err := c.Block(0, 4032, 0); !errors.As(err, &posErr) {
+		t.Errorf("expected position error, got %v", err)
+	}
+	if _, err := c.Biome(0, -65, 0); !errors.As(err, &posErr) {
+		t.Errorf("expected position error, got %v", err)
+	}
+}
+
+func TestLevelSetOutOfRange(t *testing.T) {
+	level, err := LoadLevel(writeTestWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bottom, err := level.Block(0, -64, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirt := states.Block{Name: "minecraft:dirt"}
+	var posErr *PositionError
+	// 4032 would wrap around onto the section at -64 if it were narrowed without checking it first.
+	if err := level.SetBlock(0, 4032, 0, dirt); !errors.As(err, &posErr) {
+		t.Errorf("expected position error, got %v", err)
+	}
+	if err := level.SetBiome(0, 4032, 0, "minecraft:desert"); !errors.As(err, &posErr) {
+		t.Errorf("expected position error, got %v", err)
+	}
+	if err := level.Fill(cube.Pos{0, 310, 0}, cube.Pos{1, 4032, 1}, dirt); !errors.As(err, &posErr) {
+		t.Errorf("expected position error, got %v", err)
+	}
+	if n, err := level.Replace(cube.Pos{0, -64, 0}, cube.Pos{20, -80, 1}, bottom, dirt); !errors.As(err, &posErr) || n != 0 {
+		t.Errorf("expected position error and no blocks replaced, got %v (%v replaced)", err, n)
+	}
+	if block, err := level.Block(0, -64, 0); err != nil || !reflect.DeepEqual(block, bottom) {
+		t.Errorf("expected %v at the bottom of the world, got %v (%v)", bottom, block, err)
+	}
+	if biome, err := level.Biome(0, -64, 0); err != nil || biome == "minecraft:desert" {
+		t.Errorf("expected the biome at the bottom of the world to be left unchanged, got %v (%v)", biome, err)
+	}
+	// Nothing may be set inside the world when part of the box passed to Fill lies outside of it.
+	if block, err := level.Block(0, 310, 0); err != nil || block.Name != "minecraft:air" {
+		t.Errorf("expected air at 310, got %v (%v)", block, err)
+	}
+}
+
+func TestLevelJavaRange(t *testing.T) {
+	dir := writeWorld(t, map[[2]int32]map[string]any{{0, 0}: sampleChunk(t, rand.New(rand.NewSource(1)), 0, 0)})
+	for _, test := range []struct {
+		name string
+		// dataVersion is the DataVersion of the level.dat, and dimensionType the type of its overworld, which is
+		// left out of the level.dat if nil.
+		dataVersion   int32
+		dimensionType any
+		// expected is the Java range expected to be read from the level.dat, if any.
+		expected cube.Range
+		ok       bool
+		// clipped is the number of the sections from -4 to 19 of the chunk clipped when moving the world down
+		// by 64 blocks.
+		clipped int
+	}{
+		{name: "none", dataVersion: 3105, clipped: 4},
+		{name: "overworld", dataVersion: 3105, dimensionType: "minecraft:overworld", expected: cube.Range{-64, 319}, ok: true, clipped: 4},
+		{name: "overworld caves", dataVersion: 2975, dimensionType: "minecraft:overworld_caves", expected: cube.Range{-64, 319}, ok: true, clipped: 4},
+		{name: "overworld 1.17", dataVersion: 2730, dimensionType: "minecraft:overworld", expected: cube.Range{0, 255}, ok: true},
+		{name: "nether", dataVersion: 3105, dimensionType: "minecraft:the_nether", expected: cube.Range{0, 255}, ok: true},
+		{name: "custom", dataVersion: 3105, dimensionType: map[string]any{"min_y": int32(-128), "height": int32(512)}, expected: cube.Range{-128, 383}, ok: true, clipped: 4},
+		{name: "custom low", dataVersion: 3105, dimensionType: map[string]any{"min_y": int32(0), "height": int32(128)}, expected: cube.Range{0, 127}, ok: true},
+		{name: "custom 1.16", dataVersion: 2586, dimensionType: map[string]any{"logical_height": int32(256)}, expected: cube.Range{0, 255}, ok: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			level, err := LoadLevel(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			level.dat["DataVersion"] = test.dataVersion
+			if test.dimensionType != nil {
+				level.dat["WorldGenSettings"] = map[string]any{"dimensions": map[string]any{
+					"minecraft:overworld": map[string]any{"type": test.dimensionType},
+				}}
+			}
+			if r, ok := level.javaRange(); r != test.expected || ok != test.ok {
+				t.Errorf("expected range %v (%v), got %v (%v)", test.expected, test.ok, r, ok)
+			}
+			if test.ok {
+				var posErr *PositionError
+				if _, err := level.Block(0, test.expected.Min()-1, 0); !errors.As(err, &posErr) {
+					t.Errorf("expected position error below the range, got %v", err)
+				}
+				if _, err := level.Block(0, test.expected.Max()+1, 0); !errors.As(err, &posErr) {
+					t.Errorf("expected position error above the range, got %v", err)
+				}
+			}
+
+			prov, err := mcdb.New(t.TempDir(), opt.FlateCompression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer prov.Close()
+			report, err := level.WriteBedrock(prov, Config{YShift: -64})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := report.ClippedSections(); n != test.clipped {
+				t.Errorf("expected %v clipped sections, got %v", test.clipped, n)
+			}
+		})
+	}
+}
+
+func TestLevelEdit(t *testing.T) {
+	dir := writeTestWorld(t)
+	level, err := LoadLevel(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stone, granite, dirt := states.Block{Name: "minecraft:stone"}, states.Block{Name: "minecraft:granite"}, states.Block{Name: "minecraft:dirt"}
+	grass := states.Block{Name: "minecraft:grass_block", Properties: map[string]any{"snowy": "false"}}
+
+	// The section at -4 only holds stone, and the one at -3 holds stone, granite, dirt and grass.
+	if err := level.Fill(cube.Pos{0, -64, 0}, cube.Pos{15, -49, 15}, dirt); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := level.Replace(cube.Pos{0, -48, 0}, cube.Pos{15, -33, 15}, grass, stone); err != nil || n != 1024 {
+		t.Fatalf("expected 1024 grass blocks replaced, got %v (%v)", n, err)
+	}
+	if err := level.SetBlock(5, -20, 5, granite); err != nil {
+		t.Fatal(err)
+	}
+	if err := level.SetBiome(8, -60, 8, "minecraft:desert"); err != nil {
+		t.Fatal(err)
+	}
+	// The chunk at 1, 0 was saved by 1.15 and is upgraded when it is read. Its section at 1 holds air below Y 24
+	// and stone above it.
+	if n, err := level.Replace(cube.Pos{16, 22, 0}, cube.Pos{17, 25, 1}, stone, granite); err != nil || n != 8 {
+		t.Fatalf("expected 8 stone blocks replaced, got %v (%v)", n, err)
+	}
+	if err := level.SetBlock(20, 3, 4, dirt); err != nil {
+		t.Fatal(err)
+	}
+	if err := level.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	level, err = LoadLevel(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		pos   cube.Pos
+		state states.Block
+	}{
+		{cube.Pos{0, -64, 0}, dirt},
+		{cube.Pos{15, -49, 15}, dirt},
+		{cube.Pos{0, -48, 3}, stone}, // Grass before replacing it.
+		{cube.Pos{0, -48, 2}, dirt},
+		{cube.Pos{5, -20, 5}, granite},
+		{cube.Pos{16, 23, 0}, airState},
+		{cube.Pos{17, 25, 1}, granite},
+		{cube.Pos{16, 26, 0}, stone},
+		{cube.Pos{20, 3, 4}, dirt},
+	} {
+		if state, err := level.Block(test.pos.X(), test.pos.Y(), test.pos.Z()); err != nil || !sameStates([]states.Block{state}, []states.Block{test.state}) {
+			t.Errorf("%v: expected %v, got %v (%v)", test.pos, test.state, state, err)
+		}
+	}
+	if n, err := level.Replace(cube.Pos{0, -48, 0}, cube.Pos{15, -33, 15}, grass, stone); err != nil || n != 0 {
+		t.Errorf("expected no grass left after saving, got %v (%v)", n, err)
+	}
+	for _, pos := range []cube.Pos{{8, -60, 8}, {11, -57, 11}} {
+		if biome, err := level.Biome(pos.X(), pos.Y(), pos.Z()); err != nil || biome != "minecraft:desert" {
+			t.Errorf("%v: expected desert, got %v (%v)", pos, biome, err)
+		}
+	}
+	if biome, err := level.Biome(12, -60, 8); err != nil || biome == "minecraft:desert" {
+		t.Errorf("expected the biome of the next cell to be left unchanged, got %v (%v)", biome, err)
+	}
+
+	// Saved palettes only hold the states still present in their section.
+	r, ok := level.region(0, 0)
+	if !ok {
+		t.Fatal("region 0, 0 not found")
+	}
+	c, _, err := r.Chunk(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		y       int8
+		palette []states.Block
+	}{
+		{-4, []states.Block{dirt}},
+		{-3, []states.Block{stone, granite, dirt}},
+	} {
+		sub, _ := c.Sub(test.y)
+		if !sameStates(sub.BlockStates.Palette, test.palette) {
+			t.Errorf("section %v: expected palette %v, got %v", test.y, test.palette, sub.BlockStates.Palette)
+		}
+		if len(test.palette) == 1 && len(sub.BlockStates.Data) != 0 {
+			t.Errorf("section %v: expected no data for a single state, got %v longs", test.y, len(sub.BlockStates.Data))
+		}
+	}
+	// The legacy chunk is stored in the current format once it has been saved.
+	raw, _, err := r.readSector(1, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_ = prov.Close()
+	var version struct{ DataVersion int32 }
+	if _, err := mcnbt.NewDecoder(bytes.NewReader(raw)).Decode(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version.DataVersion != upgrade.DataVersion() {
+		t.Errorf("expected the saved legacy chunk to have data version %v, got %v", upgrade.DataVersion(), version.DataVersion)
+	}
+}
+
+func TestLevelUpgrade(t *testing.T) {
+	chunks := releaseTestChunks(t)
+	chunks[[2]int32{0, 0}] = testChunk(t)
+	legacy := legacyTestChunk(t)
+	heights := make([]int32, 256)
+	for i := range heights {
+		heights[i] = int32(i)
+	}
+	// Heights range from 0 to 256, so 1.15 packs them in 9 bits that may span two longs.
+	legacy["Level"].(map[string]any)["Heightmaps"] = map[string]any{"MOTION_BLOCKING": packTestData(t, 257, 0, heights, true)}
+	chunks[[2]int32{1, 0}] = legacy
+	// Chunks from before 1.13 hold numeric block IDs, which cannot be upgraded.
+	chunks[[2]int32{5, 0}] = map[string]any{
+		"DataVersion": int32(1343),
+		"Level": map[string]any{
+			"xPos": int32(5), "zPos": int32(0),
+			"Sections": []any{map[string]any{"Y": byte(0), "Blocks": [4096]byte{}}},
+		},
+	}
+	dir := writeWorld(t, chunks)
+
+	level, err := LoadLevel(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []int{3, 0} {
+		upgraded, unsupported, err := level.Upgrade()
+		if err != nil {
+			t.Fatalf("upgrade %v: %v", i, err)
+		}
+		if upgraded != expected || !reflect.DeepEqual(unsupported, []world.ChunkPos{{5, 0}}) {
+			t.Errorf("upgrade %v: expected %v chunks upgraded and chunk 5, 0 unsupported, got %v and %v", i, expected, upgraded, unsupported)
+		}
+	}
+
+	level, err = LoadLevel(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := level.dat["DataVersion"]; v != upgrade.DataVersion() {
+		t.Errorf("expected the level.dat to have data version %v, got %v", upgrade.DataVersion(), v)
+	}
+	r, _ := level.region(0, 0)
+	for x, expected := range []int32{3105, upgrade.DataVersion(), upgrade.DataVersion(), upgrade.DataVersion(), 2975, 1343} {
+		raw, _, err := r.readSector(x, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data struct {
+			DataVersion int32
+			Heightmaps  map[string][]int64
+		}
+		if _, err := mcnbt.NewDecoder(bytes.NewReader(raw)).Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		if data.DataVersion != expected {
+			t.Errorf("chunk %v, 0: expected data version %v, got %v", x, expected, data.DataVersion)
+		}
+		if x != 1 {
+			continue
+		}
+		// The heightmaps of upgraded chunks are padded like their block states.
+		storage, err := column.NewFilledBitStorage(9, 256, data.Heightmaps["MOTION_BLOCKING"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make([]int32, 256)
+		if err := storage.DecodeAll(values); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, heights) {
+			t.Errorf("expected heights %v, got %v", heights, values)
+		}
+	}
+	if _, err := level.Block(16, 0, 0); err != nil {
+		t.Errorf("expected the upgraded chunk to be readable, got %v", err)
+	}
+}
+
+func TestLevelConcurrentReads(t *testing.T) {
+	dir := writeTestWorld(t)
+	open := func() *Level {
+		level, err := LoadLevel(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return level
+	}
+	// The expected blocks and biomes are read one by one from a separate level, so that every section is still
+	// undecoded when the goroutines start reading.
+	type entry struct {
+		block states.Block
+		biome string
+	}
+	expected, serial := make(map[[3]int]entry), open()
+	for y := -64; y < 48; y += 3 {
+		for x := 0; x < 32; x += 5 {
+			if x >= 16 && y < 0 {
+				// The chunk at 1, 0 was saved by 1.15, so its world spans 0 to 255.
+				continue
+			}
+			block, err := serial.Block(x, y, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			biome, err := serial.Biome(x, y, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected[[3]int{x, y, 7}] = entry{block: block, biome: biome}
+		}
+	}
+	if e := expected[[3]int{0, 47, 7}]; e.block.Name != "minecraft:air" || e.biome != plainsBiome {
+		t.Fatalf("expected air in plains above the chunk, got %v in %v", e.block, e.biome)
+	}
+
+	level, errs := open(), make(chan error, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pos, e := range expected {
+				block, err := level.Block(pos[0], pos[1], pos[2])
+				if err == nil && !reflect.DeepEqual(block, e.block) {
+					err = fmt.Errorf("%v: expected %v, got %v", pos, e.block, block)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				biome, err := level.Biome(pos[0], pos[1], pos[2])
+				if err == nil && biome != e.biome {
+					err = fmt.Errorf("%v: expected %v, got %v", pos, e.biome, biome)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestConvertProtocol(t *testing.T) {
+	c := &Chunk{DataVersion: 3105, YPos: -4, Status: "full", Sections: []SubChunk{{Y: 0}}}
+	c.Sections[0].BlockStates.Palette = []states.Block{airState}
+	c.Sections[0].Biomes.Palette = []string{"minecraft:deep_dark", "minecraft:mangrove_swamp"}
+	// The deep dark fills the lower half of the section, and mangrove swamps the upper half.
+	c.Sections[0].Biomes.Data = []int64{-1 << 32}
+	for protocol, expected := range map[int32][2]uint32{0: {190, 191}, 527: {190, 191}, 475: {188, 6}} {
+		conv, err := newConverter(Config{Protocol: protocol})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch, _, err := conv.convertChunk(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lower, upper := ch.Biome(0, 0, 0), ch.Biome(0, 15, 0); lower != expected[0] || upper != expected[1] {
+			t.Errorf("protocol %v: expected biomes %v, got [%v %v]", protocol, expected, lower, upper)
+		}
+	}
+}
+
+func BenchmarkWriteBedrock(b *testing.B) {
+	level, err := LoadLevel(writeSampleWorld(b, 2))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, cached := range []bool{true, false} {
+		name := "cached"
+		if !cached {
+			name = "uncached"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				prov, err := mcdb.New(b.TempDir(), opt.FlateCompression)
+				if err != nil {
+					b.Fatal(err)
+				}
+				conv, err := newConverter(Config{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if !cached {
+					conv.cache = nil
+				}
+				if err := level.writeBedrock(prov, conv); err != nil {
+					b.Fatal(err)
+				}
+				_ = prov.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkBedrockBlocks(b *testing.B) {
+	palette := make([]int32, 0, len(sampleStates))
+	for _, state := range sampleStates {
+		properties, _ := state["Properties"].(map[string]any)
+		id, ok := states.JavaStateToID(states.Block{Name: state["Name"].(string), Properties: properties})
+		if !ok {
+			b.Fatalf("unknown state %v", state)
+		}
+		palette = append(palette, id)
+	}
+	for _, cached := range []bool{true, false} {
+		name := "cached"
+		if !cached {
+			name = "uncached"
+		}
+		b.Run(name, func(b *testing.B) {
+			conv, err := newConverter(Config{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if !cached {
+				conv.cache = nil
+			}
+			mapper := conv.conf.mapper()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := conv.bedrockBlocks(palette, mapper); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// sampleStates holds the Java block states that sections of sample worlds are made of.
+var sampleStates = []map[string]any{
+	{"Name": "minecraft:air"},
+	{"Name": "minecraft:stone"},
+	{"Name": "minecraft:granite"},
+	{"Name": "minecraft:dirt"},
+	{"Name": "minecraft:grass_block", "Properties": map[string]any{"snowy": "false"}},
+	{"Name": "minecraft:water", "Properties": map[string]any{"level": "0"}},
+	{"Name": "minecraft:seagrass"},
+	{"Name": "minecraft:kelp", "Properties": map[string]any{"age": "0"}},
+	{"Name": "minecraft:oak_stairs", "Properties": map[string]any{"facing": "north", "half": "top", "shape": "straight", "waterlogged": "true"}},
+}
+
+// writeSampleWorld writes a world of n by n regions to a temporary directory and returns its path. Every region
+// holds 4 by 4 chunks, made up of sections holding random palettes of the sampleStates.
+func writeSampleWorld(tb testing.TB, n int) string {
+	r := rand.New(rand.NewSource(1))
+	chunks := make(map[[2]int32]map[string]any)
+	for regionX := int32(0); regionX < int32(n); regionX++ {
+		for regionZ := int32(0); regionZ < int32(n); regionZ++ {
+			for x := int32(0); x < 4; x++ {
+				for z := int32(0); z < 4; z++ {
+					pos := [2]int32{regionX<<5 + x, regionZ<<5 + z}
+					chunks[pos] = sampleChunk(tb, r, pos[0], pos[1])
+				}
+			}
+		}
+	}
+	return writeWorld(tb, chunks)
+}
+
+// writeWorld writes a world holding the chunk NBT passed, by chunk position, to a temporary directory and returns
+// its path.
+func writeWorld(tb testing.TB, chunks map[[2]int32]map[string]any) string {
+	dir := tb.TempDir()
+	if err := os.Mkdir(path.Join(dir, "region"), 0777); err != nil {
+		tb.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	err := nbt.NewEncoderWithEncoding(w, nbt.BigEndian).Encode(map[string]any{"Data": map[string]any{
+		"LevelName": "Sample", "DayTime": int64(0), "SpawnX": int32(0), "SpawnY": int32(64), "SpawnZ": int32(0),
+	}})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(dir, "level.dat"), buf.Bytes(), 0666); err != nil {
+		tb.Fatal(err)
+	}
+
+	regions := make(map[[2]int32]*Region)
+	for pos, data := range chunks {
+		regionPos := [2]int32{pos[0] >> 5, pos[1] >> 5}
+		reg, ok := regions[regionPos]
+		if !ok {
+			raw, err := region.Create(path.Join(dir, "region", fmt.Sprintf("r.%v.%v.mca", regionPos[0], regionPos[1])))
+			if err != nil {
+				tb.Fatal(err)
+			}
+			reg = &Region{raw: raw, x: int(regionPos[0]), z: int(regionPos[1])}
+			regions[regionPos] = reg
+		}
+		if err := reg.writeSector(int(pos[0]&31), int(pos[1]&31), data); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	for _, reg := range regions {
+		if err := reg.raw.Close(); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// sampleChunk returns the NBT of a full 1.19 chunk at the chunk position passed, filled with random sections.
+func sampleChunk(tb testing.TB, r *rand.Rand, x, z int32) map[string]any {
+	sections := make([]any, 0, 24)
+	for y := -4; y < 20; y++ {
+		palette := make([]any, 0, len(sampleStates))
+		for _, i := range r.Perm(len(sampleStates))[:1+r.Intn(len(sampleStates))] {
+			palette = append(palette, sampleStates[i])
+		}
+		blockStates := map[string]any{"palette": palette}
+		if len(palette) > 1 {
+			values := make([]int32, 4096)
+			for i := range values {
+				values[i] = int32(r.Intn(len(palette)))
+			}
+			storage := column.NewEmptyBitStorage(maxInt32(int32(bits.Len(uint(len(palette)-1))), 4), 4096)
+			if err := storage.EncodeAll(values); err != nil {
+				tb.Fatal(err)
+			}
+			blockStates["data"] = longArray(storage.Data())
+		}
+		sections = append(sections, map[string]any{
+			"Y":            byte(y),
+			"block_states": blockStates,
+			"biomes":       map[string]any{"palette": []any{"minecraft:plains"}},
+		})
+	}
+	return map[string]any{
+		"DataVersion": int32(3105),
+		"xPos":        x,
+		"yPos":        int32(-4),
+		"zPos":        z,
+		"Status":      "full",
+		"sections":    sections,
+	}
+}
+
+// maxInt32 returns the larger of two integers.
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// sameStates checks if the two lists of Java block states passed hold the same states in the same order. States
+// decoded without properties have nil properties, so they are compared by their state IDs.
+func sameStates(a, b []states.Block) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		idA, okA := states.JavaStateToID(a[i])
+		idB, okB := states.JavaStateToID(b[i])
+		if !okA || !okB || idA != idB {
+			return false
+		}
+	}
+	return true
 }
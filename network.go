@@ -0,0 +1,117 @@
+package mcanvil
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/biomes"
+	"github.com/justtaldevelops/mcanvil/column"
+	"github.com/justtaldevelops/mcanvil/states"
+	"io"
+)
+
+// WriteNetwork writes the sections of the chunk to the writer passed in the Java network chunk format, as sent in
+// the data of the chunk data packet. The number of sections passed are written from the lowest section of the
+// chunk up, and sections that the chunk does not hold are written as air. Block states and biomes are written
+// using the IDs of the states and biomes packages, which the registries sent to clients must match.
+func (c *Chunk) WriteNetwork(w io.Writer, sections int) error {
+	for i := 0; i < sections; i++ {
+		y := int8(int(c.YPos) + i)
+		var section *column.Section
+		if sub, ok := c.Sub(y); ok && len(sub.BlockStates.Palette) > 0 {
+			var err error
+			if section, err = sub.NetworkSection(); err != nil {
+				return &SectionError{Y: y, Err: err}
+			}
+		} else {
+			section = emptyNetworkSection()
+		}
+		if _, err := section.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NetworkLight returns the sky and block light of the chunk as sent to Java clients, for a world of the number of
+// sections passed. Light is included for the section below and above the world as well.
+func (c *Chunk) NetworkLight(sections int) column.Light {
+	n := sections + 2
+	skyMask, blockMask := make([]int64, (n+63)/64), make([]int64, (n+63)/64)
+	emptySkyMask, emptyBlockMask := make([]int64, (n+63)/64), make([]int64, (n+63)/64)
+	var light column.Light
+	for i := 0; i < n; i++ {
+		sub, ok := c.Sub(int8(int(c.YPos) - 1 + i))
+		if !ok {
+			emptySkyMask[i/64] |= 1 << (i % 64)
+			emptyBlockMask[i/64] |= 1 << (i % 64)
+			continue
+		}
+		if sky, ok := sub.SkyLight.([]byte); ok && len(sky) == column.LightSectionSize {
+			skyMask[i/64] |= 1 << (i % 64)
+			light.SkyLight = append(light.SkyLight, sky)
+		} else {
+			emptySkyMask[i/64] |= 1 << (i % 64)
+		}
+		if block, ok := sub.BlockLight.([]byte); ok && len(block) == column.LightSectionSize {
+			blockMask[i/64] |= 1 << (i % 64)
+			light.BlockLight = append(light.BlockLight, block)
+		} else {
+			emptyBlockMask[i/64] |= 1 << (i % 64)
+		}
+	}
+	light.SkyLightMask, light.BlockLightMask = skyMask, blockMask
+	light.EmptySkyLightMask, light.EmptyBlockLightMask = emptySkyMask, emptyBlockMask
+	return light
+}
+
+// NetworkSection returns the sub-chunk as a section of the Java network chunk format, counting the blocks in it
+// that are not air.
+func (s *SubChunk) NetworkSection() (*column.Section, error) {
+	blocks, err := s.BlockPalette()
+	if err != nil {
+		return nil, err
+	}
+	biomePalette, err := s.BiomePalette()
+	if err != nil {
+		return nil, err
+	}
+	palette, indices, err := paletteIndices(blocks)
+	if err != nil {
+		return nil, err
+	}
+	air := make([]bool, len(palette))
+	for i, id := range palette {
+		state, ok := states.IDToJavaState(id)
+		if !ok {
+			return nil, fmt.Errorf("could not find state for id: %d", id)
+		}
+		air[i] = isAir(state)
+	}
+	var count int16
+	for _, index := range indices {
+		if !air[index] {
+			count++
+		}
+	}
+	return &column.Section{BlockCount: count, Blocks: blocks, Biomes: biomePalette}, nil
+}
+
+// emptyNetworkSection returns a section of the Java network chunk format filled with air and plains.
+func emptyNetworkSection() *column.Section {
+	air, _ := states.JavaStateToID(airState)
+	plains, _ := biomes.JavaNameToID("minecraft:plains")
+	blockType, biomeType := blockPaletteType(), biomePaletteType()
+	return &column.Section{
+		Blocks: column.NewFilledDataPalette(blockType, blockType.GlobalBitsPerEntry, column.NewSingletonPalette(air), column.NewEmptyBitStorage(0, blockType.StorageSize)),
+		Biomes: column.NewFilledDataPalette(biomeType, biomeType.GlobalBitsPerEntry, column.NewSingletonPalette(plains), column.NewEmptyBitStorage(0, biomeType.StorageSize)),
+	}
+}
+
+// isAir returns true if the Java state passed is one of the air blocks, which Java does not count as blocks in a
+// section.
+func isAir(state states.Block) bool {
+	switch state.Name {
+	case "minecraft:air", "minecraft:cave_air", "minecraft:void_air":
+		return true
+	}
+	return false
+}
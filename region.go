@@ -2,71 +2,28 @@ package mcanvil
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	mcnbt "github.com/Tnze/go-mc/nbt"
 	"github.com/Tnze/go-mc/save/region"
 	"github.com/df-mc/dragonfly/server/world"
-	"github.com/df-mc/dragonfly/server/world/chunk"
 	"github.com/df-mc/dragonfly/server/world/mcdb"
-	"github.com/justtaldevelops/mcanvil/biomes"
-	"github.com/justtaldevelops/mcanvil/column"
-	"github.com/justtaldevelops/mcanvil/states"
+	"github.com/justtaldevelops/mcanvil/upgrade"
 	"github.com/klauspost/compress/zlib"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
-	"math/bits"
+	"io/ioutil"
 	"path"
 	"regexp"
 	"strconv"
+	"sync"
 )
 
-// Chunk represents a 16x16x16 chunk of blocks. In Java, these are known as columns.
-type Chunk struct {
-	DataVersion   int32
-	XPos          int32            `nbt:"xPos"`
-	YPos          int32            `nbt:"yPos"`
-	ZPos          int32            `nbt:"zPos"`
-	BlockEntities []map[string]any `nbt:"block_entities"`
-	Structures    map[string]any   `nbt:"structures"`
-	Heightmaps    struct {
-		MotionBlocking         any `nbt:"MOTION_BLOCKING"`
-		MotionBlockingNoLeaves any `nbt:"MOTION_BLOCKING_NO_LEAVES"`
-		OceanFloor             any `nbt:"OCEAN_FLOOR"`
-		OceanFloorWg           any `nbt:"OCEAN_FLOOR_WG"`
-		WorldSurface           any `nbt:"WORLD_SURFACE"`
-		WorldSurfaceWg         any `nbt:"WORLD_SURFACE_WG"`
-	}
-	Sections       []SubChunk `nbt:"sections"`
-	Lights         any        `nbt:"Lights"`
-	Entities       any        `nbt:"entities"`
-	BlockTicks     any        `nbt:"block_ticks"`
-	FluidTicks     any        `nbt:"fluid_ticks"`
-	PostProcessing any
-	CarvingMasks   any
-	InhabitedTime  int64
-	IsLightOn      byte `nbt:"isLightOn"`
-	LastUpdate     int64
-	Status         string
-}
-
-// SubChunk represents a 16x16 sub-chunk of a chunk. In Java, these are known as chunks or sections.
-type SubChunk struct {
-	Y           byte
-	BlockStates struct {
-		Palette []states.Block `nbt:"palette"`
-		Data    []int64        `nbt:"data,omitempty"`
-	} `nbt:"block_states"`
-	Biomes struct {
-		Palette []string `nbt:"palette"`
-		Data    []int64  `nbt:"data,omitempty"`
-	} `nbt:"biomes"`
-	SkyLight   any `nbt:"SkyLight,omitempty"`
-	BlockLight any `nbt:"BlockLight,omitempty"`
-}
-
 // regionExp is a regular expression that matches the region file name.
 var regionExp = regexp.MustCompile(`^r\.(-?\d+)\.(-?\d+)\.mca$`)
 
 // Region is an extension of the go-mc region implementation.
 type Region struct {
+	mu   sync.Mutex
 	raw  *region.Region
 	x, z int
 }
@@ -89,184 +46,217 @@ func LoadRegion(file string) (*Region, error) {
 // Chunks returns a slice of maps representing encoded chunks in this region.
 func (r *Region) Chunks() ([]Chunk, error) {
 	chunks := make([]Chunk, 0, 1024)
-	boundX, boundZ := r.x<<5, r.z<<5
+	boundX, boundZ := int32(r.x<<5), int32(r.z<<5)
 	for chunkX := boundX; chunkX < boundX+32; chunkX++ {
 		for chunkZ := boundZ; chunkZ < boundZ+32; chunkZ++ {
-			c, err := r.raw.ReadSector(chunkX&0x1f, chunkZ&0x1f)
-			if err != nil {
-				continue
-			}
-			z, err := zlib.NewReader(bytes.NewReader(c[1:]))
+			c, ok, err := r.Chunk(chunkX, chunkZ)
 			if err != nil {
 				return nil, err
 			}
-
-			var data Chunk
-			err = nbt.NewDecoderWithEncoding(z, nbt.BigEndian).Decode(&data)
-			if err != nil {
-				return nil, err
+			if ok {
+				chunks = append(chunks, c)
 			}
-			err = z.Close()
-			if err != nil {
-				return nil, err
-			}
-			chunks = append(chunks, data)
 		}
 	}
 	return chunks, nil
 }
 
-// WriteBedrock converts and writes a region file to a Bedrock world provider.
-func (r *Region) WriteBedrock(prov *mcdb.Provider) error {
-	chunks, err := r.Chunks()
-	if err != nil {
-		return fmt.Errorf("could not load chunk structures: %v", err)
+// Chunk reads the chunk at the chunk coordinates passed. If the chunk is not stored in the region, false is
+// returned. Chunks saved by older versions are upgraded to the current format before they are returned.
+func (r *Region) Chunk(x, z int32) (Chunk, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	localX, localZ := region.In(int(x), int(z))
+	raw, ok, err := r.readSector(localX, localZ)
+	if err != nil || !ok {
+		return Chunk{}, false, err
 	}
-	airRuntimeID, ok := chunk.StateToRuntimeID("minecraft:air", nil)
-	if !ok {
-		return fmt.Errorf("could not find air runtime id")
+
+	var version struct{ DataVersion int32 }
+	if _, err := mcnbt.NewDecoder(bytes.NewReader(raw)).Decode(&version); err != nil {
+		return Chunk{}, false, err
 	}
-	waterRuntimeID, ok := chunk.StateToRuntimeID("minecraft:water", map[string]any{"liquid_depth": int32(0)})
-	if !ok {
-		return fmt.Errorf("could not find water runtime id")
+	if version.DataVersion < upgrade.DataVersion() {
+		var data map[string]any
+		if err := nbt.UnmarshalEncoding(raw, &data, nbt.BigEndian); err != nil {
+			return Chunk{}, false, err
+		}
+		if _, err := upgrade.Chunk(data); err != nil {
+			return Chunk{}, false, err
+		}
+		if raw, err = nbt.MarshalEncoding(data, nbt.BigEndian); err != nil {
+			return Chunk{}, false, err
+		}
 	}
 
-	for _, c := range chunks {
-		if c.Status != "full" {
-			// Don't convert incomplete chunks, to be consistent with Bedrock.
-			continue
-		}
-		ch := chunk.New(airRuntimeID, world.Overworld.Range())
-		offsetX, offsetZ := c.XPos<<4, c.ZPos<<4
-		for _, s := range c.Sections {
-			rawBlockPalette := make([]int32, 0, len(s.BlockStates.Palette))
-			for _, state := range s.BlockStates.Palette {
-				id, ok := states.JavaStateToID(state)
-				if !ok {
-					return fmt.Errorf("could not find block id for state %v", state)
-				}
-				rawBlockPalette = append(rawBlockPalette, id)
-			}
+	// The go-mc NBT decoder is used here, as it supports decoding long arrays into slices.
+	var c Chunk
+	if _, err := mcnbt.NewDecoder(bytes.NewReader(raw)).Decode(&c); err != nil {
+		return Chunk{}, false, err
+	}
+	return c, true, nil
+}
 
-			n := int32(bits.Len(uint(len(rawBlockPalette) - 1)))
-			p, t := column.Palette(column.NewGlobalPalette()), column.ChunkPaletteType()
-			if n == 0 {
-				p = column.NewSingletonPalette(rawBlockPalette[0])
-			} else if n <= t.MinimumBitsPerEntry {
-				p, n = column.NewFilledListPalette(4, rawBlockPalette), 4
-			} else if n <= t.MaximumBitsPerEntry {
-				p = column.NewFilledMapPalette(n, rawBlockPalette)
-			}
+// Upgrade upgrades all chunks in the region saved by older versions to the current format in place. The number
+// of chunks upgraded is returned, along with the positions of chunks that were left as they are because the
+// upgrade package does not support their format, such as chunks from before 1.13.
+func (r *Region) Upgrade() (upgraded int, unsupported []world.ChunkPos, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-			storage := column.NewEmptyBitStorage(n, 4096)
-			if len(s.BlockStates.Data) > 0 {
-				storage, err = column.NewFilledBitStorage(n, storage.Capacity(), s.BlockStates.Data)
-				if err != nil {
-					return err
-				}
+	for localX := 0; localX < 32; localX++ {
+		for localZ := 0; localZ < 32; localZ++ {
+			raw, ok, err := r.readSector(localX, localZ)
+			if err != nil {
+				return upgraded, unsupported, err
 			}
-
-			ind := int8(s.Y) - int8(c.YPos)
-			offsetY := ch.SubY(int16(ind))
-
-			sub := ch.Sub()[ind]
-			dataPalette := column.NewFilledDataPalette(t, n, p, storage)
-			for blockX := int32(0); blockX < 16; blockX++ {
-				for blockY := int32(0); blockY < 16; blockY++ {
-					for blockZ := int32(0); blockZ < 16; blockZ++ {
-						id, err := dataPalette.Get(column.BlockPos{blockX, blockY, blockZ})
-						if err != nil {
-							return err
-						}
-						javaState, ok := states.IDToJavaState(id)
-						if !ok {
-							return fmt.Errorf("could not find state for id: %d", id)
-						}
-						if javaState.Name == "minecraft:air" {
-							// Chunks are already prefilled with air.
-							continue
-						}
-
-						bedrockState, waterlogged, ok := states.ConvertToBedrock(javaState)
-						if !ok {
-							return fmt.Errorf("could not find bedrock state for java state: %v", javaState)
-						}
-						rid, ok := chunk.StateToRuntimeID(bedrockState.Name, bedrockState.Properties)
-						if !ok {
-							return fmt.Errorf("could not find bedrock runtime id for state: %v", bedrockState)
-						}
-
-						sub.SetBlock(byte(blockX), byte(blockY), byte(blockZ), 0, rid)
-						if waterlogged {
-							sub.SetBlock(byte(blockX), byte(blockY), byte(blockZ), 1, waterRuntimeID)
-						}
-					}
-				}
+			if !ok {
+				continue
 			}
-
-			rawBiomePalette := make([]int32, 0, len(s.Biomes.Palette))
-			for _, name := range s.Biomes.Palette {
-				id, ok := biomes.JavaNameToID(name)
-				if !ok {
-					return fmt.Errorf("could not find biome id for name: %v", name)
-				}
-				rawBiomePalette = append(rawBiomePalette, id)
+			var data map[string]any
+			if err := nbt.UnmarshalEncoding(raw, &data, nbt.BigEndian); err != nil {
+				return upgraded, unsupported, err
 			}
-
-			n = int32(bits.Len(uint(len(rawBiomePalette) - 1)))
-			p, t = column.Palette(column.NewGlobalPalette()), column.BiomePaletteType()
-			if n == 0 {
-				p = column.NewSingletonPalette(rawBiomePalette[0])
-			} else if n <= t.MaximumBitsPerEntry {
-				p = column.NewFilledListPalette(n, rawBiomePalette)
+			pos := world.ChunkPos{int32(r.x<<5 + localX), int32(r.z<<5 + localZ)}
+			ok, err = upgrade.Chunk(data)
+			if errors.Is(err, upgrade.ErrUnsupported) {
+				unsupported = append(unsupported, pos)
+				continue
+			} else if err != nil {
+				return upgraded, unsupported, fmt.Errorf("chunk %v, %v: %w", pos.X(), pos.Z(), err)
 			}
-
-			storage = column.NewEmptyBitStorage(n, 64)
-			if len(s.Biomes.Data) > 0 {
-				storage, err = column.NewFilledBitStorage(n, storage.Capacity(), s.Biomes.Data)
-				if err != nil {
-					return err
-				}
+			if !ok {
+				continue
+			}
+			if err := r.writeSector(localX, localZ, data); err != nil {
+				return upgraded, unsupported, err
 			}
+			upgraded++
+		}
+	}
+	return upgraded, unsupported, nil
+}
 
-			for i := int32(0); i < storage.Capacity(); i++ {
-				paletteID, err := storage.Get(i)
-				if err != nil {
-					return err
-				}
-				id := p.IDToState(paletteID)
-				name, ok := biomes.IDToJavaName(id)
-				if !ok {
-					return fmt.Errorf("could not find biome name for id: %d", id)
-				}
-				if name == "minecraft:ocean" {
-					// Chunks use the ocean biome by default.
-					continue
-				}
+// writeChunk writes the sections of the chunk passed back into the region. Data of the stored chunk that is
+// not held by the Chunk structure is preserved.
+func (r *Region) writeChunk(c *Chunk) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-				bedrockID, ok := biomes.ConvertToBedrock(name)
-				if !ok {
-					return fmt.Errorf("could not find bedrock id for biome name: %v", name)
-				}
+	localX, localZ := region.In(int(c.XPos), int(c.ZPos))
+	raw, _, err := r.readSector(localX, localZ)
+	if err != nil {
+		return err
+	}
+	var data map[string]any
+	if err := nbt.UnmarshalEncoding(raw, &data, nbt.BigEndian); err != nil {
+		return err
+	}
+	// The sections of the chunk passed are in the current format, so the stored chunk must be too.
+	if _, err := upgrade.Chunk(data); err != nil {
+		return err
+	}
 
-				baseX := i & 3
-				baseY := (i >> 4) & 3
-				baseZ := (i >> 2) & 3
+	sections, _ := data["sections"].([]any)
+	for i := range c.Sections {
+		s := &c.Sections[i]
 
-				for blockX := baseX << 2; blockX < (baseX<<2)+4; blockX++ {
-					for blockZ := baseZ << 2; blockZ < (baseZ<<2)+4; blockZ++ {
-						for blockY := baseY << 2; blockY < (baseY<<2)+4; blockY++ {
-							ch.SetBiome(byte(offsetX+blockX), int16(blockY)+offsetY, byte(offsetZ+blockZ), bedrockID)
-						}
-					}
-				}
+		var section map[string]any
+		for _, v := range sections {
+			if m, ok := v.(map[string]any); ok && m["Y"] == s.Y {
+				section = m
+				break
 			}
 		}
+		if section == nil {
+			section = make(map[string]any)
+			sections = append(sections, section)
+		}
+		if err := s.encode(section); err != nil {
+			return err
+		}
+	}
+	data["sections"] = sections
+	// Light is not updated when editing blocks, so make sure the game recalculates it when loading the chunk.
+	data["isLightOn"] = byte(0)
+	return r.writeSector(localX, localZ, data)
+}
 
-		ch.Compact()
+// readSector reads the decompressed chunk NBT at the local chunk coordinates passed. If no chunk is stored
+// there, false is returned.
+func (r *Region) readSector(localX, localZ int) ([]byte, bool, error) {
+	if !r.raw.ExistSector(localX, localZ) {
+		return nil, false, nil
+	}
+	c, err := r.raw.ReadSector(localX, localZ)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(c) == 0 {
+		return nil, false, nil
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(c[1:]))
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, reader.Close()
+}
 
-		err = prov.SaveChunk(world.ChunkPos{c.XPos, c.ZPos}, ch, world.Overworld)
+// writeSector compresses and writes the chunk NBT passed at the local chunk coordinates passed.
+func (r *Region) writeSector(localX, localZ int, data map[string]any) error {
+	// The NBT is encoded in full before compressing it: the encoder passes strings to the writer without copying
+	// them, which the zlib writer does not cope with when the race detector is enabled.
+	var encoded bytes.Buffer
+	if err := nbt.NewEncoderWithEncoding(&encoded, nbt.BigEndian).Encode(data); err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer([]byte{2})
+	writer := zlib.NewWriter(buf)
+	if _, err := writer.Write(encoded.Bytes()); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return r.raw.WriteSector(localX, localZ, buf.Bytes())
+}
+
+// WriteBedrock converts and writes a region file to a Bedrock world provider, using the Config passed. A Report
+// of the conversion is returned.
+func (r *Region) WriteBedrock(prov *mcdb.Provider, conf Config) (*Report, error) {
+	conv, err := newConverter(conf)
+	if err != nil {
+		return nil, err
+	}
+	return conv.report, r.writeBedrock(prov, conv)
+}
+
+// writeBedrock converts and writes a region file to a Bedrock world provider using the converter passed.
+func (r *Region) writeBedrock(prov *mcdb.Provider, conv *converter) error {
+	chunks, err := r.Chunks()
+	if err != nil {
+		return fmt.Errorf("could not load chunk structures: %w", err)
+	}
+	for i := range chunks {
+		c := &chunks[i]
+		if c.Status != "full" {
+			// Don't convert incomplete chunks, to be consistent with Bedrock.
+			continue
+		}
+		ch, blockEntities, err := conv.convertChunk(c)
 		if err != nil {
+			return fmt.Errorf("could not convert chunk %v, %v: %w", c.XPos, c.ZPos, err)
+		}
+		pos := world.ChunkPos{c.XPos, c.ZPos}
+		if err := prov.SaveChunk(pos, ch, world.Overworld); err != nil {
+			return err
+		}
+		if err := prov.SaveBlockNBT(pos, blockEntities, world.Overworld); err != nil {
 			return err
 		}
 	}
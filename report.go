@@ -0,0 +1,165 @@
+package mcanvil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Report holds a summary of a conversion, such as the Java block states and biomes that could not be mapped
+// to Bedrock. A Report is safe for concurrent use.
+type Report struct {
+	mu sync.Mutex
+	// unmappedBlocks maps every unmapped Java block state to the number of blocks with that state.
+	unmappedBlocks map[string]int
+	// unmappedBiomes maps every unmapped Java biome to the number of 4x4x4 cells with that biome.
+	unmappedBiomes map[string]int
+	// clippedSections is the number of sections left out because they were outside the Bedrock range.
+	clippedSections int
+	// unconvertedBlockEntities maps the IDs of Java block entities without a converter to the number left out.
+	unconvertedBlockEntities map[string]int
+	// untranslatableCommands maps the Java commands that could not be translated to the reason why.
+	untranslatableCommands map[string]string
+}
+
+// newReport creates a new, empty Report.
+func newReport() *Report {
+	return &Report{
+		unmappedBlocks:           make(map[string]int),
+		unmappedBiomes:           make(map[string]int),
+		unconvertedBlockEntities: make(map[string]int),
+		untranslatableCommands:   make(map[string]string),
+	}
+}
+
+// UnmappedBlocks returns all Java block states that could not be mapped to Bedrock, in the compressed Java
+// format, along with the number of blocks that had the state.
+func (r *Report) UnmappedBlocks() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyCounts(r.unmappedBlocks)
+}
+
+// UnmappedBiomes returns all Java biomes that could not be mapped to Bedrock, along with the number of 4x4x4
+// cells that had the biome.
+func (r *Report) UnmappedBiomes() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyCounts(r.unmappedBiomes)
+}
+
+// ClippedSections returns the number of sections that were left out because they ended up outside the vertical
+// range of the Bedrock world.
+func (r *Report) ClippedSections() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clippedSections
+}
+
+// UnconvertedBlockEntities returns the IDs of all Java block entities that were left out because they cannot be
+// converted to Bedrock, along with the number of block entities with the ID.
+func (r *Report) UnconvertedBlockEntities() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyCounts(r.unconvertedBlockEntities)
+}
+
+// UntranslatableCommands returns the Java commands of command blocks that could not be translated to Bedrock,
+// along with the reason why. These commands are kept as they are in the converted world.
+func (r *Report) UntranslatableCommands() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := make(map[string]string, len(r.untranslatableCommands))
+	for k, v := range r.untranslatableCommands {
+		m[k] = v
+	}
+	return m
+}
+
+// String returns a human-readable summary of the Report, listing the most common entries first.
+func (r *Report) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	writeCounts(&b, "unmapped blocks", r.unmappedBlocks)
+	writeCounts(&b, "unmapped biomes", r.unmappedBiomes)
+	writeCounts(&b, "unconverted block entities", r.unconvertedBlockEntities)
+	_, _ = fmt.Fprintf(&b, "clipped sections: %v\n", r.clippedSections)
+
+	commands := make([]string, 0, len(r.untranslatableCommands))
+	for command := range r.untranslatableCommands {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	_, _ = fmt.Fprintf(&b, "untranslatable commands (%v):\n", len(commands))
+	for _, command := range commands {
+		_, _ = fmt.Fprintf(&b, "\t%v: %v\n", command, r.untranslatableCommands[command])
+	}
+	return b.String()
+}
+
+// addUnmappedBlock records n blocks of a Java block state that could not be mapped.
+func (r *Report) addUnmappedBlock(state string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unmappedBlocks[state] += n
+}
+
+// addUnmappedBiome records n cells of a Java biome that could not be mapped.
+func (r *Report) addUnmappedBiome(name string, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unmappedBiomes[name] += n
+}
+
+// addClippedSection records a section that was left out because it was outside the Bedrock range.
+func (r *Report) addClippedSection() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clippedSections++
+}
+
+// addUnconvertedBlockEntity records a Java block entity that was left out because it has no converter.
+func (r *Report) addUnconvertedBlockEntity(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unconvertedBlockEntities[id]++
+}
+
+// addUntranslatableCommand records a Java command that could not be translated, along with the error returned
+// by the translator.
+func (r *Report) addUntranslatableCommand(command string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.untranslatableCommands[command] = err.Error()
+}
+
+// copyCounts returns a copy of the counts passed.
+func copyCounts(counts map[string]int) map[string]int {
+	m := make(map[string]int, len(counts))
+	for k, v := range counts {
+		m[k] = v
+	}
+	return m
+}
+
+// writeCounts writes a titled list of the counts passed to the builder, sorted by count in descending order.
+func writeCounts(b *strings.Builder, title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] == counts[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return counts[keys[i]] > counts[keys[j]]
+	})
+
+	_, _ = fmt.Fprintf(b, "%v (%v):\n", title, len(keys))
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(b, "\t%v: %v\n", k, counts[k])
+	}
+}
@@ -3,68 +3,110 @@ package states
 import (
 	_ "embed"
 	"encoding/json"
+	"fmt"
 	"github.com/tidwall/gjson"
 	"strings"
+	"sync"
 )
 
 var (
 	//go:embed blocks.json
 	blockMappingData []byte
-	// javaToBedrockState is a map between a Java state hash and a Bedrock state.
-	javaToBedrockState = make(map[blockHash]Block)
+	// stateMu guards idToJavaState and javaStateToID, as states may be registered by mappers at runtime.
+	stateMu sync.RWMutex
 	// idToJavaState is a map between a Java state ID and a Java state.
 	idToJavaState = make(map[int32]Block)
 	// javaStateToID is a map between a Java state and a Java state ID.
 	javaStateToID = make(map[blockHash]int32)
-	// waterloggedBlocks is a set of all waterlogged Java block IDs.
-	waterloggedBlocks = make(map[blockHash]struct{})
+	// javaNameToIDs is a map between a Java block name and the IDs of all states with that name.
+	javaNameToIDs = make(map[string][]int32)
 )
 
 func init() {
 	parsedData := gjson.ParseBytes(blockMappingData)
 	parsedData.ForEach(func(key, value gjson.Result) bool {
-		k, v := key.String(), value.String()
-		bedrockState := parseBedrockBlockJSON(v)
-		javaState := parseJavaCompressedBlock(k)
-		id := int32(len(idToJavaState))
-		h := hashBlock(javaState)
-
-		javaToBedrockState[h] = bedrockState
-		javaStateToID[h] = id
-		idToJavaState[id] = javaState
-		if javaState.Name == "minecraft:bubble_column" || javaState.Name == "minecraft:kelp" || strings.Contains(k, "waterlogged=true") || strings.Contains(k, "seagrass") {
-			waterloggedBlocks[h] = struct{}{}
+		javaState, err := parseJavaCompressedBlock(key.String())
+		if err != nil {
+			panic(err)
+		}
+		bedrockState, err := parseBedrockBlockJSON(value.String())
+		if err != nil {
+			panic(err)
 		}
+		defaultMapper.Map(javaState, bedrockState)
 		return true
 	})
 }
 
 // IDToJavaState converts a Java state ID to a Java state.
 func IDToJavaState(id int32) (Block, bool) {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
 	state, ok := idToJavaState[id]
 	return state, ok
 }
 
+// Count returns the number of Java states currently registered. Java state IDs range from zero up to, but not
+// including, the count.
+func Count() int32 {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
+	return int32(len(idToJavaState))
+}
+
 // JavaStateToID converts a Java state to a Java state ID.
 func JavaStateToID(state Block) (int32, bool) {
+	stateMu.RLock()
+	defer stateMu.RUnlock()
 	id, ok := javaStateToID[hashBlock(state)]
 	return id, ok
 }
 
-// ConvertToBedrock converts a Java state to a Bedrock state. The second boolean is true if the state is waterlogged.
+// ConvertToBedrock converts a Java state to a Bedrock state using the default mapper. The second boolean is
+// true if the state is waterlogged.
 func ConvertToBedrock(state Block) (Block, bool, bool) {
+	return defaultMapper.ConvertToBedrock(state)
+}
+
+// ConvertToJava converts a Bedrock state to a Java state using the default mapper.
+func ConvertToJava(state Block) (Block, bool) {
+	return defaultMapper.ConvertToJava(state)
+}
+
+// ParseJava parses a Java state in the compressed format returned by Block.String, such as
+// "minecraft:oak_log[axis=y]".
+func ParseJava(s string) (Block, error) {
+	return parseJavaCompressedBlock(s)
+}
+
+// register registers a Java state, assigning it the next free Java state ID if it did not yet have one.
+func register(state Block) int32 {
 	h := hashBlock(state)
-	converted, ok := javaToBedrockState[h]
-	_, waterlogged := waterloggedBlocks[h]
-	return converted, waterlogged, ok
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if id, ok := javaStateToID[h]; ok {
+		return id
+	}
+	id := int32(len(idToJavaState))
+	javaStateToID[h] = id
+	idToJavaState[id] = state
+	javaNameToIDs[state.Name] = append(javaNameToIDs[state.Name], id)
+	return id
+}
+
+// waterlogged returns true if the Java state passed holds a liquid according to the default liquid rules.
+func waterlogged(state Block) bool {
+	_, ok := defaultLiquids.Liquid(state)
+	return ok
 }
 
 // parseBedrockBlockJSON parses a JSON block state string and returns a Block.
-func parseBedrockBlockJSON(data string) Block {
+func parseBedrockBlockJSON(data string) (Block, error) {
 	var state Block
 	err := json.Unmarshal([]byte(data), &state)
 	if err != nil {
-		panic(err)
+		return Block{}, err
 	}
 
 	// The standard JSON package automatically converts numbers to floats, but we need them as integers.
@@ -73,18 +115,21 @@ func parseBedrockBlockJSON(data string) Block {
 			state.Properties[k] = int32(v)
 		}
 	}
-	return state
+	return state, nil
 }
 
 // parseJavaCompressedBlock parses a compressed block state string and returns a Block.
-func parseJavaCompressedBlock(compressed string) Block {
+func parseJavaCompressedBlock(compressed string) (Block, error) {
 	data := strings.Split(strings.TrimSuffix(compressed, "]"), "[")
 	name, properties := data[0], map[string]any{}
 	if len(data) > 1 {
 		for _, entry := range strings.Split(data[1], ",") {
 			values := strings.Split(entry, "=")
+			if len(values) != 2 {
+				return Block{}, fmt.Errorf("invalid property %q in state %v", entry, compressed)
+			}
 			properties[values[0]] = values[1]
 		}
 	}
-	return Block{Name: name, Properties: properties}
+	return Block{Name: name, Properties: properties}, nil
 }
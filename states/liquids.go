@@ -0,0 +1,184 @@
+package states
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	//go:embed liquids.json
+	liquidData []byte
+	// defaultLiquids holds the embedded liquid rules.
+	defaultLiquids = mustLoadLiquids(liquidData)
+)
+
+// LiquidRule is a rule of a liquid table. Java stores liquids in blocks using a property such as waterlogged, or
+// implicitly for blocks like kelp, while Bedrock places the liquid on a second block layer.
+type LiquidRule struct {
+	// Name is the name of the Java blocks the rule applies to. If empty, the rule applies to all blocks.
+	Name string `json:"name"`
+	// Properties are the Java properties a block must have for the rule to apply.
+	Properties map[string]string `json:"properties"`
+	// Liquid is the Bedrock block placed on the liquid layer, such as water with a liquid_depth. If nil, no
+	// liquid is placed, which may be used for Java states that Bedrock cannot hold liquids in. Java only holds
+	// sources in blocks, so flowing liquids on the Bedrock liquid layer are dropped when converting back.
+	Liquid *Block `json:"liquid"`
+}
+
+// matches returns true if the rule applies to the Java state passed.
+func (r LiquidRule) matches(state Block) bool {
+	if r.Name != "" && r.Name != state.Name {
+		return false
+	}
+	for k, v := range r.Properties {
+		if p, ok := state.Properties[k]; !ok || fmt.Sprint(p) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Liquids is a table of LiquidRules deciding which Java states hold liquids and what Bedrock places on the liquid
+// layer for them. The first rule that matches a state is used. Liquids is safe for concurrent use.
+type Liquids struct {
+	mu    sync.RWMutex
+	rules []LiquidRule
+}
+
+// DefaultLiquids returns the table holding the default, embedded liquid rules.
+func DefaultLiquids() *Liquids {
+	return defaultLiquids
+}
+
+// NewLiquids creates a new table holding the rules of the parent passed. If the parent is nil, the table starts
+// out empty.
+func NewLiquids(parent *Liquids) *Liquids {
+	l := &Liquids{}
+	if parent != nil {
+		parent.mu.RLock()
+		l.rules = append(l.rules, parent.rules...)
+		parent.mu.RUnlock()
+	}
+	return l
+}
+
+// Add adds rules to the table. The rules take precedence over the rules already in the table.
+func (l *Liquids) Add(rules ...LiquidRule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = append(append([]LiquidRule(nil), rules...), l.rules...)
+}
+
+// LoadJSON loads rules from JSON in the same format as the embedded rules: a list of objects holding an optional
+// "name", "properties" and "liquid", with the liquid in the format of a Bedrock state in the block mappings. The
+// rules take precedence over the rules already in the table.
+func (l *Liquids) LoadJSON(r io.Reader) error {
+	var rules []LiquidRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return fmt.Errorf("invalid liquid json: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.Liquid == nil {
+			continue
+		}
+		// The standard JSON package automatically converts numbers to floats, but we need them as integers.
+		for k, v := range rule.Liquid.Properties {
+			if v, ok := v.(float64); ok {
+				rule.Liquid.Properties[k] = int32(v)
+			}
+		}
+	}
+	l.Add(rules...)
+	return nil
+}
+
+// Liquid returns the Bedrock block to place on the liquid layer for the Java state passed. If the state does not
+// hold a liquid in Bedrock, false is returned.
+func (l *Liquids) Liquid(state Block) (Block, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	liquid, ok, _ := l.liquid(state)
+	return liquid, ok
+}
+
+// Waterlog is the reverse of Liquid: it returns the Java state holding the Bedrock liquid passed, given the Java
+// state converted from the first block layer. The liquid is only held if a rule produces the exact liquid for the
+// resulting state, so flowing liquids, which Java only holds as sources, are not held, and neither are liquids in
+// blocks that Bedrock waterlogs but Java does not. In these cases, false is returned along with the state passed.
+func (l *Liquids) Waterlog(state, liquid Block) (Block, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	h := hashBlock(liquid)
+	for _, r := range l.rules {
+		if r.Liquid == nil || hashBlock(*r.Liquid) != h || (r.Name != "" && r.Name != state.Name) {
+			continue
+		}
+		waterlogged := Block{Name: state.Name, Properties: make(map[string]any, len(state.Properties))}
+		for k, v := range state.Properties {
+			waterlogged.Properties[k] = v
+		}
+		for k, v := range r.Properties {
+			waterlogged.Properties[k] = v
+		}
+		if _, ok := JavaStateToID(waterlogged); !ok {
+			continue
+		}
+		// A rule placed before this one may prevent the liquid from being held, such as for Java states that
+		// Bedrock cannot waterlog.
+		if held, ok, _ := l.liquid(waterlogged); ok && hashBlock(held) == h {
+			return waterlogged, true
+		}
+	}
+	return state, false
+}
+
+// ConvertToJava converts a Bedrock block to a Java state using the mapper passed. The state is the block on the
+// first layer, and the liquid the block on the liquid layer, which is air if the layer is empty. Liquids that the
+// Java state cannot hold are dropped. If the mapper cannot convert the state, false is returned.
+func (l *Liquids) ConvertToJava(m *Mapper, state, liquid Block) (Block, bool) {
+	java, ok := m.ConvertToJava(state)
+	if !ok {
+		return Block{}, false
+	}
+	if liquid.Name == "minecraft:air" {
+		return java, true
+	}
+	java, _ = l.Waterlog(java, liquid)
+	return java, true
+}
+
+// holdsLiquid returns true if a rule applies to the Java state passed, which means that Java holds a liquid in it,
+// even if Bedrock does not.
+func (l *Liquids) holdsLiquid(state Block) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, _, matched := l.liquid(state)
+	return matched
+}
+
+// liquid returns the liquid of the first rule that applies to the Java state passed. The second boolean is true if
+// a rule applied, even if it places no liquid. The mutex must be held while calling liquid.
+func (l *Liquids) liquid(state Block) (Block, bool, bool) {
+	for _, r := range l.rules {
+		if r.matches(state) {
+			if r.Liquid == nil {
+				return Block{}, false, true
+			}
+			return *r.Liquid, true, true
+		}
+	}
+	return Block{}, false, false
+}
+
+// mustLoadLiquids loads a liquid table from the JSON passed, panicking if it is invalid.
+func mustLoadLiquids(data []byte) *Liquids {
+	l := NewLiquids(nil)
+	if err := l.LoadJSON(bytes.NewReader(data)); err != nil {
+		panic(err)
+	}
+	return l
+}
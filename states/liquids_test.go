@@ -0,0 +1,122 @@
+package states
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLiquid(t *testing.T) {
+	m := NewMapper(DefaultMapper())
+	// Rails are washed away by water in Bedrock, so they may not hold it, unlike in Java.
+	m.Map(mustParseJava(t, "minecraft:rail[shape=north_south,waterlogged=true]"), Block{Name: "minecraft:rail", Properties: map[string]any{"rail_direction": int32(0)}})
+	m.Map(mustParseJava(t, "minecraft:rail[shape=north_south,waterlogged=false]"), Block{Name: "minecraft:rail", Properties: map[string]any{"rail_direction": int32(0)}})
+
+	water := Block{Name: "minecraft:water", Properties: map[string]any{"liquid_depth": int32(0)}}
+	for java, expected := range map[string]*Block{
+		"minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=true]":  &water,
+		"minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=false]": nil,
+		"minecraft:seagrass":                                 &water,
+		"minecraft:kelp[age=0]":                              &water,
+		"minecraft:bubble_column[drag=true]":                 &water,
+		"minecraft:bubble_column[drag=false]":                &water,
+		"minecraft:rail[shape=north_south,waterlogged=true]": nil,
+		"minecraft:water[level=1]":                           nil,
+		"minecraft:stone":                                    nil,
+	} {
+		liquid, ok := DefaultLiquids().Liquid(mustParseJava(t, java))
+		if expected == nil {
+			if ok {
+				t.Errorf("%v: expected no liquid, got %v", java, liquid)
+			}
+			continue
+		}
+		if !ok || hashBlock(liquid) != hashBlock(*expected) {
+			t.Errorf("%v: expected %v, got %v (ok: %v)", java, *expected, liquid, ok)
+		}
+	}
+	if bedrock, waterlogged, _ := m.ConvertToBedrock(mustParseJava(t, "minecraft:rail[shape=north_south,waterlogged=true]")); waterlogged {
+		t.Errorf("expected %v not to be waterlogged in Bedrock", bedrock)
+	}
+}
+
+func TestLiquidsConvertToJava(t *testing.T) {
+	m := NewMapper(DefaultMapper())
+	// The waterlogged rail is mapped first, but the dry one must still be preferred when converting back.
+	rail := Block{Name: "minecraft:rail", Properties: map[string]any{"rail_direction": int32(0)}}
+	m.Map(mustParseJava(t, "minecraft:rail[shape=north_south,waterlogged=true]"), rail)
+	m.Map(mustParseJava(t, "minecraft:rail[shape=north_south,waterlogged=false]"), rail)
+
+	lava := NewLiquids(DefaultLiquids())
+	lava.Add(LiquidRule{
+		Name:       "minecraft:oak_stairs",
+		Properties: map[string]string{"waterlogged": "true"},
+		Liquid:     &Block{Name: "minecraft:lava", Properties: map[string]any{"liquid_depth": int32(0)}},
+	})
+
+	stairs := Block{Name: "minecraft:oak_stairs", Properties: map[string]any{"upside_down_bit": true, "weirdo_direction": int32(3)}}
+	air := Block{Name: "minecraft:air"}
+	water := Block{Name: "minecraft:water", Properties: map[string]any{"liquid_depth": int32(0)}}
+	flowingWater := Block{Name: "minecraft:flowing_water", Properties: map[string]any{"liquid_depth": int32(3)}}
+	lavaSource := Block{Name: "minecraft:lava", Properties: map[string]any{"liquid_depth": int32(0)}}
+	for _, test := range []struct {
+		name          string
+		liquids       *Liquids
+		state, liquid Block
+		expected      string
+	}{
+		{"dry", DefaultLiquids(), stairs, air, "minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=false]"},
+		{"waterlogged", DefaultLiquids(), stairs, water, "minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=true]"},
+		{"flowing", DefaultLiquids(), stairs, flowingWater, "minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=false]"},
+		{"lava", DefaultLiquids(), stairs, lavaSource, "minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=false]"},
+		{"custom lava", lava, stairs, lavaSource, "minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=true]"},
+		{"custom lava with water", lava, stairs, water, "minecraft:oak_stairs[facing=north,half=top,shape=straight,waterlogged=false]"},
+		{"implicit", DefaultLiquids(), Block{Name: "minecraft:seagrass", Properties: map[string]any{"sea_grass_type": "default"}}, water, "minecraft:seagrass"},
+		{"bubble column", DefaultLiquids(), Block{Name: "minecraft:bubble_column", Properties: map[string]any{"drag_down": true}}, water, "minecraft:bubble_column[drag=true]"},
+		{"bedrock only", DefaultLiquids(), Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "stone"}}, water, "minecraft:stone"},
+		{"java only", DefaultLiquids(), rail, water, "minecraft:rail[shape=north_south,waterlogged=false]"},
+	} {
+		converted, ok := test.liquids.ConvertToJava(m, test.state, test.liquid)
+		if !ok {
+			t.Errorf("%v: could not convert %v", test.name, test.state)
+			continue
+		}
+		if expected := mustParseJava(t, test.expected); hashBlock(converted) != hashBlock(expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, expected, converted)
+		}
+	}
+
+	if _, ok := DefaultLiquids().ConvertToJava(m, Block{Name: "minecraft:unknown"}, air); ok {
+		t.Error("expected an unknown state not to be converted")
+	}
+}
+
+func TestLiquidsLoadJSON(t *testing.T) {
+	l := NewLiquids(DefaultLiquids())
+	err := l.LoadJSON(strings.NewReader(`[{"name": "minecraft:seagrass", "liquid": null}, {"name": "minecraft:stone", "liquid": {"bedrock_identifier": "minecraft:flowing_lava", "bedrock_states": {"liquid_depth": 4}}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if liquid, ok := l.Liquid(mustParseJava(t, "minecraft:seagrass")); ok {
+		t.Errorf("expected seagrass to hold no liquid, got %v", liquid)
+	}
+	expected := Block{Name: "minecraft:flowing_lava", Properties: map[string]any{"liquid_depth": int32(4)}}
+	if liquid, ok := l.Liquid(mustParseJava(t, "minecraft:stone")); !ok || hashBlock(liquid) != hashBlock(expected) {
+		t.Errorf("expected stone to hold %v, got %v (ok: %v)", expected, liquid, ok)
+	}
+	// The default table must not be changed by loading rules into a child table.
+	if _, ok := DefaultLiquids().Liquid(mustParseJava(t, "minecraft:seagrass")); !ok {
+		t.Error("expected seagrass to hold water in the default table")
+	}
+	if err := l.LoadJSON(strings.NewReader(`{`)); err == nil {
+		t.Error("expected invalid json to be rejected")
+	}
+}
+
+// mustParseJava parses a Java state in the compressed format, failing the test if it is invalid.
+func mustParseJava(t *testing.T, s string) Block {
+	state, err := parseJavaCompressedBlock(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
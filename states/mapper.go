@@ -0,0 +1,159 @@
+package states
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pelletier/go-toml"
+	"github.com/tidwall/gjson"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Mapper maps Java states to Bedrock states. Mappers may be layered over a parent mapper, in which case states
+// that the mapper does not map itself are looked up in the parent.
+type Mapper struct {
+	// parent is the mapper used for states not mapped by this mapper. It is nil for the default mapper.
+	parent *Mapper
+	// javaToBedrockState is a map between a Java state hash and a Bedrock state.
+	javaToBedrockState map[blockHash]Block
+	// waterloggedBlocks is a set of all waterlogged Java states mapped by this mapper.
+	waterloggedBlocks map[blockHash]struct{}
+	// bedrockToJavaState is a map between a Bedrock state hash and the Java state it is converted back to.
+	bedrockToJavaState map[blockHash]Block
+}
+
+// defaultMapper is the mapper holding the embedded block mappings.
+var defaultMapper = NewMapper(nil)
+
+// DefaultMapper returns the mapper holding the default, embedded block mappings.
+func DefaultMapper() *Mapper {
+	return defaultMapper
+}
+
+// NewMapper creates a new, empty mapper layered over the parent passed. If the parent is nil, the mapper will
+// only map the states that are explicitly added to it.
+func NewMapper(parent *Mapper) *Mapper {
+	return &Mapper{
+		parent:             parent,
+		javaToBedrockState: make(map[blockHash]Block),
+		waterloggedBlocks:  make(map[blockHash]struct{}),
+		bedrockToJavaState: make(map[blockHash]Block),
+	}
+}
+
+// Map maps the Java state passed to the Bedrock state passed, overriding any mapping of the parent. Java
+// states that are not yet known are registered, so that they may be used in sections. When converting back to
+// Java, the first Java state mapped to a Bedrock state is used, preferring states that do not hold liquids, as
+// Bedrock stores those on a separate layer.
+func (m *Mapper) Map(java, bedrock Block) {
+	h := hashBlock(java)
+	register(java)
+
+	m.javaToBedrockState[h] = bedrock
+	if waterlogged(java) {
+		m.waterloggedBlocks[h] = struct{}{}
+	} else {
+		delete(m.waterloggedBlocks, h)
+	}
+
+	bedrockHash := hashBlock(bedrock)
+	liquid := defaultLiquids.holdsLiquid(java)
+	if existing, ok := m.bedrockToJavaState[bedrockHash]; !ok || (!liquid && defaultLiquids.holdsLiquid(existing)) {
+		m.bedrockToJavaState[bedrockHash] = java
+	}
+}
+
+// LoadJSON loads mappings from JSON in the same format as the embedded mappings: an object with compressed Java
+// states, such as "minecraft:oak_log[axis=y]", as keys, and objects holding a "bedrock_identifier" and
+// "bedrock_states" as values.
+func (m *Mapper) LoadJSON(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !gjson.ValidBytes(data) {
+		return fmt.Errorf("invalid block mapping json")
+	}
+	gjson.ParseBytes(data).ForEach(func(key, value gjson.Result) bool {
+		var javaState, bedrockState Block
+		if javaState, err = parseJavaCompressedBlock(key.String()); err != nil {
+			return false
+		}
+		if bedrockState, err = parseBedrockBlockJSON(value.Raw); err != nil {
+			err = fmt.Errorf("invalid mapping for %v: %w", key.String(), err)
+			return false
+		}
+		m.Map(javaState, bedrockState)
+		return true
+	})
+	return err
+}
+
+// LoadTOML loads mappings from TOML. Every table has a compressed Java state as its key, and holds a
+// "bedrock_identifier" and "bedrock_states", like the JSON accepted by LoadJSON.
+func (m *Mapper) LoadTOML(r io.Reader) error {
+	tree, err := toml.LoadReader(r)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(tree.ToMap())
+	if err != nil {
+		return err
+	}
+	return m.LoadJSON(strings.NewReader(string(data)))
+}
+
+// ConvertToBedrock converts a Java state to a Bedrock state. The second boolean is true if the state is waterlogged.
+func (m *Mapper) ConvertToBedrock(state Block) (Block, bool, bool) {
+	h := hashBlock(state)
+	for mapper := m; mapper != nil; mapper = mapper.parent {
+		if converted, ok := mapper.javaToBedrockState[h]; ok {
+			_, waterlogged := mapper.waterloggedBlocks[h]
+			return converted, waterlogged, true
+		}
+	}
+	return Block{}, false, false
+}
+
+// ConvertToJava converts a Bedrock state to a Java state. Liquids on the second Bedrock layer are not taken into
+// account: Liquids.ConvertToJava converts both layers.
+func (m *Mapper) ConvertToJava(state Block) (Block, bool) {
+	h := hashBlock(state)
+	for mapper := m; mapper != nil; mapper = mapper.parent {
+		if converted, ok := mapper.bedrockToJavaState[h]; ok {
+			return converted, true
+		}
+	}
+	return Block{}, false
+}
+
+// Nearest returns the Java state with the same name as the state passed that this mapper can convert and that
+// shares the most property values with it. Properties that the states do not share are dropped. If no state
+// with the same name can be converted, false is returned.
+func (m *Mapper) Nearest(state Block) (Block, bool) {
+	stateMu.RLock()
+	candidates := make([]Block, 0, len(javaNameToIDs[state.Name]))
+	for _, id := range javaNameToIDs[state.Name] {
+		candidates = append(candidates, idToJavaState[id])
+	}
+	stateMu.RUnlock()
+
+	var nearest Block
+	best := -1
+	for _, candidate := range candidates {
+		if _, _, ok := m.ConvertToBedrock(candidate); !ok {
+			continue
+		}
+		var matches int
+		for k, v := range candidate.Properties {
+			if state.Properties[k] == v {
+				matches++
+			}
+		}
+		if matches > best {
+			nearest, best = candidate, matches
+		}
+	}
+	return nearest, best >= 0
+}
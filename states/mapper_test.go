@@ -0,0 +1,119 @@
+package states
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapperNearest(t *testing.T) {
+	m, other := NewMapper(nil), NewMapper(nil)
+	red := Block{Name: "testmod:lamp", Properties: map[string]any{"color": "red", "lit": "true"}}
+	blue := Block{Name: "testmod:lamp", Properties: map[string]any{"color": "blue", "lit": "false"}}
+	m.Map(red, Block{Name: "minecraft:redstone_lamp", Properties: map[string]any{}})
+	m.Map(blue, Block{Name: "minecraft:stone", Properties: map[string]any{}})
+	// States mapped by another mapper are registered, but cannot be converted by the first one.
+	green := Block{Name: "testmod:lamp", Properties: map[string]any{"color": "green", "lit": "true", "size": "big"}}
+	other.Map(green, Block{Name: "minecraft:dirt", Properties: map[string]any{}})
+
+	for _, test := range []struct {
+		state    Block
+		expected Block
+		ok       bool
+	}{
+		{state: Block{Name: "testmod:lamp", Properties: map[string]any{"color": "red", "lit": "true", "size": "big"}}, expected: red, ok: true},
+		{state: Block{Name: "testmod:lamp", Properties: map[string]any{"color": "blue", "lit": "false", "size": "small"}}, expected: blue, ok: true},
+		{state: Block{Name: "testmod:lamp", Properties: map[string]any{"color": "green", "lit": "true", "size": "big"}}, expected: red, ok: true},
+		{state: Block{Name: "testmod:lamp", Properties: map[string]any{"color": "blue", "lit": "true"}}, expected: red, ok: true},
+		{state: Block{Name: "testmod:unknown"}},
+	} {
+		nearest, ok := m.Nearest(test.state)
+		if ok != test.ok || (ok && hashBlock(nearest) != hashBlock(test.expected)) {
+			t.Errorf("%v: expected %v (%v), got %v (%v)", test.state, test.expected, test.ok, nearest, ok)
+		}
+	}
+	if nearest, ok := other.Nearest(red); !ok || hashBlock(nearest) != hashBlock(green) {
+		t.Errorf("expected %v, got %v (%v)", green, nearest, ok)
+	}
+}
+
+func TestMapperLayer(t *testing.T) {
+	m := NewMapper(DefaultMapper())
+	err := m.LoadJSON(strings.NewReader(`{
+		"minecraft:dirt": {"bedrock_identifier": "minecraft:stone", "bedrock_states": {"stone_type": "granite"}},
+		"testmod:crate[open=true]": {"bedrock_identifier": "minecraft:barrel", "bedrock_states": {"facing_direction": 1, "open_bit": true}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirt, granite, stone := Block{Name: "minecraft:dirt"}, Block{Name: "minecraft:granite"}, Block{Name: "minecraft:stone"}
+	for _, test := range []struct {
+		mapper   *Mapper
+		state    Block
+		expected Block
+	}{
+		// The layer overrides the mapping of dirt, and falls through to the default mapper for other states.
+		{m, dirt, Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "granite"}}},
+		{m, granite, Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "granite"}}},
+		{m, stone, Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "stone"}}},
+		{m, Block{Name: "testmod:crate", Properties: map[string]any{"open": "true"}}, Block{Name: "minecraft:barrel", Properties: map[string]any{"facing_direction": int32(1), "open_bit": true}}},
+		// The parent is left unchanged.
+		{DefaultMapper(), dirt, Block{Name: "minecraft:dirt", Properties: map[string]any{"dirt_type": "normal"}}},
+	} {
+		converted, _, ok := test.mapper.ConvertToBedrock(test.state)
+		if !ok || !reflect.DeepEqual(converted, test.expected) {
+			t.Errorf("%v: expected %v, got %v (%v)", test.state, test.expected, converted, ok)
+		}
+	}
+	if _, _, ok := DefaultMapper().ConvertToBedrock(Block{Name: "testmod:crate", Properties: map[string]any{"open": "true"}}); ok {
+		t.Error("expected the default mapper not to map states added to a layer")
+	}
+	// States mapped by the layer are converted back to Java before those of the parent.
+	if java, ok := m.ConvertToJava(Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "granite"}}); !ok || java.Name != "minecraft:dirt" {
+		t.Errorf("expected granite to convert back to dirt, got %v (%v)", java, ok)
+	}
+	if java, ok := m.ConvertToJava(Block{Name: "minecraft:stone", Properties: map[string]any{"stone_type": "stone"}}); !ok || java.Name != "minecraft:stone" {
+		t.Errorf("expected stone to convert back to stone, got %v (%v)", java, ok)
+	}
+}
+
+func TestMapperLoadTOML(t *testing.T) {
+	fromJSON, fromTOML := NewMapper(nil), NewMapper(nil)
+	err := fromJSON.LoadJSON(strings.NewReader(`{
+		"testmod:crate[facing=up,open=true]": {"bedrock_identifier": "minecraft:barrel", "bedrock_states": {"facing_direction": 1, "open_bit": true}},
+		"testmod:crate[facing=up,open=false]": {"bedrock_identifier": "minecraft:barrel", "bedrock_states": {"facing_direction": 1, "open_bit": false}},
+		"testmod:slab[waterlogged=true]": {"bedrock_identifier": "minecraft:stone_block_slab", "bedrock_states": {"stone_slab_type": "smooth_stone", "top_slot_bit": false}},
+		"testmod:marker": {"bedrock_identifier": "minecraft:structure_void"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fromTOML.LoadTOML(strings.NewReader(`
+"testmod:crate[facing=up,open=true]" = { bedrock_identifier = "minecraft:barrel", bedrock_states = { facing_direction = 1, open_bit = true } }
+"testmod:crate[facing=up,open=false]" = { bedrock_identifier = "minecraft:barrel", bedrock_states = { facing_direction = 1, open_bit = false } }
+"testmod:slab[waterlogged=true]" = { bedrock_identifier = "minecraft:stone_block_slab", bedrock_states = { stone_slab_type = "smooth_stone", top_slot_bit = false } }
+
+["testmod:marker"]
+bedrock_identifier = "minecraft:structure_void"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON, fromTOML) {
+		t.Errorf("expected the same mapper from JSON and TOML:\n%v\n%v", fromJSON, fromTOML)
+	}
+	if len(fromTOML.javaToBedrockState) != 4 || len(fromTOML.waterloggedBlocks) != 1 {
+		t.Errorf("expected 4 states of which 1 is waterlogged, got %v and %v", len(fromTOML.javaToBedrockState), len(fromTOML.waterloggedBlocks))
+	}
+
+	for _, data := range []string{`["testmod:crate"`, `"testmod:crate[open]" = { bedrock_identifier = "minecraft:barrel" }`} {
+		if err := NewMapper(nil).LoadTOML(strings.NewReader(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+	for _, data := range []string{`{`, `{"testmod:crate[open]": {"bedrock_identifier": "minecraft:barrel"}}`} {
+		if err := NewMapper(nil).LoadJSON(strings.NewReader(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
@@ -15,6 +15,25 @@ type Block struct {
 	Properties map[string]any `json:"bedrock_states" nbt:"Properties,omitempty"`
 }
 
+// String returns the block in the compressed Java format, such as "minecraft:oak_log[axis=y]". Properties are
+// sorted by their keys.
+func (b Block) String() string {
+	if len(b.Properties) == 0 {
+		return b.Name
+	}
+	keys := make([]string, 0, len(b.Properties))
+	for k := range b.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	properties := make([]string, 0, len(keys))
+	for _, k := range keys {
+		properties = append(properties, fmt.Sprintf("%v=%v", k, b.Properties[k]))
+	}
+	return b.Name + "[" + strings.Join(properties, ",") + "]"
+}
+
 // blockHash is a hash of a Block, to be used in map keys.
 type blockHash struct {
 	name, properties string
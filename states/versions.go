@@ -0,0 +1,36 @@
+package states
+
+import "github.com/justtaldevelops/mcanvil/internal/versions"
+
+const (
+	// JavaDataVersion is the Java data version of the block mappings, which is that of Minecraft 1.19. Chunks of
+	// older Java versions are upgraded to the current block names by the upgrade package when they are read, so
+	// the same mappings are used to convert them. Mappings are not registered per Java version.
+	JavaDataVersion = 3105
+	// BedrockProtocol is the Bedrock protocol version targeted by the default block mappings, which is that of
+	// Minecraft 1.19.0.
+	BedrockProtocol = 527
+)
+
+// mappers holds all mappers registered using RegisterMapper.
+var mappers versions.Registry[*Mapper]
+
+func init() {
+	RegisterMapper(BedrockProtocol, defaultMapper)
+}
+
+// RegisterMapper registers a mapper for converting Java block states to the Bedrock protocol version passed. A
+// mapper registered earlier for the same version is replaced.
+func RegisterMapper(protocol int32, m *Mapper) {
+	mappers.Register(protocol, m)
+}
+
+// MapperFor returns the registered mapper best suited for converting Java block states to the Bedrock protocol
+// version passed: the one registered for the newest protocol version not newer than the one passed, or the
+// oldest one registered if none is old enough.
+func MapperFor(protocol int32) *Mapper {
+	if m, ok := mappers.Lookup(protocol); ok {
+		return m
+	}
+	return defaultMapper
+}
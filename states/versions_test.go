@@ -0,0 +1,28 @@
+package states
+
+import "testing"
+
+func TestMapperFor(t *testing.T) {
+	for _, protocol := range []int32{475, BedrockProtocol, 560} {
+		if MapperFor(protocol) != DefaultMapper() {
+			t.Errorf("protocol %v: expected the default mapper", protocol)
+		}
+	}
+
+	// Blocks renamed in 1.17 are converted from their current names, which are the ones upgraded chunks hold.
+	m := MapperFor(BedrockProtocol)
+	for java, bedrock := range map[string]Block{
+		"minecraft:dirt_path":               {Name: "minecraft:grass_path"},
+		"minecraft:water_cauldron[level=2]": {Name: "minecraft:cauldron", Properties: map[string]any{"cauldron_liquid": "water", "fill_level": int32(4)}},
+	} {
+		state, err := parseJavaCompressedBlock(java)
+		if err != nil {
+			t.Fatal(err)
+		}
+		converted, _, ok := m.ConvertToBedrock(state)
+		if !ok || hashBlock(converted) != hashBlock(bedrock) {
+			t.Errorf("%v: expected %v, got %v (ok: %v)", java, bedrock, converted, ok)
+		}
+	}
+
+}
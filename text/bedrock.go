@@ -0,0 +1,330 @@
+package text
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ClickPolicy specifies how click events, which Bedrock text does not support, are converted.
+type ClickPolicy int
+
+const (
+	// ClickDrop drops click events, leaving only the text of the component.
+	ClickDrop ClickPolicy = iota
+	// ClickAppend appends the value of the click event, such as the URL or command, in brackets after the text
+	// of the component.
+	ClickAppend
+)
+
+// FontPolicy specifies how text in fonts other than the default font, which Bedrock does not have, is converted.
+type FontPolicy int
+
+const (
+	// FontKeep keeps the text, rendering it in the default font.
+	FontKeep FontPolicy = iota
+	// FontDrop drops the text, which is useful for fonts like the enchanting table font whose text is not meant
+	// to be read.
+	FontDrop
+)
+
+// Options holds the settings used when converting text components to Bedrock formatted text. The zero value is
+// valid and uses the default settings.
+type Options struct {
+	// ClickEvents is the policy applied to click events.
+	ClickEvents ClickPolicy
+	// Fonts is the policy applied to text in fonts other than the default font.
+	Fonts FontPolicy
+	// Translate returns the translated format string for a translation key, such as "Hello %s". If nil, or if
+	// it returns false, the fallback of the component is used, or the key itself if there is none.
+	Translate func(key string) (string, bool)
+}
+
+// ToBedrock converts a Java JSON text component to Bedrock formatted text. Strings that are not valid JSON are
+// treated as literal text, as done by older versions.
+func ToBedrock(data string, opts Options) string {
+	c, err := Parse(data)
+	if err != nil {
+		return data
+	}
+	return c.Bedrock(opts)
+}
+
+// ToJava converts Bedrock formatted text to a Java JSON text component.
+func ToJava(s string) string {
+	return FromBedrock(s).JSON()
+}
+
+// style is the resolved style of a component, inherited by its children.
+type style struct {
+	color                    byte
+	bold, italic, obfuscated bool
+	font                     string
+}
+
+// Bedrock renders the component as Bedrock text using formatting codes. Bedrock has no underlined or
+// strikethrough text, so those styles are dropped, and hex colors are replaced with the nearest named color.
+func (c Component) Bedrock(opts Options) string {
+	r := &renderer{opts: opts}
+	r.render(c, style{})
+	return r.b.String()
+}
+
+// renderer renders components to Bedrock text, tracking the style written last.
+type renderer struct {
+	opts    Options
+	b       strings.Builder
+	current style
+}
+
+// render renders the component passed and its children using the style inherited from its parent.
+func (r *renderer) render(c Component, parent style) {
+	s := parent
+	if c.Color != "" {
+		if code, ok := colorCode(c.Color); ok {
+			s.color = code
+		}
+	}
+	if c.Bold != nil {
+		s.bold = *c.Bold
+	}
+	if c.Italic != nil {
+		s.italic = *c.Italic
+	}
+	if c.Obfuscated != nil {
+		s.obfuscated = *c.Obfuscated
+	}
+	if c.Font != "" {
+		s.font = c.Font
+	}
+
+	if s.font == "" || s.font == "minecraft:default" || r.opts.Fonts == FontKeep {
+		r.writeContent(c, s)
+		if c.ClickEvent != nil && r.opts.ClickEvents == ClickAppend && c.ClickEvent.Value != "" {
+			r.write(" ("+c.ClickEvent.Value+")", s)
+		}
+	}
+	for _, child := range c.Extra {
+		r.render(child, s)
+	}
+}
+
+// writeContent writes the text displayed by the component itself, excluding its children, in the style passed.
+// The arguments of a translation are rendered in between the text of the format string, inheriting the style.
+func (r *renderer) writeContent(c Component, s style) {
+	format, ok := r.translation(c)
+	if !ok {
+		r.write(r.content(c), s)
+		return
+	}
+	formatTranslation(format, len(c.With), func(text string) {
+		r.write(text, s)
+	}, func(i int) {
+		r.render(c.With[i], s)
+	})
+}
+
+// content returns the plain text displayed by the component itself, excluding its children.
+func (r *renderer) content(c Component) string {
+	if format, ok := r.translation(c); ok {
+		var b strings.Builder
+		formatTranslation(format, len(c.With), func(text string) {
+			b.WriteString(text)
+		}, func(i int) {
+			b.WriteString(c.With[i].String())
+		})
+		return b.String()
+	}
+	switch {
+	case c.Translate != "":
+		return c.Fallback
+	case c.Score != nil:
+		return c.Score.Value
+	case c.Selector != "":
+		return c.Selector
+	case c.Keybind != "":
+		return c.Keybind
+	}
+	return c.Text
+}
+
+// translation returns the format string of a translatable component. If the component is not translatable, or if
+// the translation is unknown and the component has a fallback, false is returned.
+func (r *renderer) translation(c Component) (string, bool) {
+	if c.Translate == "" {
+		return "", false
+	}
+	if r.opts.Translate != nil {
+		if format, ok := r.opts.Translate(c.Translate); ok {
+			return format, true
+		}
+	}
+	if c.Fallback != "" {
+		return "", false
+	}
+	return c.Translate, true
+}
+
+// write writes text in the style passed, writing formatting codes if the style differs from the current style.
+func (r *renderer) write(text string, s style) {
+	if text == "" {
+		return
+	}
+	if s.color != r.current.color || s.bold != r.current.bold || s.italic != r.current.italic || s.obfuscated != r.current.obfuscated {
+		if r.b.Len() > 0 {
+			r.b.WriteString("§r")
+		}
+		if s.color != 0 {
+			r.b.WriteString("§" + string(s.color))
+		}
+		if s.bold {
+			r.b.WriteString("§l")
+		}
+		if s.italic {
+			r.b.WriteString("§o")
+		}
+		if s.obfuscated {
+			r.b.WriteString("§k")
+		}
+		r.current = s
+	}
+	r.b.WriteString(text)
+}
+
+// translationArgExp matches the arguments of a translation format string, such as %s and %1$s.
+var translationArgExp = regexp.MustCompile(`%(?:(\d+)\$)?([s%])`)
+
+// formatTranslation formats a translation format string with n arguments, calling text for the literal text in
+// between the arguments, and arg for every argument with its index.
+func formatTranslation(format string, n int, text func(string), arg func(int)) {
+	next, last := 0, 0
+	for _, m := range translationArgExp.FindAllStringSubmatchIndex(format, -1) {
+		text(format[last:m[0]])
+		last = m[1]
+		if format[m[4]:m[5]] == "%" {
+			text("%")
+			continue
+		}
+		i := next
+		if m[2] != -1 {
+			index, _ := strconv.Atoi(format[m[2]:m[3]])
+			i = index - 1
+		} else {
+			next++
+		}
+		if i >= 0 && i < n {
+			arg(i)
+		}
+	}
+	text(format[last:])
+}
+
+// namedColors holds the formatting codes and RGB values of the named Java colors.
+var namedColors = []struct {
+	name string
+	code byte
+	rgb  int32
+}{
+	{"black", '0', 0x000000},
+	{"dark_blue", '1', 0x0000aa},
+	{"dark_green", '2', 0x00aa00},
+	{"dark_aqua", '3', 0x00aaaa},
+	{"dark_red", '4', 0xaa0000},
+	{"dark_purple", '5', 0xaa00aa},
+	{"gold", '6', 0xffaa00},
+	{"gray", '7', 0xaaaaaa},
+	{"dark_gray", '8', 0x555555},
+	{"blue", '9', 0x5555ff},
+	{"green", 'a', 0x55ff55},
+	{"aqua", 'b', 0x55ffff},
+	{"red", 'c', 0xff5555},
+	{"light_purple", 'd', 0xff55ff},
+	{"yellow", 'e', 0xffff55},
+	{"white", 'f', 0xffffff},
+}
+
+// colorCode returns the formatting code of a named or hex color. Hex colors use the nearest named color.
+func colorCode(color string) (byte, bool) {
+	for _, c := range namedColors {
+		if c.name == color {
+			return c.code, true
+		}
+	}
+	if !strings.HasPrefix(color, "#") || len(color) != 7 {
+		return 0, false
+	}
+	rgb, err := strconv.ParseInt(color[1:], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	var code byte
+	best := int32(-1)
+	for _, c := range namedColors {
+		dr, dg, db := (c.rgb>>16)-int32(rgb>>16), (c.rgb>>8)&0xff-int32(rgb>>8)&0xff, c.rgb&0xff-int32(rgb)&0xff
+		if d := dr*dr + dg*dg + db*db; best == -1 || d < best {
+			code, best = c.code, d
+		}
+	}
+	return code, true
+}
+
+// FromBedrock parses Bedrock formatted text into a text component. Every run of text with the same formatting
+// becomes a child component.
+func FromBedrock(s string) Component {
+	var (
+		c       Component
+		current Component
+		b       strings.Builder
+	)
+	flush := func() {
+		if b.Len() > 0 {
+			current.Text = b.String()
+			c.Extra = append(c.Extra, current)
+			b.Reset()
+		}
+	}
+	t := true
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '§' || i+1 == len(runes) {
+			b.WriteRune(runes[i])
+			continue
+		}
+		flush()
+		i++
+		switch code := runes[i]; code {
+		case 'l':
+			current.Bold = &t
+		case 'o':
+			current.Italic = &t
+		case 'k':
+			current.Obfuscated = &t
+		case 'r':
+			current = Component{}
+		default:
+			for _, color := range namedColors {
+				if rune(color.code) == code {
+					current = Component{Color: color.name}
+				}
+			}
+		}
+	}
+	flush()
+	if len(c.Extra) == 1 {
+		return c.Extra[0]
+	}
+	if len(c.Extra) == 0 {
+		return Component{Text: ""}
+	}
+	return c
+}
+
+// String returns the plain text of the component and its children, without any formatting.
+func (c Component) String() string {
+	var b strings.Builder
+	b.WriteString((&renderer{}).content(c))
+	for _, child := range c.Extra {
+		b.WriteString(child.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToBedrock(t *testing.T) {
+	t.Parallel()
+	translations := map[string]string{
+		"multiplayer.player.joined": "%s joined the game",
+		"chat.type.text":            "<%s> %s",
+		"commands.swap":             "%2$s and %1$s, 100%%",
+	}
+	opts := Options{Translate: func(key string) (string, bool) {
+		format, ok := translations[key]
+		return format, ok
+	}}
+	for _, test := range []struct {
+		data     string
+		opts     Options
+		expected string
+	}{
+		{`"Hello"`, opts, "Hello"},
+		{`not json`, opts, "not json"},
+		{`{"text": "A", "color": "red", "extra": [{"text": "B", "bold": true}, "C"]}`, opts, "§cA§r§c§lB§r§cC"},
+		{`["A", {"text": "B", "color": "#ff5556"}]`, opts, "A§r§cB"},
+		{`{"text": "A", "underlined": true, "strikethrough": true}`, opts, "A"},
+		{`{"translate": "multiplayer.player.joined", "with": [{"text": "Bob", "color": "red"}]}`, opts, "§cBob§r joined the game"},
+		{`[{"translate": "multiplayer.player.joined", "with": [{"text": "Bob", "color": "red"}]}, " Hi"]`, opts, "§cBob§r joined the game Hi"},
+		{`{"translate": "multiplayer.player.joined", "color": "yellow", "with": ["Bob"]}`, opts, "§eBob joined the game"},
+		{`{"translate": "multiplayer.player.joined", "color": "yellow", "with": [{"text": "Bob", "italic": true}]}`, opts, "§e§oBob§r§e joined the game"},
+		{`{"translate": "chat.type.text", "with": ["Bob", {"translate": "multiplayer.player.joined", "with": ["Al"]}]}`, opts, "<Bob> Al joined the game"},
+		{`{"translate": "commands.swap", "with": ["A", "B"]}`, opts, "B and A, 100%"},
+		{`{"translate": "unknown.key", "fallback": "Fallback"}`, opts, "Fallback"},
+		{`{"translate": "unknown %s", "with": ["key"]}`, Options{}, "unknown key"},
+		{`{"text": "Click", "clickEvent": {"action": "open_url", "value": "https://example.com"}}`, opts, "Click"},
+		{`{"text": "Click", "clickEvent": {"action": "open_url", "value": "https://example.com"}}`, Options{ClickEvents: ClickAppend}, "Click (https://example.com)"},
+		{`["A", {"text": "B", "font": "minecraft:alt"}]`, opts, "AB"},
+		{`["A", {"text": "B", "font": "minecraft:alt"}]`, Options{Fonts: FontDrop}, "A"},
+		{`{"score": {"name": "@p", "objective": "kills", "value": "3"}}`, opts, "3"},
+	} {
+		if text := ToBedrock(test.data, test.opts); text != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.data, test.expected, text)
+		}
+	}
+}
+
+func TestComponentString(t *testing.T) {
+	t.Parallel()
+	c, err := Parse(`{"translate": "%s joined", "color": "red", "with": [{"text": "Bob", "bold": true}], "extra": ["!"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.String(); s != "Bob joined!" {
+		t.Errorf("expected %q, got %q", "Bob joined!", s)
+	}
+}
+
+func TestFromBedrock(t *testing.T) {
+	t.Parallel()
+	yes := true
+	for s, expected := range map[string]Component{
+		"":              {},
+		"Hello":         {Text: "Hello"},
+		"§cRed":         {Text: "Red", Color: "red"},
+		"A§lB§rC":       {Extra: []Component{{Text: "A"}, {Text: "B", Bold: &yes}, {Text: "C"}}},
+		"§9§oBlue§":     {Text: "Blue§", Color: "blue", Italic: &yes},
+		"§kSecret§r!":   {Extra: []Component{{Text: "Secret", Obfuscated: &yes}, {Text: "!"}}},
+		"§l§6Gold text": {Text: "Gold text", Color: "gold"},
+	} {
+		if c := FromBedrock(s); !reflect.DeepEqual(c, expected) {
+			t.Errorf("%q: expected %#v, got %#v", s, expected, c)
+		}
+	}
+	if data := ToJava("§cA§rB"); data != `{"text":"","extra":[{"text":"A","color":"red"},{"text":"B"}]}` {
+		t.Errorf("unexpected json %v", data)
+	}
+}
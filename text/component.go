@@ -0,0 +1,202 @@
+package text
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Component is a Java text component, as stored in JSON or NBT in signs, item names, books and more.
+type Component struct {
+	// Text is the literal text of the component.
+	Text string `json:"text,omitempty"`
+	// Translate is a translation key. The translated text is formatted using the components in With.
+	Translate string      `json:"translate,omitempty"`
+	With      []Component `json:"with,omitempty"`
+	// Fallback is the text used if the translation key is unknown.
+	Fallback string `json:"fallback,omitempty"`
+	// Score is a scoreboard score displayed by the component.
+	Score *Score `json:"score,omitempty"`
+	// Selector is an entity selector, resolved to the names of the entities it selects by the game.
+	Selector string `json:"selector,omitempty"`
+	// Keybind is the name of a key binding, displayed as the key bound to it.
+	Keybind string `json:"keybind,omitempty"`
+
+	// Color is a named color, such as "dark_red", or a hex color in the format "#RRGGBB".
+	Color         string `json:"color,omitempty"`
+	Bold          *bool  `json:"bold,omitempty"`
+	Italic        *bool  `json:"italic,omitempty"`
+	Underlined    *bool  `json:"underlined,omitempty"`
+	Strikethrough *bool  `json:"strikethrough,omitempty"`
+	Obfuscated    *bool  `json:"obfuscated,omitempty"`
+	// Font is the resource location of the font the component is rendered in.
+	Font string `json:"font,omitempty"`
+
+	// ClickEvent is the action performed when the component is clicked.
+	ClickEvent *ClickEvent `json:"clickEvent,omitempty"`
+	// HoverEvent is kept as it is, as Bedrock has no equivalent.
+	HoverEvent any `json:"hoverEvent,omitempty"`
+	// Insertion is the text inserted into the chat when the component is shift-clicked.
+	Insertion string `json:"insertion,omitempty"`
+
+	// Extra holds the child components, which inherit the style of the component.
+	Extra []Component `json:"extra,omitempty"`
+}
+
+// Score is the content of a score component.
+type Score struct {
+	// Name is the name of the score holder, or a selector.
+	Name string `json:"name"`
+	// Objective is the name of the scoreboard objective.
+	Objective string `json:"objective"`
+	// Value is the resolved value of the score, if it was stored along with the component.
+	Value string `json:"value,omitempty"`
+}
+
+// ClickEvent is the action performed when a component is clicked.
+type ClickEvent struct {
+	// Action is the action performed, such as "open_url" or "run_command".
+	Action string `json:"action"`
+	// Value is the URL, command or other value used by the action.
+	Value string `json:"value"`
+}
+
+// Parse parses a Java JSON text component. Besides objects, the JSON may be a string, which is a literal text
+// component, or an array, of which the first component is the parent of the other components.
+func Parse(data string) (Component, error) {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return Component{}, fmt.Errorf("invalid text component: %w", err)
+	}
+	return decode(v)
+}
+
+// FromNBT parses a Java text component stored as NBT, as done since 1.20.3. The NBT may be a string, list or
+// compound, like the JSON accepted by Parse.
+func FromNBT(v any) (Component, error) {
+	return decode(v)
+}
+
+// JSON encodes the component as Java JSON text component.
+func (c Component) JSON() string {
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// MarshalJSON encodes the component as JSON. Java requires components to have content, so components without
+// any get an empty text.
+func (c Component) MarshalJSON() ([]byte, error) {
+	type component Component
+	data, err := json.Marshal(component(c))
+	if err != nil || c.Text != "" || c.Translate != "" || c.Score != nil || c.Selector != "" || c.Keybind != "" {
+		return data, err
+	}
+	if len(data) == 2 {
+		return []byte(`{"text":""}`), nil
+	}
+	return append([]byte(`{"text":"",`), data[1:]...), nil
+}
+
+// decode decodes a text component from a decoded JSON or NBT value.
+func decode(v any) (Component, error) {
+	switch v := v.(type) {
+	case string:
+		return Component{Text: v}, nil
+	case []any:
+		if len(v) == 0 {
+			return Component{}, fmt.Errorf("empty text component list")
+		}
+		components := make([]Component, 0, len(v))
+		for _, e := range v {
+			c, err := decode(e)
+			if err != nil {
+				return Component{}, err
+			}
+			components = append(components, c)
+		}
+		parent := components[0]
+		parent.Extra = append(parent.Extra, components[1:]...)
+		return parent, nil
+	case map[string]any:
+		return decodeCompound(v)
+	case float64, int32, int64, uint8, int16, bool:
+		return Component{Text: fmt.Sprint(v)}, nil
+	}
+	return Component{}, fmt.Errorf("invalid text component type %T", v)
+}
+
+// decodeCompound decodes a text component from a JSON object or NBT compound.
+func decodeCompound(m map[string]any) (Component, error) {
+	var c Component
+	if text, ok := m["text"]; ok {
+		c.Text = fmt.Sprint(text)
+	} else if text, ok := m[""]; ok {
+		// NBT components may store their text with an empty key.
+		c.Text = fmt.Sprint(text)
+	}
+	c.Translate, _ = m["translate"].(string)
+	c.Fallback, _ = m["fallback"].(string)
+	c.Selector, _ = m["selector"].(string)
+	c.Keybind, _ = m["keybind"].(string)
+	c.Color, _ = m["color"].(string)
+	c.Font, _ = m["font"].(string)
+	c.Insertion, _ = m["insertion"].(string)
+	c.HoverEvent = m["hoverEvent"]
+	c.Bold, c.Italic, c.Underlined = flag(m["bold"]), flag(m["italic"]), flag(m["underlined"])
+	c.Strikethrough, c.Obfuscated = flag(m["strikethrough"]), flag(m["obfuscated"])
+
+	if score, ok := m["score"].(map[string]any); ok {
+		c.Score = &Score{}
+		c.Score.Name, _ = score["name"].(string)
+		c.Score.Objective, _ = score["objective"].(string)
+		if value, ok := score["value"]; ok {
+			c.Score.Value = fmt.Sprint(value)
+		}
+	}
+	if click, ok := m["clickEvent"].(map[string]any); ok {
+		c.ClickEvent = &ClickEvent{}
+		c.ClickEvent.Action, _ = click["action"].(string)
+		c.ClickEvent.Value = fmt.Sprint(click["value"])
+	}
+
+	var err error
+	if c.With, err = decodeList(m["with"]); err != nil {
+		return Component{}, err
+	}
+	if c.Extra, err = decodeList(m["extra"]); err != nil {
+		return Component{}, err
+	}
+	return c, nil
+}
+
+// decodeList decodes a list of text components, if the value passed is a list.
+func decodeList(v any) ([]Component, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, nil
+	}
+	components := make([]Component, 0, len(list))
+	for _, e := range list {
+		c, err := decode(e)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+// flag decodes a style flag, which is a boolean in JSON and a byte in NBT.
+func flag(v any) *bool {
+	var b bool
+	switch v := v.(type) {
+	case bool:
+		b = v
+	case uint8:
+		b = v != 0
+	case int8:
+		b = v != 0
+	default:
+		return nil
+	}
+	return &b
+}
@@ -0,0 +1,68 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	yes, no := true, false
+	for data, expected := range map[string]Component{
+		`"Hello"`:                                      {Text: "Hello"},
+		`["A", {"text": "B", "color": "red"}]`:         {Text: "A", Extra: []Component{{Text: "B", Color: "red"}}},
+		`{"text": "A", "bold": true, "italic": false}`: {Text: "A", Bold: &yes, Italic: &no},
+		`{"translate": "chat.type.text", "with": ["Bob", {"text": "hi"}], "fallback": "<%s> %s"}`: {
+			Translate: "chat.type.text", With: []Component{{Text: "Bob"}, {Text: "hi"}}, Fallback: "<%s> %s",
+		},
+		`{"score": {"name": "@p", "objective": "kills", "value": 3}}`: {Score: &Score{Name: "@p", Objective: "kills", Value: "3"}},
+		`{"text": "", "clickEvent": {"action": "open_url", "value": "https://example.com"}}`: {
+			ClickEvent: &ClickEvent{Action: "open_url", Value: "https://example.com"},
+		},
+		`{"selector": "@a", "keybind": "key.jump"}`: {Selector: "@a", Keybind: "key.jump"},
+		`12`: {Text: "12"},
+	} {
+		c, err := Parse(data)
+		if err != nil {
+			t.Errorf("%v: %v", data, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, expected) {
+			t.Errorf("%v: expected %#v, got %#v", data, expected, c)
+		}
+	}
+	for _, data := range []string{`[]`, `{"text": "A", "extra": [null]}`, `{`} {
+		if c, err := Parse(data); err == nil {
+			t.Errorf("%v: expected an error, got %#v", data, c)
+		}
+	}
+}
+
+func TestFromNBT(t *testing.T) {
+	t.Parallel()
+	c, err := FromNBT(map[string]any{"": "A", "bold": uint8(1), "extra": []any{"B", int32(3)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	yes := true
+	if expected := (Component{Text: "A", Bold: &yes, Extra: []Component{{Text: "B"}, {Text: "3"}}}); !reflect.DeepEqual(c, expected) {
+		t.Errorf("expected %#v, got %#v", expected, c)
+	}
+}
+
+func TestComponentJSON(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		c        Component
+		expected string
+	}{
+		{Component{Text: "A"}, `{"text":"A"}`},
+		{Component{}, `{"text":""}`},
+		{Component{Color: "red", Extra: []Component{{Text: "A"}}}, `{"text":"","color":"red","extra":[{"text":"A"}]}`},
+		{Component{Translate: "a.b"}, `{"translate":"a.b"}`},
+	} {
+		if data := test.c.JSON(); data != test.expected {
+			t.Errorf("expected %v, got %v", test.expected, data)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package upgrade
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/column"
+	"math/bits"
+)
+
+func init() {
+	Register(Fix{DataVersion: 704, Name: "block entity ids", Apply: fixBlockEntityIDs})
+	Register(Fix{DataVersion: 1451, Name: "flattening", Apply: fixFlattening})
+	Register(Fix{DataVersion: column.PaddedDataVersion, Name: "1.16 block state packing", Apply: fixBlockStatePacking})
+	Register(Fix{DataVersion: 2681, Name: "1.17 block renames", Apply: fixBlockRenames})
+	Register(Fix{DataVersion: 2838, Name: "1.18 biome renames", Apply: fixBiomeRenames})
+	Register(Fix{DataVersion: 2844, Name: "1.18 chunk format", Apply: fixChunkFormat})
+}
+
+// fixBlockEntityIDs renames the block entity IDs used before Minecraft 1.11 to their namespaced IDs.
+func fixBlockEntityIDs(data map[string]any) error {
+	for _, blockEntity := range compounds(compound(data, "Level"), "TileEntities") {
+		id, _ := blockEntity["id"].(string)
+		if renamed, ok := blockEntityRenames[id]; ok {
+			blockEntity["id"] = renamed
+		}
+	}
+	return nil
+}
+
+// fixFlattening fails with ErrUnsupported for chunks that still hold numeric block IDs from before Minecraft 1.13,
+// as flattening them is not supported. Chunks without any block data, such as empty chunks, are left as they are.
+func fixFlattening(data map[string]any) error {
+	for _, section := range compounds(compound(data, "Level"), "Sections") {
+		if _, ok := section["Blocks"]; ok {
+			return fmt.Errorf("%w: chunk holds numeric block ids, which cannot be flattened", ErrUnsupported)
+		}
+	}
+	return nil
+}
+
+// fixBlockStatePacking repacks the block states and heightmaps of chunks saved before Minecraft 1.16, which pack
+// entries tightly so that they may span two longs, into the padded format used since.
+func fixBlockStatePacking(data map[string]any) error {
+	level := compound(data, "Level")
+	for _, section := range compounds(level, "Sections") {
+		longs := integers(section["BlockStates"])
+		palette := list(section, "Palette")
+		if len(longs) == 0 || len(palette) == 0 {
+			continue
+		}
+		// Sections always use at least 4 bits per entry.
+		padded, err := repack(longs, int32(maxInt(bits.Len(uint(len(palette)-1)), 4)), 4096)
+		if err != nil {
+			return fmt.Errorf("section %v: %w", sectionY(section), err)
+		}
+		section["BlockStates"] = longArray(padded)
+	}
+	heightmaps := compound(level, "Heightmaps")
+	for name, v := range heightmaps {
+		// Heightmaps hold a height from 0 to 256 for every column, which takes 9 bits.
+		padded, err := repack(integers(v), 9, 256)
+		if err != nil {
+			// Heightmaps that cannot be read are dropped, so that the game calculates them again.
+			delete(heightmaps, name)
+			continue
+		}
+		heightmaps[name] = longArray(padded)
+	}
+	return nil
+}
+
+// repack decodes the longs passed, which pack size entries of the number of bits passed such that they may span two
+// longs, and encodes them again padded so that no entry spans two longs.
+func repack(longs []int64, bitsPerEntry, size int32) ([]int64, error) {
+	spanning, err := column.NewFilledSpanningBitStorage(bitsPerEntry, size, longs)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int32, size)
+	if err := spanning.DecodeAll(values); err != nil {
+		return nil, err
+	}
+	padded := column.NewEmptyBitStorage(bitsPerEntry, size)
+	if err := padded.EncodeAll(values); err != nil {
+		return nil, err
+	}
+	return padded.Data(), nil
+}
+
+// fixBlockRenames applies the block changes of Minecraft 1.17: grass paths were renamed to dirt paths, and
+// cauldrons holding water became water cauldrons.
+func fixBlockRenames(data map[string]any) error {
+	for _, state := range blockStates(data) {
+		switch state["Name"] {
+		case "minecraft:grass_path":
+			state["Name"] = "minecraft:dirt_path"
+		case "minecraft:cauldron":
+			properties := compound(state, "Properties")
+			if level, ok := properties["level"]; ok && level != "0" {
+				state["Name"] = "minecraft:water_cauldron"
+				break
+			}
+			delete(state, "Properties")
+		}
+	}
+	return nil
+}
+
+// fixBiomeRenames renames the biomes that were renamed or merged in Minecraft 1.18 in chunks that already use
+// biome palettes.
+func fixBiomeRenames(data map[string]any) error {
+	for _, section := range compounds(data, "sections") {
+		palette := list(compound(section, "biomes"), "palette")
+		for i, name := range palette {
+			if renamed, ok := biomeRenames[fmt.Sprint(name)]; ok {
+				palette[i] = renamed
+			}
+		}
+	}
+	return nil
+}
+
+// fixChunkFormat moves the chunk data out of the "Level" compound into the root, as done in Minecraft 1.18.
+// Sections get block state and biome palettes, and the chunk-wide biome array is split up over the sections.
+func fixChunkFormat(data map[string]any) error {
+	level := compound(data, "Level")
+	if level == nil {
+		return nil
+	}
+	delete(data, "Level")
+
+	legacyBiomes := integers(level["Biomes"])
+	delete(level, "Biomes")
+	legacySections := compounds(level, "Sections")
+	delete(level, "Sections")
+	for _, key := range []string{"ToBeTicked", "LiquidsToBeTicked"} {
+		// These tags only exist in proto chunks, and have no equivalent anymore.
+		delete(level, key)
+	}
+
+	rename(level, "TileEntities", "block_entities")
+	rename(level, "TileTicks", "block_ticks")
+	rename(level, "LiquidTicks", "fluid_ticks")
+	rename(level, "Entities", "entities")
+	rename(level, "Structures", "structures")
+	rename(compound(level, "structures"), "Starts", "starts")
+	if status, ok := level["Status"].(string); ok {
+		if renamed, ok := statusRenames[status]; ok {
+			level["Status"] = renamed
+		}
+	}
+	for k, v := range level {
+		data[k] = v
+	}
+	// Chunks from before 1.18 always span sections 0 to 15.
+	data["yPos"] = int32(0)
+
+	sections := make([]any, 0, len(legacySections)+16)
+	present := make(map[int8]bool)
+	for _, section := range legacySections {
+		y := sectionY(section)
+		present[y] = true
+
+		blockStates := map[string]any{"palette": []any{map[string]any{"Name": "minecraft:air"}}}
+		if palette, ok := section["Palette"]; ok {
+			blockStates["palette"] = palette
+			// Before 1.18, sections holding a single state still stored 4 bits per block. Since then, such sections
+			// are saved without data, as vanilla does.
+			if longs := integers(section["BlockStates"]); len(longs) > 0 && len(list(section, "Palette")) > 1 {
+				blockStates["data"] = longArray(longs)
+			}
+		}
+		delete(section, "Palette")
+		delete(section, "BlockStates")
+		section["block_states"] = blockStates
+
+		biomes, err := sectionBiomes(legacyBiomes, y)
+		if err != nil {
+			return err
+		}
+		section["biomes"] = biomes
+		sections = append(sections, section)
+	}
+	for y := int8(0); y < 16; y++ {
+		if present[y] {
+			continue
+		}
+		biomes, err := sectionBiomes(legacyBiomes, y)
+		if err != nil {
+			return err
+		}
+		sections = append(sections, map[string]any{
+			"Y":            uint8(y),
+			"block_states": map[string]any{"palette": []any{map[string]any{"Name": "minecraft:air"}}},
+			"biomes":       biomes,
+		})
+	}
+	data["sections"] = sections
+	return nil
+}
+
+// sectionBiomes builds the biome palette of the section at the Y passed from the numeric biome array used before
+// Minecraft 1.18. The array either holds a 4x4x4 cell for each biome, as done since 1.15, or one biome per
+// column. Sections outside the cells of the array use the nearest layer of cells.
+func sectionBiomes(legacyBiomes []int64, y int8) (map[string]any, error) {
+	cells := make([]string, 64)
+	for i := range cells {
+		cellX, cellY, cellZ := i&3, i>>4, (i>>2)&3
+
+		var id int64
+		switch len(legacyBiomes) {
+		case 0:
+			id = 1 // Chunks without biomes use plains.
+		case 256:
+			id = legacyBiomes[(cellZ<<2)<<4|cellX<<2]
+		case 1024:
+			layer := minInt(maxInt(int(y)<<2+cellY, 0), 63)
+			id = legacyBiomes[layer<<4|cellZ<<2|cellX]
+		default:
+			return nil, fmt.Errorf("invalid biome array length %v", len(legacyBiomes))
+		}
+		name, ok := legacyBiomeIDs[int32(id)]
+		if !ok {
+			name = "minecraft:plains"
+		}
+		if renamed, ok := biomeRenames[name]; ok {
+			name = renamed
+		}
+		cells[i] = name
+	}
+
+	var palette []any
+	indices := make(map[string]int32)
+	for _, name := range cells {
+		if _, ok := indices[name]; !ok {
+			indices[name] = int32(len(palette))
+			palette = append(palette, name)
+		}
+	}
+	biomes := map[string]any{"palette": palette}
+	if len(palette) == 1 {
+		return biomes, nil
+	}
+	storage := column.NewEmptyBitStorage(int32(bits.Len(uint(len(palette)-1))), 64)
+	for i, name := range cells {
+		if err := storage.Set(int32(i), indices[name]); err != nil {
+			return nil, err
+		}
+	}
+	biomes["data"] = longArray(storage.Data())
+	return biomes, nil
+}
+
+// blockStates returns all block state compounds in the palettes of the sections of the chunk passed, in both
+// the chunk format from before and after Minecraft 1.18.
+func blockStates(data map[string]any) []map[string]any {
+	var states []map[string]any
+	for _, section := range compounds(compound(data, "Level"), "Sections") {
+		states = append(states, compounds(section, "Palette")...)
+	}
+	for _, section := range compounds(data, "sections") {
+		states = append(states, compounds(compound(section, "block_states"), "palette")...)
+	}
+	return states
+}
+
+// sectionY returns the signed Y of the section compound passed.
+func sectionY(section map[string]any) int8 {
+	switch y := section["Y"].(type) {
+	case uint8:
+		return int8(y)
+	case int8:
+		return y
+	case int32:
+		return int8(y)
+	}
+	return 0
+}
+
+// minInt returns the smallest of two ints.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the largest of two ints.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
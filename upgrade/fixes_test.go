@@ -0,0 +1,329 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"github.com/justtaldevelops/mcanvil/column"
+	"math/bits"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkLegacy(t *testing.T) {
+	palette := []any{
+		map[string]any{"Name": "minecraft:air"},
+		map[string]any{"Name": "minecraft:grass_path"},
+		map[string]any{"Name": "minecraft:cauldron", "Properties": map[string]any{"level": "0"}},
+		map[string]any{"Name": "minecraft:cauldron", "Properties": map[string]any{"level": "2"}},
+	}
+	for len(palette) < 17 {
+		// Palettes of more than 16 states need 5 bits per entry, so that entries span two longs.
+		palette = append(palette, map[string]any{"Name": fmt.Sprintf("minecraft:filler_%v", len(palette))})
+	}
+	values := make([]int32, 4096)
+	for i := range values {
+		values[i] = int32(i % len(palette))
+	}
+	spanning := column.NewEmptySpanningBitStorage(5, 4096)
+	if err := spanning.EncodeAll(values); err != nil {
+		t.Fatal(err)
+	}
+	var legacyBiomes [1024]int32
+	for i := range legacyBiomes {
+		// Mountains along the west edge of the chunk, and plains elsewhere.
+		legacyBiomes[i] = 1
+		if i&3 == 0 {
+			legacyBiomes[i] = 3
+		}
+	}
+	heights := make([]int32, 256)
+	for i := range heights {
+		heights[i] = int32(i)
+	}
+	heightmap := column.NewEmptySpanningBitStorage(9, 256)
+	if err := heightmap.EncodeAll(heights); err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]any{
+		"DataVersion": int32(2230),
+		"Level": map[string]any{
+			"xPos": int32(3), "zPos": int32(-2), "Status": "postprocessed", "Biomes": legacyBiomes,
+			// Heightmaps that cannot be read, such as the one holding too few longs, are dropped.
+			"Heightmaps":   map[string]any{"MOTION_BLOCKING": longArray(heightmap.Data()), "WORLD_SURFACE": longArray(make([]int64, 4))},
+			"TileEntities": []any{map[string]any{"id": "minecraft:chest", "x": int32(48), "y": int32(1), "z": int32(-32)}},
+			"Sections": []any{
+				map[string]any{"Y": uint8(0), "Palette": palette, "BlockStates": longArray(spanning.Data()), "SkyLight": [2048]byte{}},
+			},
+		},
+	}
+	upgraded, err := Chunk(data)
+	if err != nil || !upgraded {
+		t.Fatalf("expected chunk to be upgraded, got %v (upgraded: %v)", err, upgraded)
+	}
+
+	if v := data["DataVersion"]; v != DataVersion() {
+		t.Fatalf("expected data version %v, got %v", DataVersion(), v)
+	}
+	if _, ok := data["Level"]; ok {
+		t.Fatalf("expected Level compound to be removed")
+	}
+	for key, expected := range map[string]any{"xPos": int32(3), "yPos": int32(0), "zPos": int32(-2), "Status": "full"} {
+		if data[key] != expected {
+			t.Errorf("%v: expected %v, got %v", key, expected, data[key])
+		}
+	}
+	if blockEntities := compounds(data, "block_entities"); len(blockEntities) != 1 || blockEntities[0]["id"] != "minecraft:chest" {
+		t.Errorf("expected the chest to be kept as block entity, got %v", data["block_entities"])
+	}
+
+	heightmaps := compound(data, "Heightmaps")
+	if _, ok := heightmaps["WORLD_SURFACE"]; ok || len(heightmaps) != 1 {
+		t.Errorf("expected only the motion blocking heightmap to be kept, got %v", heightmaps)
+	}
+	paddedHeightmap, err := column.NewFilledBitStorage(9, 256, integers(heightmaps["MOTION_BLOCKING"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorage(t, paddedHeightmap, heights)
+
+	sections := sectionsByY(t, data)
+	if len(sections) != 16 {
+		t.Fatalf("expected 16 sections, got %v", len(sections))
+	}
+	section := sections[0]
+	if _, ok := section["SkyLight"]; !ok {
+		t.Errorf("expected light to be kept")
+	}
+	blockStates := compound(section, "block_states")
+	upgradedPalette := compounds(blockStates, "palette")
+	for i, expected := range []map[string]any{
+		{"Name": "minecraft:air"},
+		{"Name": "minecraft:dirt_path"},
+		{"Name": "minecraft:cauldron"},
+		{"Name": "minecraft:water_cauldron", "Properties": map[string]any{"level": "2"}},
+	} {
+		if !reflect.DeepEqual(upgradedPalette[i], expected) {
+			t.Errorf("palette entry %v: expected %v, got %v", i, expected, upgradedPalette[i])
+		}
+	}
+	padded, err := column.NewFilledBitStorage(5, 4096, integers(blockStates["data"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorage(t, padded, values)
+
+	biomes := compound(section, "biomes")
+	if palette := list(biomes, "palette"); !reflect.DeepEqual(palette, []any{"minecraft:windswept_hills", "minecraft:plains"}) {
+		t.Fatalf("expected windswept hills and plains, got %v", palette)
+	}
+	cells, err := column.NewFilledBitStorage(1, 64, integers(biomes["data"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCells := make([]int32, 64)
+	for i := range expectedCells {
+		if i&3 != 0 {
+			expectedCells[i] = 1
+		}
+	}
+	checkStorage(t, cells, expectedCells)
+
+	for y := int8(1); y < 16; y++ {
+		states := compounds(compound(sections[y], "block_states"), "palette")
+		if len(states) != 1 || states[0]["Name"] != "minecraft:air" {
+			t.Errorf("section %v: expected air, got %v", y, states)
+		}
+	}
+}
+
+func TestChunkSingleStateSection(t *testing.T) {
+	stone, dirt := map[string]any{"Name": "minecraft:stone"}, map[string]any{"Name": "minecraft:dirt"}
+	values := make([]int32, 4096)
+	for i := range values {
+		values[i] = int32(i >> 11)
+	}
+	halves := column.NewEmptyBitStorage(4, 4096)
+	if err := halves.EncodeAll(values); err != nil {
+		t.Fatal(err)
+	}
+	// Before 1.18, sections of a single state still store 4 bits for every block.
+	data := map[string]any{
+		"DataVersion": int32(2730),
+		"Level": map[string]any{
+			"xPos": int32(0), "zPos": int32(0), "Status": "full",
+			"Sections": []any{
+				map[string]any{"Y": uint8(0), "Palette": []any{stone}, "BlockStates": longArray(make([]int64, 256))},
+				map[string]any{"Y": uint8(1), "Palette": []any{stone, dirt}, "BlockStates": longArray(halves.Data())},
+			},
+		},
+	}
+	if _, err := Chunk(data); err != nil {
+		t.Fatal(err)
+	}
+	sections := sectionsByY(t, data)
+	if blockStates := compound(sections[0], "block_states"); blockStates["data"] != nil {
+		t.Fatalf("expected no data for a single state, got %v longs", len(integers(blockStates["data"])))
+	}
+	storage, err := column.NewFilledBitStorage(4, 4096, integers(compound(sections[1], "block_states")["data"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStorage(t, storage, values)
+}
+
+func TestFixBlockEntityIDs(t *testing.T) {
+	data := map[string]any{"Level": map[string]any{"TileEntities": []any{
+		map[string]any{"id": "Chest"},
+		map[string]any{"id": "MobSpawner"},
+		map[string]any{"id": "minecraft:furnace"},
+	}}}
+	if err := fixBlockEntityIDs(data); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"minecraft:chest", "minecraft:mob_spawner", "minecraft:furnace"} {
+		if id := compounds(compound(data, "Level"), "TileEntities")[i]["id"]; id != expected {
+			t.Errorf("block entity %v: expected %v, got %v", i, expected, id)
+		}
+	}
+}
+
+func TestFixFlattening(t *testing.T) {
+	data := map[string]any{
+		"DataVersion": int32(1343),
+		"Level":       map[string]any{"Sections": []any{map[string]any{"Y": uint8(0), "Blocks": [4096]byte{}}}},
+	}
+	if _, err := Chunk(data); !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "flattening") {
+		t.Fatalf("expected flattening to fail as unsupported, got %v", err)
+	}
+
+	empty := map[string]any{"DataVersion": int32(1343), "Level": map[string]any{"xPos": int32(0), "zPos": int32(0)}}
+	if _, err := Chunk(empty); err != nil {
+		t.Fatalf("expected chunk without blocks to be upgraded, got %v", err)
+	}
+}
+
+func TestFixBiomeRenames(t *testing.T) {
+	// Snapshots of 1.18 before the biome renames already used the current chunk format.
+	data := map[string]any{"DataVersion": int32(2825), "sections": []any{
+		map[string]any{"Y": uint8(0), "biomes": map[string]any{"palette": []any{"minecraft:mountains", "minecraft:plains", "minecraft:giant_tree_taiga"}}},
+	}}
+	if _, err := Chunk(data); err != nil {
+		t.Fatal(err)
+	}
+	palette := list(compound(compounds(data, "sections")[0], "biomes"), "palette")
+	if expected := []any{"minecraft:windswept_hills", "minecraft:plains", "minecraft:old_growth_pine_taiga"}; !reflect.DeepEqual(palette, expected) {
+		t.Fatalf("expected %v, got %v", expected, palette)
+	}
+}
+
+func TestSectionBiomes(t *testing.T) {
+	var columns [256]int32
+	for i := range columns {
+		// Deserts in the western half of the chunk, jungles in the eastern half.
+		columns[i] = 21
+		if i&15 < 8 {
+			columns[i] = 2
+		}
+	}
+	var unknown [1024]int32
+	for i := range unknown {
+		unknown[i] = 1000
+	}
+	for _, test := range []struct {
+		name     string
+		biomes   []int64
+		y        int8
+		expected func(cellX, cellY, cellZ int) string
+	}{
+		{name: "none", expected: func(int, int, int) string { return "minecraft:plains" }},
+		{name: "columns", biomes: integers(columns), expected: func(cellX, _, _ int) string {
+			if cellX < 2 {
+				return "minecraft:desert"
+			}
+			return "minecraft:jungle"
+		}},
+		{name: "unknown", biomes: integers(unknown), expected: func(int, int, int) string { return "minecraft:plains" }},
+		// Sections below the world use the lowest layer of cells, and sections above it the highest.
+		{name: "below", biomes: layeredBiomes(), y: -1, expected: func(int, int, int) string { return "minecraft:desert" }},
+		{name: "lowest", biomes: layeredBiomes(), y: 0, expected: func(_, cellY, _ int) string {
+			if cellY == 0 {
+				return "minecraft:desert"
+			}
+			return "minecraft:forest"
+		}},
+		{name: "above", biomes: layeredBiomes(), y: 16, expected: func(int, int, int) string { return "minecraft:snowy_plains" }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			biomes, err := sectionBiomes(test.biomes, test.y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			palette := list(biomes, "palette")
+			indices := make([]int32, 64)
+			if len(palette) > 1 {
+				storage, err := column.NewFilledBitStorage(int32(bits.Len(uint(len(palette)-1))), 64, integers(biomes["data"]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := storage.DecodeAll(indices); err != nil {
+					t.Fatal(err)
+				}
+			} else if biomes["data"] != nil {
+				t.Fatalf("expected no data for a single biome")
+			}
+			for i, index := range indices {
+				if got, expected := palette[index], test.expected(i&3, i>>4, (i>>2)&3); got != expected {
+					t.Fatalf("cell %v: expected %v, got %v", i, expected, got)
+				}
+			}
+		})
+	}
+	if _, err := sectionBiomes(make([]int64, 100), 0); err == nil {
+		t.Fatalf("expected an error for an invalid biome array")
+	}
+}
+
+// layeredBiomes returns a 1.15 biome array with deserts in the lowest layer of cells, snowy tundras in the highest
+// and forests in between.
+func layeredBiomes() []int64 {
+	biomes := make([]int64, 1024)
+	for i := range biomes {
+		switch layer := i >> 4; {
+		case layer == 0:
+			biomes[i] = 2
+		case layer == 63:
+			biomes[i] = 12
+		default:
+			biomes[i] = 4
+		}
+	}
+	return biomes
+}
+
+// sectionsByY returns the sections of the upgraded chunk NBT passed by their Y.
+func sectionsByY(t *testing.T, data map[string]any) map[int8]map[string]any {
+	t.Helper()
+	sections := make(map[int8]map[string]any)
+	for _, section := range compounds(data, "sections") {
+		if _, ok := sections[sectionY(section)]; ok {
+			t.Fatalf("duplicate section %v", sectionY(section))
+		}
+		sections[sectionY(section)] = section
+	}
+	return sections
+}
+
+// checkStorage checks that the values of the storage passed match the values expected.
+func checkStorage(t *testing.T, storage *column.BitStorage, expected []int32) {
+	t.Helper()
+	values := make([]int32, len(expected))
+	if err := storage.DecodeAll(values); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range expected {
+		if values[i] != v {
+			t.Fatalf("entry %v: expected %v, got %v", i, v, values[i])
+		}
+	}
+}
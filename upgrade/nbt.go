@@ -0,0 +1,62 @@
+package upgrade
+
+import "reflect"
+
+// compound returns the compound tag stored under the key passed, or nil if there is none.
+func compound(data map[string]any, key string) map[string]any {
+	m, _ := data[key].(map[string]any)
+	return m
+}
+
+// list returns the list tag stored under the key passed, or nil if there is none.
+func list(data map[string]any, key string) []any {
+	l, _ := data[key].([]any)
+	return l
+}
+
+// compounds returns all compound tags in the list tag stored under the key passed.
+func compounds(data map[string]any, key string) []map[string]any {
+	var m []map[string]any
+	for _, v := range list(data, key) {
+		if c, ok := v.(map[string]any); ok {
+			m = append(m, c)
+		}
+	}
+	return m
+}
+
+// rename moves the tag stored under the key from to the key to, if present.
+func rename(data map[string]any, from, to string) {
+	if v, ok := data[from]; ok {
+		delete(data, from)
+		data[to] = v
+	}
+}
+
+// integers returns the values of an integer array tag, such as an int array or long array, as int64s. Arrays are
+// decoded into fixed size arrays, so reflection is needed to read them.
+func integers(v any) []int64 {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Array && val.Kind() != reflect.Slice {
+		return nil
+	}
+	values := make([]int64, val.Len())
+	for i := range values {
+		switch e := val.Index(i); e.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			values[i] = e.Int()
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			values[i] = int64(e.Uint())
+		default:
+			return nil
+		}
+	}
+	return values
+}
+
+// longArray returns the slice passed as a fixed size array, so that it is encoded as a long array tag.
+func longArray(data []int64) any {
+	arr := reflect.New(reflect.ArrayOf(len(data), reflect.TypeOf(int64(0)))).Elem()
+	reflect.Copy(arr, reflect.ValueOf(data))
+	return arr.Interface()
+}
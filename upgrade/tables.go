@@ -0,0 +1,169 @@
+package upgrade
+
+var (
+	// legacyBiomeIDs maps the numeric biome IDs used before Minecraft 1.18 to their names at the time.
+	legacyBiomeIDs = map[int32]string{
+		0:   "minecraft:ocean",
+		1:   "minecraft:plains",
+		2:   "minecraft:desert",
+		3:   "minecraft:mountains",
+		4:   "minecraft:forest",
+		5:   "minecraft:taiga",
+		6:   "minecraft:swamp",
+		7:   "minecraft:river",
+		8:   "minecraft:nether_wastes",
+		9:   "minecraft:the_end",
+		10:  "minecraft:frozen_ocean",
+		11:  "minecraft:frozen_river",
+		12:  "minecraft:snowy_tundra",
+		13:  "minecraft:snowy_mountains",
+		14:  "minecraft:mushroom_fields",
+		15:  "minecraft:mushroom_field_shore",
+		16:  "minecraft:beach",
+		17:  "minecraft:desert_hills",
+		18:  "minecraft:wooded_hills",
+		19:  "minecraft:taiga_hills",
+		20:  "minecraft:mountain_edge",
+		21:  "minecraft:jungle",
+		22:  "minecraft:jungle_hills",
+		23:  "minecraft:jungle_edge",
+		24:  "minecraft:deep_ocean",
+		25:  "minecraft:stone_shore",
+		26:  "minecraft:snowy_beach",
+		27:  "minecraft:birch_forest",
+		28:  "minecraft:birch_forest_hills",
+		29:  "minecraft:dark_forest",
+		30:  "minecraft:snowy_taiga",
+		31:  "minecraft:snowy_taiga_hills",
+		32:  "minecraft:giant_tree_taiga",
+		33:  "minecraft:giant_tree_taiga_hills",
+		34:  "minecraft:wooded_mountains",
+		35:  "minecraft:savanna",
+		36:  "minecraft:savanna_plateau",
+		37:  "minecraft:badlands",
+		38:  "minecraft:wooded_badlands_plateau",
+		39:  "minecraft:badlands_plateau",
+		40:  "minecraft:small_end_islands",
+		41:  "minecraft:end_midlands",
+		42:  "minecraft:end_highlands",
+		43:  "minecraft:end_barrens",
+		44:  "minecraft:warm_ocean",
+		45:  "minecraft:lukewarm_ocean",
+		46:  "minecraft:cold_ocean",
+		47:  "minecraft:deep_warm_ocean",
+		48:  "minecraft:deep_lukewarm_ocean",
+		49:  "minecraft:deep_cold_ocean",
+		50:  "minecraft:deep_frozen_ocean",
+		127: "minecraft:the_void",
+		129: "minecraft:sunflower_plains",
+		130: "minecraft:desert_lakes",
+		131: "minecraft:gravelly_mountains",
+		132: "minecraft:flower_forest",
+		133: "minecraft:taiga_mountains",
+		134: "minecraft:swamp_hills",
+		140: "minecraft:ice_spikes",
+		149: "minecraft:modified_jungle",
+		151: "minecraft:modified_jungle_edge",
+		155: "minecraft:tall_birch_forest",
+		156: "minecraft:tall_birch_hills",
+		157: "minecraft:dark_forest_hills",
+		158: "minecraft:snowy_taiga_mountains",
+		160: "minecraft:giant_spruce_taiga",
+		161: "minecraft:giant_spruce_taiga_hills",
+		162: "minecraft:modified_gravelly_mountains",
+		163: "minecraft:shattered_savanna",
+		164: "minecraft:shattered_savanna_plateau",
+		165: "minecraft:eroded_badlands",
+		166: "minecraft:modified_wooded_badlands_plateau",
+		167: "minecraft:modified_badlands_plateau",
+		168: "minecraft:bamboo_jungle",
+		169: "minecraft:bamboo_jungle_hills",
+		170: "minecraft:soul_sand_valley",
+		171: "minecraft:crimson_forest",
+		172: "minecraft:warped_forest",
+		173: "minecraft:basalt_deltas",
+		174: "minecraft:dripstone_caves",
+		175: "minecraft:lush_caves",
+	}
+	// biomeRenames maps the names of biomes that were renamed or merged into other biomes up to Minecraft 1.18 to
+	// their current names.
+	biomeRenames = map[string]string{
+		"minecraft:badlands_plateau":                 "minecraft:badlands",
+		"minecraft:bamboo_jungle_hills":              "minecraft:bamboo_jungle",
+		"minecraft:birch_forest_hills":               "minecraft:birch_forest",
+		"minecraft:dark_forest_hills":                "minecraft:dark_forest",
+		"minecraft:desert_hills":                     "minecraft:desert",
+		"minecraft:desert_lakes":                     "minecraft:desert",
+		"minecraft:giant_spruce_taiga":               "minecraft:old_growth_spruce_taiga",
+		"minecraft:giant_spruce_taiga_hills":         "minecraft:old_growth_spruce_taiga",
+		"minecraft:giant_tree_taiga":                 "minecraft:old_growth_pine_taiga",
+		"minecraft:giant_tree_taiga_hills":           "minecraft:old_growth_pine_taiga",
+		"minecraft:gravelly_mountains":               "minecraft:windswept_gravelly_hills",
+		"minecraft:jungle_edge":                      "minecraft:sparse_jungle",
+		"minecraft:jungle_hills":                     "minecraft:jungle",
+		"minecraft:lofty_peaks":                      "minecraft:jagged_peaks",
+		"minecraft:modified_badlands_plateau":        "minecraft:badlands",
+		"minecraft:modified_gravelly_mountains":      "minecraft:windswept_gravelly_hills",
+		"minecraft:modified_jungle":                  "minecraft:jungle",
+		"minecraft:modified_jungle_edge":             "minecraft:sparse_jungle",
+		"minecraft:modified_wooded_badlands_plateau": "minecraft:wooded_badlands",
+		"minecraft:mountain_edge":                    "minecraft:windswept_hills",
+		"minecraft:mountains":                        "minecraft:windswept_hills",
+		"minecraft:mushroom_field_shore":             "minecraft:mushroom_fields",
+		"minecraft:nether":                           "minecraft:nether_wastes",
+		"minecraft:shattered_savanna":                "minecraft:windswept_savanna",
+		"minecraft:shattered_savanna_plateau":        "minecraft:windswept_savanna",
+		"minecraft:snowcapped_peaks":                 "minecraft:frozen_peaks",
+		"minecraft:snowy_mountains":                  "minecraft:snowy_plains",
+		"minecraft:snowy_taiga_hills":                "minecraft:snowy_taiga",
+		"minecraft:snowy_taiga_mountains":            "minecraft:snowy_taiga",
+		"minecraft:snowy_tundra":                     "minecraft:snowy_plains",
+		"minecraft:stone_shore":                      "minecraft:stony_shore",
+		"minecraft:swamp_hills":                      "minecraft:swamp",
+		"minecraft:taiga_hills":                      "minecraft:taiga",
+		"minecraft:taiga_mountains":                  "minecraft:taiga",
+		"minecraft:tall_birch_forest":                "minecraft:old_growth_birch_forest",
+		"minecraft:tall_birch_hills":                 "minecraft:old_growth_birch_forest",
+		"minecraft:wooded_badlands_plateau":          "minecraft:wooded_badlands",
+		"minecraft:wooded_hills":                     "minecraft:forest",
+		"minecraft:wooded_mountains":                 "minecraft:windswept_forest",
+	}
+	// blockEntityRenames maps the block entity IDs used before Minecraft 1.11 to their namespaced IDs.
+	blockEntityRenames = map[string]string{
+		"Airportal":    "minecraft:end_portal",
+		"Banner":       "minecraft:banner",
+		"Beacon":       "minecraft:beacon",
+		"Cauldron":     "minecraft:brewing_stand",
+		"Chest":        "minecraft:chest",
+		"Comparator":   "minecraft:comparator",
+		"Control":      "minecraft:command_block",
+		"DLDetector":   "minecraft:daylight_detector",
+		"Dropper":      "minecraft:dropper",
+		"EnchantTable": "minecraft:enchanting_table",
+		"EndGateway":   "minecraft:end_gateway",
+		"EnderChest":   "minecraft:ender_chest",
+		"FlowerPot":    "minecraft:flower_pot",
+		"Furnace":      "minecraft:furnace",
+		"Hopper":       "minecraft:hopper",
+		"MobSpawner":   "minecraft:mob_spawner",
+		"Music":        "minecraft:noteblock",
+		"Piston":       "minecraft:piston",
+		"RecordPlayer": "minecraft:jukebox",
+		"Sign":         "minecraft:sign",
+		"Skull":        "minecraft:skull",
+		"Structure":    "minecraft:structure_block",
+		"Trap":         "minecraft:dispenser",
+	}
+	// statusRenames maps the chunk statuses used by Minecraft 1.13 to the statuses used since 1.14.
+	statusRenames = map[string]string{
+		"base":          "empty",
+		"carved":        "carvers",
+		"liquid_carved": "liquid_carvers",
+		"decorated":     "features",
+		"lighted":       "light",
+		"mobs_spawned":  "spawn",
+		"finalized":     "heightmaps",
+		"fullchunk":     "full",
+		"postprocessed": "full",
+	}
+)
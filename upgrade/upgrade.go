@@ -0,0 +1,76 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Fix is a single step upgrading chunk NBT to a newer data version, similar to the data fixers used by Java.
+type Fix struct {
+	// DataVersion is the data version that introduced the change the fix upgrades to. The fix is applied to all
+	// chunks with an older data version.
+	DataVersion int32
+	// Name is a short description of the fix, used in errors.
+	Name string
+	// Apply applies the fix to the chunk NBT passed, modifying it in place.
+	Apply func(data map[string]any) error
+}
+
+// ErrUnsupported is returned, wrapped, by Chunk for chunks in a format that cannot be upgraded, such as chunks
+// holding numeric block IDs from before Minecraft 1.13. Callers may use errors.Is to skip such chunks.
+var ErrUnsupported = errors.New("unsupported chunk format")
+
+var (
+	// fixesMu guards fixes.
+	fixesMu sync.RWMutex
+	// fixes holds all registered fixes, ordered by their data version.
+	fixes []Fix
+)
+
+// Register registers a fix. Fixes are applied in the order of their data versions, and fixes with the same data
+// version in the order in which they were registered.
+func Register(f Fix) {
+	fixesMu.Lock()
+	defer fixesMu.Unlock()
+	fixes = append(fixes, f)
+	sort.SliceStable(fixes, func(i, j int) bool {
+		return fixes[i].DataVersion < fixes[j].DataVersion
+	})
+}
+
+// DataVersion returns the data version chunks have after being upgraded, which is the data version of the
+// newest registered fix.
+func DataVersion() int32 {
+	fixesMu.RLock()
+	defer fixesMu.RUnlock()
+	if len(fixes) == 0 {
+		return 0
+	}
+	return fixes[len(fixes)-1].DataVersion
+}
+
+// Chunk upgrades the chunk NBT passed in place by applying all fixes newer than the DataVersion of the chunk.
+// The DataVersion of the chunk is updated to that of the last fix applied. True is returned if any fix was
+// applied.
+func Chunk(data map[string]any) (bool, error) {
+	version, _ := data["DataVersion"].(int32)
+
+	fixesMu.RLock()
+	pending := make([]Fix, 0, len(fixes))
+	for _, f := range fixes {
+		if f.DataVersion > version {
+			pending = append(pending, f)
+		}
+	}
+	fixesMu.RUnlock()
+
+	for _, f := range pending {
+		if err := f.Apply(data); err != nil {
+			return false, fmt.Errorf("upgrade to data version %v (%v): %w", f.DataVersion, f.Name, err)
+		}
+		data["DataVersion"] = f.DataVersion
+	}
+	return len(pending) > 0, nil
+}
@@ -0,0 +1,59 @@
+package upgrade
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	fixesMu.Lock()
+	registered := fixes
+	fixes = nil
+	fixesMu.Unlock()
+	defer func() {
+		fixesMu.Lock()
+		fixes = registered
+		fixesMu.Unlock()
+	}()
+
+	var applied []string
+	fix := func(name string) func(map[string]any) error {
+		return func(map[string]any) error {
+			applied = append(applied, name)
+			return nil
+		}
+	}
+	Register(Fix{DataVersion: 200, Name: "c", Apply: fix("c")})
+	Register(Fix{DataVersion: 100, Name: "a", Apply: fix("a")})
+	Register(Fix{DataVersion: 200, Name: "d", Apply: fix("d")})
+	Register(Fix{DataVersion: 150, Name: "b", Apply: fix("b")})
+	if v := DataVersion(); v != 200 {
+		t.Fatalf("expected data version 200, got %v", v)
+	}
+
+	data := map[string]any{"DataVersion": int32(100)}
+	upgraded, err := Chunk(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fixes with the same data version are applied in the order in which they were registered.
+	if got := strings.Join(applied, ""); !upgraded || got != "bcd" {
+		t.Fatalf("expected fixes bcd to be applied, got %q (upgraded: %v)", got, upgraded)
+	}
+	if data["DataVersion"] != int32(200) {
+		t.Fatalf("expected data version 200 after upgrading, got %v", data["DataVersion"])
+	}
+	if upgraded, err := Chunk(data); err != nil || upgraded {
+		t.Fatalf("expected an upgraded chunk to be left alone, got %v (upgraded: %v)", err, upgraded)
+	}
+
+	failure := errors.New("failure")
+	Register(Fix{DataVersion: 300, Name: "broken", Apply: func(map[string]any) error { return failure }})
+	if _, err := Chunk(data); !errors.Is(err, failure) || !strings.Contains(err.Error(), "300 (broken)") {
+		t.Fatalf("expected the error of the fix, got %v", err)
+	}
+	if data["DataVersion"] != int32(200) {
+		t.Fatalf("expected the data version of a failed fix not to be set, got %v", data["DataVersion"])
+	}
+}
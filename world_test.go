@@ -1,6 +1,7 @@
 package mcanvil
 
 import (
+	"flag"
 	"fmt"
 	"github.com/df-mc/dragonfly/server"
 	"github.com/df-mc/dragonfly/server/player/chat"
@@ -11,7 +12,13 @@ import (
 	"testing"
 )
 
+// serve is set to start a server in TestWorld, serving the world in the world folder until it is closed.
+var serve = flag.Bool("serve", false, "start a server for the converted world in TestWorld")
+
 func TestWorld(t *testing.T) {
+	if !*serve {
+		t.Skip("pass -serve to start a server for the converted world")
+	}
 	log := logrus.New()
 	log.Formatter = &logrus.TextFormatter{ForceColors: true}
 	log.Level = logrus.DebugLevel